@@ -0,0 +1,282 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+	"github.com/user/jswitch/pkg/config"
+	"github.com/user/jswitch/pkg/models"
+	"github.com/user/jswitch/pkg/paths"
+	"github.com/user/jswitch/pkg/truststore"
+)
+
+func newCertsCmd(a *app) *cobra.Command {
+	var all bool
+	cmd := &cobra.Command{
+		Use:   "certs",
+		Short: "Manage extra trusted certificates for every installed JDK",
+		Long: "Keep a set of extra trusted certificates, such as a corporate root CA, in the truststore\n" +
+			"(lib/security/cacerts) of every JDK jswitch installed. The truststores are edited directly,\n" +
+			"in JKS or PKCS#12 format, and read back to verify; keytool is not needed. Certificates are\n" +
+			"added again after every install and sync.\n\n" +
+			"Only JDKs in install-dir are changed, except with --all. The truststore-password setting is\n" +
+			"the password of the truststores.",
+		Args: exactArgs(0),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runCertsList(a, all)
+		},
+	}
+	cmd.PersistentFlags().BoolVar(&all, "all", false, "also change the JDKs jswitch did not install (found by scan, or system installs)")
+
+	var name string
+	add := &cobra.Command{
+		Use:   "add <file>",
+		Short: "Trust the certificates of a PEM or DER file in every JDK",
+		Args:  exactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runCertsAdd(a, args[0], name, all)
+		},
+	}
+	add.Flags().StringVar(&name, "name", "", "name of the certificate in jswitch (default: the file name without extension)")
+
+	cmd.AddCommand(
+		add,
+		&cobra.Command{
+			Use:               "remove <name>",
+			Aliases:           []string{"rm"},
+			Short:             "Stop trusting a certificate added with 'jswitch certs add'",
+			Args:              exactArgs(1),
+			ValidArgsFunction: a.completeCertNames,
+			RunE: func(cmd *cobra.Command, args []string) error {
+				return runCertsRemove(a, args[0], all)
+			},
+		},
+		&cobra.Command{
+			Use:     "list",
+			Aliases: []string{"ls"},
+			Short:   "List the extra certificates and how many JDKs trust them",
+			Args:    exactArgs(0),
+			RunE: func(cmd *cobra.Command, args []string) error {
+				return runCertsList(a, all)
+			},
+		},
+		&cobra.Command{
+			Use:   "apply",
+			Short: "Add the extra certificates to every JDK that lacks them",
+			Args:  exactArgs(0),
+			RunE: func(cmd *cobra.Command, args []string) error {
+				return runCertsApply(a, all)
+			},
+		},
+	)
+	return cmd
+}
+
+func runCertsAdd(a *app, file, name string, all bool) error {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return err
+	}
+	certs, err := truststore.ReadPEM(data)
+	if err != nil {
+		return usageErrorf("%s: %v", file, err)
+	}
+	if name == "" {
+		name = truststore.NameFor(file)
+	}
+	dir, err := paths.CertsDir()
+	if err != nil {
+		return err
+	}
+	cert, err := truststore.AddToSet(dir, name, certs)
+	if err != nil {
+		return usageErrorf("%v", err)
+	}
+	for _, c := range certs {
+		a.infof("Added %s (%s, expires %s).\n", c.Subject.CommonName, truststore.Fingerprint(c), c.NotAfter.Format("2006-01-02"))
+	}
+	return applyCerts(a, all, []truststore.Cert{cert}, nil)
+}
+
+func runCertsRemove(a *app, name string, all bool) error {
+	set, dir, err := loadCertSet()
+	if err != nil {
+		return err
+	}
+	for _, cert := range set {
+		if cert.Name != name {
+			continue
+		}
+		// The others are applied again, in case one of them holds the same certificate.
+		var rest []truststore.Cert
+		for _, other := range set {
+			if other.Name != name {
+				rest = append(rest, other)
+			}
+		}
+		if err := applyCerts(a, all, rest, []truststore.Cert{cert}); err != nil {
+			return err
+		}
+		if err := os.Remove(cert.Path); err != nil {
+			return err
+		}
+		a.infof("Removed %s from the extra certificates.\n", name)
+		return nil
+	}
+	return &cliError{code: exitNotFound, err: fmt.Errorf("no certificate named %q in %s; see 'jswitch certs list'", name, dir)}
+}
+
+func runCertsApply(a *app, all bool) error {
+	set, _, err := loadCertSet()
+	if err != nil {
+		return err
+	}
+	if len(set) == 0 {
+		a.infof("No extra certificates. Add one with 'jswitch certs add <file>'.\n")
+		return nil
+	}
+	return applyCerts(a, all, set, nil)
+}
+
+func runCertsList(a *app, all bool) error {
+	set, _, err := loadCertSet()
+	if err != nil {
+		return err
+	}
+	if len(set) == 0 {
+		a.infof("No extra certificates. Add one with 'jswitch certs add <file>'.\n")
+		return nil
+	}
+	cfg, err := loadConfig()
+	if err != nil {
+		return err
+	}
+	targets, err := certTargets(cfg, all)
+	if err != nil {
+		return err
+	}
+	password := cfg.SettingString(config.SettingTrustPassword)
+
+	w := tabwriter.NewWriter(a.stdout, 0, 0, 3, ' ', 0)
+	fmt.Fprintln(w, "NAME\tSUBJECT\tEXPIRES\tSHA-256\tTRUSTED BY")
+	for _, cert := range set {
+		trusted := 0
+		for _, inst := range targets {
+			ok, err := truststore.Trusts(inst.Path, password, cert)
+			if err != nil {
+				a.debugf("%s: %v\n", inst.Path, err)
+			}
+			if ok {
+				trusted++
+			}
+		}
+		for i, c := range cert.Certs {
+			label := cert.Name
+			if len(cert.Certs) > 1 {
+				label = fmt.Sprintf("%s (%d/%d)", cert.Name, i+1, len(cert.Certs))
+			}
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%d/%d\n", label, c.Subject.CommonName, c.NotAfter.Format("2006-01-02"),
+				truststore.Fingerprint(c)[:23]+"...", trusted, len(targets))
+		}
+	}
+	return w.Flush()
+}
+
+// applyCerts adds add to and removes remove from the truststore of every target
+// installation. An installation that fails is reported and the others are still changed.
+func applyCerts(a *app, all bool, add, remove []truststore.Cert) error {
+	cfg, err := loadConfig()
+	if err != nil {
+		return err
+	}
+	targets, err := certTargets(cfg, all)
+	if err != nil {
+		return err
+	}
+	if len(targets) == 0 {
+		a.infof("No installations to update yet; the certificates will be added to the JDKs you install.\n")
+		return nil
+	}
+	password := cfg.SettingString(config.SettingTrustPassword)
+
+	var failed []string
+	changed := 0
+	for _, inst := range targets {
+		result, err := truststore.Apply(inst.Path, password, add, remove)
+		if err != nil {
+			a.warnf("Java %s at %s: %v\n", inst.Version, inst.Path, err)
+			failed = append(failed, inst.Version)
+			continue
+		}
+		if result.Added > 0 || result.Removed > 0 {
+			changed++
+			a.debugf("%s: %d added, %d removed (verified)\n", result.Path, result.Added, result.Removed)
+		}
+	}
+	a.infof("Updated and verified the truststore of %d installation(s), %d already up to date.\n",
+		changed, len(targets)-changed-len(failed))
+	if len(failed) > 0 {
+		return environmentError(fmt.Errorf("could not update the truststore of Java %s", strings.Join(failed, ", ")))
+	}
+	return nil
+}
+
+// applyCertsAfterInstall adds the extra certificates to an installation jswitch just
+// installed. Failures are reported as warnings: the install itself has already succeeded.
+func applyCertsAfterInstall(a *app, inst models.JavaInstallation) {
+	set, _, err := loadCertSet()
+	if err != nil {
+		a.warnf("could not read the extra certificates: %v\n", err)
+		return
+	}
+	if len(set) == 0 {
+		return
+	}
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		return
+	}
+	result, err := truststore.Apply(inst.Path, cfg.SettingString(config.SettingTrustPassword), set, nil)
+	if err != nil {
+		a.warnf("could not add the extra certificates to Java %s: %v (run 'jswitch certs apply' to retry)\n", inst.Version, err)
+		return
+	}
+	// Like the download progress, this goes to stderr so that structured output stays clean.
+	if result.Added > 0 && !a.quiet {
+		fmt.Fprintf(a.stderr, "Added %d extra trusted certificate(s) to %s.\n", result.Added, result.Path)
+	}
+}
+
+// certTargets returns the installations whose truststores 'jswitch certs' changes.
+func certTargets(cfg *config.Config, all bool) ([]models.JavaInstallation, error) {
+	if all {
+		return cfg.Installations, nil
+	}
+	dir, err := installDir(cfg)
+	if err != nil {
+		return nil, err
+	}
+	var targets []models.JavaInstallation
+	for _, inst := range cfg.Installations {
+		if isWithin(dir, inst.Path) && !cfg.IsSystem(inst) {
+			targets = append(targets, inst)
+		}
+	}
+	return targets, nil
+}
+
+func loadCertSet() ([]truststore.Cert, string, error) {
+	dir, err := paths.CertsDir()
+	if err != nil {
+		return nil, "", err
+	}
+	set, err := truststore.LoadSet(dir)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, dir, configError(err)
+	}
+	return set, dir, nil
+}
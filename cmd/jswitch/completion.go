@@ -0,0 +1,253 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/user/jswitch/pkg/config"
+	"github.com/user/jswitch/pkg/fetcher"
+	"github.com/user/jswitch/pkg/resolver"
+	"github.com/user/jswitch/pkg/shell"
+)
+
+func newCompletionCmd(a *app) *cobra.Command {
+	return &cobra.Command{
+		Use:   "completion bash|zsh|fish|pwsh",
+		Short: "Generate a shell completion script",
+		Long: `Generate a completion script for your shell. Versions and aliases are completed
+dynamically from your config, and 'install' completes feature releases from the
+listing cached by 'jswitch list-remote'.
+
+  bash:  source <(jswitch completion bash)
+         # or: jswitch completion bash > /etc/bash_completion.d/jswitch
+  zsh:   jswitch completion zsh > "${fpath[1]}/_jswitch"
+  fish:  jswitch completion fish > ~/.config/fish/completions/jswitch.fish
+  pwsh:  jswitch completion pwsh | Out-String | Invoke-Expression`,
+		ValidArgs: []string{"bash", "zsh", "fish", "pwsh"},
+		Args:      exactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			root := cmd.Root()
+			switch args[0] {
+			case "bash":
+				return root.GenBashCompletionV2(a.stdout, true)
+			case "zsh":
+				return root.GenZshCompletion(a.stdout)
+			case "fish":
+				return root.GenFishCompletion(a.stdout, true)
+			case "pwsh", "powershell":
+				return root.GenPowerShellCompletionWithDesc(a.stdout)
+			default:
+				return usageErrorf("unsupported shell %q (use bash, zsh, fish or pwsh)", args[0])
+			}
+		},
+	}
+}
+
+// completionConfig loads the config for completion. PersistentPreRun does not run
+// for completion requests, so --config has to be applied here.
+func (a *app) completionConfig() *config.Config {
+	config.SetPath(a.configPath)
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		return &config.Config{}
+	}
+	return cfg
+}
+
+// versionCandidates lists every installed version and alias, with descriptions.
+func (a *app) versionCandidates(toComplete string) []string {
+	cfg := a.completionConfig()
+
+	var out []string
+	add := func(value, desc string) {
+		if strings.HasPrefix(value, toComplete) {
+			out = append(out, value+"\t"+desc)
+		}
+	}
+	for _, name := range cfg.AliasNames() {
+		add(name, "alias for "+cfg.Aliases[name].Target)
+	}
+	for _, inst := range cfg.Installations {
+		desc := inst.Vendor
+		if inst.Version == cfg.CurrentVersion {
+			desc += " (current)"
+		}
+		add(inst.Version, desc)
+	}
+	return out
+}
+
+// completeVersionArg completes a version specifier at argument position pos.
+func (a *app) completeVersionArg(pos int) cobra.CompletionFunc {
+	return func(cmd *cobra.Command, args []string, toComplete string) ([]cobra.Completion, cobra.ShellCompDirective) {
+		if len(args) != pos {
+			return nil, cobra.ShellCompDirectiveNoFileComp
+		}
+		return a.versionCandidates(toComplete), cobra.ShellCompDirectiveNoFileComp
+	}
+}
+
+func (a *app) completeUse(cmd *cobra.Command, args []string, toComplete string) ([]cobra.Completion, cobra.ShellCompDirective) {
+	if len(args) != 0 {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+	candidates := a.versionCandidates(toComplete)
+	if strings.HasPrefix("-", toComplete) {
+		candidates = append(candidates, "-\tprevious version")
+	}
+	return candidates, cobra.ShellCompDirectiveNoFileComp
+}
+
+func (a *app) completeAliasNames(cmd *cobra.Command, args []string, toComplete string) ([]cobra.Completion, cobra.ShellCompDirective) {
+	if len(args) != 0 {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+	cfg := a.completionConfig()
+	var out []string
+	for _, name := range cfg.AliasNames() {
+		if strings.HasPrefix(name, toComplete) {
+			out = append(out, name+"\t"+cfg.Aliases[name].Target)
+		}
+	}
+	return out, cobra.ShellCompDirectiveNoFileComp
+}
+
+func (a *app) completeCertNames(cmd *cobra.Command, args []string, toComplete string) ([]cobra.Completion, cobra.ShellCompDirective) {
+	if len(args) != 0 {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+	set, _, _ := loadCertSet()
+	var out []string
+	for _, cert := range set {
+		if strings.HasPrefix(cert.Name, toComplete) {
+			out = append(out, cert.Name+"\t"+cert.Certs[0].Subject.CommonName)
+		}
+	}
+	return out, cobra.ShellCompDirectiveNoFileComp
+}
+
+func (a *app) completeEnvUnset(cmd *cobra.Command, args []string, toComplete string) ([]cobra.Completion, cobra.ShellCompDirective) {
+	cfg := a.completionConfig()
+	var out []string
+	if len(args) == 0 {
+		for _, spec := range cfg.EnvSpecs() {
+			if strings.HasPrefix(spec, toComplete) {
+				out = append(out, spec)
+			}
+		}
+		return out, cobra.ShellCompDirectiveNoFileComp
+	}
+	for name := range cfg.Env[args[0]] {
+		if strings.HasPrefix(name, toComplete) && !slices.Contains(args[1:], name) {
+			out = append(out, name)
+		}
+	}
+	sort.Strings(out)
+	return out, cobra.ShellCompDirectiveNoFileComp
+}
+
+func (a *app) completeAliasSet(cmd *cobra.Command, args []string, toComplete string) ([]cobra.Completion, cobra.ShellCompDirective) {
+	switch len(args) {
+	case 0:
+		return a.completeAliasNames(cmd, args, toComplete)
+	case 1:
+		return a.versionCandidates(toComplete), cobra.ShellCompDirectiveNoFileComp
+	default:
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+}
+
+// completeWhich completes tool names from the active installation's bin directory, then versions.
+func (a *app) completeWhich(cmd *cobra.Command, args []string, toComplete string) ([]cobra.Completion, cobra.ShellCompDirective) {
+	switch len(args) {
+	case 0:
+		res, err := resolver.Active(a.completionConfig(), ".")
+		if err != nil {
+			return nil, cobra.ShellCompDirectiveNoFileComp
+		}
+		entries, err := os.ReadDir(filepath.Join(res.Installation.Path, "bin"))
+		if err != nil {
+			return nil, cobra.ShellCompDirectiveNoFileComp
+		}
+		var out []string
+		for _, e := range entries {
+			name := strings.TrimSuffix(e.Name(), ".exe")
+			if !e.IsDir() && strings.HasPrefix(name, toComplete) {
+				out = append(out, name)
+			}
+		}
+		return out, cobra.ShellCompDirectiveNoFileComp
+	case 1:
+		return a.versionCandidates(toComplete), cobra.ShellCompDirectiveNoFileComp
+	default:
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+}
+
+// completeInstall offers feature releases from the cached remote listing. It never hits the network.
+func (a *app) completeInstall(cmd *cobra.Command, args []string, toComplete string) ([]cobra.Completion, cobra.ShellCompDirective) {
+	if len(args) != 0 {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+	available, err := fetcher.CachedAvailableReleases()
+	if err != nil {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+	var out []string
+	for i := len(available.Releases) - 1; i >= 0; i-- {
+		v := strconv.Itoa(available.Releases[i])
+		if !strings.HasPrefix(v, toComplete) {
+			continue
+		}
+		if available.IsLTS(available.Releases[i]) {
+			v += "\tLTS"
+		}
+		out = append(out, v)
+	}
+	return out, cobra.ShellCompDirectiveNoFileComp | cobra.ShellCompDirectiveKeepOrder
+}
+
+func completeShells(cmd *cobra.Command, args []string, toComplete string) ([]cobra.Completion, cobra.ShellCompDirective) {
+	return shell.Names, cobra.ShellCompDirectiveNoFileComp
+}
+
+// completeSettingKeys completes setting names.
+func completeSettingKeys(cmd *cobra.Command, args []string, toComplete string) ([]cobra.Completion, cobra.ShellCompDirective) {
+	if len(args) > 0 {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+	var keys []cobra.Completion
+	for _, s := range config.Settings() {
+		keys = append(keys, cobra.CompletionWithDesc(s.Key, s.Description))
+	}
+	return keys, cobra.ShellCompDirectiveNoFileComp
+}
+
+// completeSettingSet completes the key, then the accepted values for choice and bool settings.
+func completeSettingSet(cmd *cobra.Command, args []string, toComplete string) ([]cobra.Completion, cobra.ShellCompDirective) {
+	if len(args) == 0 {
+		return completeSettingKeys(cmd, args, toComplete)
+	}
+	if len(args) > 1 {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+	s, err := config.LookupSetting(args[0])
+	if err != nil {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+	switch s.Kind {
+	case config.KindChoice:
+		return s.Choices, cobra.ShellCompDirectiveNoFileComp
+	case config.KindBool:
+		return []cobra.Completion{"true", "false"}, cobra.ShellCompDirectiveNoFileComp
+	case config.KindPath:
+		return nil, cobra.ShellCompDirectiveFilterDirs
+	default:
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+}
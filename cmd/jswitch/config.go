@@ -0,0 +1,327 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+	"github.com/user/jswitch/pkg/config"
+	"github.com/user/jswitch/pkg/fetcher"
+	"github.com/user/jswitch/pkg/output"
+	"github.com/user/jswitch/pkg/paths"
+	"github.com/user/jswitch/pkg/shell"
+	"github.com/user/jswitch/pkg/switcher"
+)
+
+func newConfigCmd(a *app) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "config",
+		Short: "Manage settings and repair the jswitch config",
+		Long:  "Read and change user settings, or repair a damaged config.\n\nSettings:\n" + settingsHelp(),
+		Args:  exactArgs(0),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return cmd.Help()
+		},
+	}
+
+	var dryRun bool
+	repair := &cobra.Command{
+		Use:   "repair",
+		Short: "Recover a damaged config and fix inconsistent entries",
+		Long: "Salvage what can be read from a damaged config (falling back to config.json.bak), then remove\n" +
+			"missing or duplicate installations, clear an unknown current version and fix dangling aliases.\n" +
+			"The file being replaced is kept as config.json.bak, or config.json.corrupt if it was damaged.",
+		Args: exactArgs(0),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runConfigRepair(a, dryRun)
+		},
+	}
+	repair.Flags().BoolVar(&dryRun, "dry-run", false, "report what would change without writing")
+
+	var system bool
+	set := &cobra.Command{
+		Use:   "set <key> <value>",
+		Short: "Change a setting",
+		Long: "Change a setting. Environment variables (JSWITCH_<KEY>) take precedence over the config.\n" +
+			"With --system, change the system config, which applies to every user who has not set the key.\n\nSettings:\n" + settingsHelp(),
+		Args:              exactArgs(2),
+		ValidArgsFunction: completeSettingSet,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runConfigSet(a, args[0], &args[1], system)
+		},
+	}
+	set.Flags().BoolVar(&system, "system", false, "change the system config shared by every user")
+	unset := &cobra.Command{
+		Use:               "unset <key>",
+		Short:             "Restore a setting's default",
+		Args:              exactArgs(1),
+		ValidArgsFunction: completeSettingKeys,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runConfigSet(a, args[0], nil, system)
+		},
+	}
+	unset.Flags().BoolVar(&system, "system", false, "change the system config shared by every user")
+
+	var out outputFlags
+	list := &cobra.Command{
+		Use:     "list",
+		Aliases: []string{"ls"},
+		Short:   "Show every setting with its effective value and source",
+		Args:    exactArgs(0),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			opts, err := out.options()
+			if err != nil {
+				return err
+			}
+			return runConfigList(a, opts)
+		},
+	}
+	out.register(list)
+
+	cmd.AddCommand(
+		list,
+		&cobra.Command{
+			Use:               "get <key>",
+			Short:             "Print the effective value of a setting",
+			Args:              exactArgs(1),
+			ValidArgsFunction: completeSettingKeys,
+			RunE: func(cmd *cobra.Command, args []string) error {
+				return runConfigGet(a, args[0])
+			},
+		},
+		set,
+		unset,
+		repair,
+	)
+	return cmd
+}
+
+// settingsHelp describes the setting registry for --help.
+func settingsHelp() string {
+	var b strings.Builder
+	for _, s := range config.Settings() {
+		def := s.Default
+		if def == "" {
+			def = "unset"
+		}
+		fmt.Fprintf(&b, "  %-21s %s (%s, default %s, env %s)\n", s.Key, s.Description, s.Kind, def, s.EnvVar())
+	}
+	return strings.TrimSuffix(b.String(), "\n")
+}
+
+func runConfigList(a *app, opts output.Options) error {
+	cfg, err := loadConfig()
+	if err != nil {
+		return err
+	}
+
+	var views []output.Setting
+	for _, s := range config.Settings() {
+		v, err := cfg.GetSetting(s.Key)
+		if err != nil {
+			return configError(err)
+		}
+		views = append(views, output.Setting{
+			Key:     s.Key,
+			Value:   v.Value,
+			Source:  v.Source,
+			Default: s.Default,
+			Type:    s.Kind.String(),
+			EnvVar:  s.EnvVar(),
+		})
+	}
+
+	if opts.Structured() {
+		return opts.Write(a.stdout, output.KindSettingList, views)
+	}
+
+	w := tabwriter.NewWriter(a.stdout, 0, 0, 3, ' ', 0)
+	fmt.Fprintln(w, "KEY\tVALUE\tSOURCE")
+	for _, v := range views {
+		source := v.Source
+		if source == config.SourceEnv {
+			source = "env (" + v.EnvVar + ")"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\n", v.Key, v.Value, source)
+	}
+	return w.Flush()
+}
+
+func runConfigGet(a *app, key string) error {
+	cfg, err := loadConfig()
+	if err != nil {
+		return err
+	}
+	if _, err := config.LookupSetting(key); err != nil {
+		return &cliError{code: exitNotFound, err: err}
+	}
+	v, err := cfg.GetSetting(key)
+	if err != nil {
+		return configError(err)
+	}
+	fmt.Fprintln(a.stdout, v.Value)
+	a.debugf("source: %s\n", v.Source)
+	return nil
+}
+
+// runConfigSet stores value for key, or removes the key when value is nil, in the user
+// config or, with system set, the system config.
+func runConfigSet(a *app, key string, value *string, system bool) error {
+	s, err := config.LookupSetting(key)
+	if err != nil {
+		return &cliError{code: exitNotFound, err: err}
+	}
+
+	var stored string
+	var changed bool
+	var cfg *config.Config
+	apply := func(c *config.Config) error {
+		cfg = c
+		if value == nil {
+			changed, _ = c.UnsetSetting(key)
+			return nil
+		}
+		v, err := c.SetSetting(key, *value)
+		if err != nil {
+			return usageErrorf("%v", err)
+		}
+		stored, changed = v, true
+		return nil
+	}
+	if system {
+		var applyErr error
+		err = config.UpdateSystem(func(c *config.Config) error {
+			applyErr = apply(c)
+			return applyErr
+		})
+		if err != nil && applyErr == nil {
+			err = configError(err)
+		}
+		if err != nil {
+			return err
+		}
+		if cfg, err = loadConfig(); err != nil {
+			return err
+		}
+	} else if err := updateConfig(apply); err != nil {
+		return err
+	}
+
+	switch {
+	case value != nil:
+		a.infof("%s = %s\n", key, stored)
+	case changed:
+		if v, err := cfg.GetSetting(key); err == nil && v.Source == config.SourceSystem {
+			a.infof("%s now follows the system config (%s).\n", key, v.Value)
+		} else {
+			a.infof("%s restored to its default (%s).\n", key, cfg.SettingString(key))
+		}
+	default:
+		a.infof("%s was not set.\n", key)
+	}
+	if env := os.Getenv(s.EnvVar()); env != "" {
+		a.warnf("%s=%s is set and takes precedence over the config\n", s.EnvVar(), env)
+	}
+
+	if key == config.SettingAutoSwitch && changed {
+		refreshShells(a, cfg.SettingBool(config.SettingAutoSwitch))
+	}
+	return nil
+}
+
+// refreshShells rewrites the managed block of every configured shell profile.
+func refreshShells(a *app, autoSwitch bool) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return
+	}
+	linkPath, err := paths.CurrentLink()
+	if err != nil {
+		return
+	}
+	for _, sh := range shell.Detect(home) {
+		if !sh.IsConfigured() {
+			continue
+		}
+		sh.AutoSwitch = autoSwitch
+		changed, err := sh.Install(linkPath)
+		if err != nil {
+			a.warnf("could not update %s: %v\n", sh.Profile, err)
+		} else if changed {
+			a.infof("Updated %s; open a new shell to apply it.\n", sh.Profile)
+		}
+	}
+}
+
+// fetchOptions builds download options from the mirror, image-type and default-vendor settings.
+func fetchOptions(cfg *config.Config) (fetcher.Options, error) {
+	opts := fetcher.DefaultOptions()
+	for _, key := range []string{config.SettingMirror, config.SettingImageType, config.SettingDefaultVendor} {
+		if _, err := cfg.GetSetting(key); err != nil {
+			return opts, err
+		}
+	}
+	vendor, err := fetcher.VendorParam(cfg.SettingString(config.SettingDefaultVendor))
+	if err != nil {
+		return opts, fmt.Errorf("%w; change the %s setting", err, config.SettingDefaultVendor)
+	}
+	opts.APIBase = cfg.SettingString(config.SettingMirror)
+	opts.ImageType = cfg.SettingString(config.SettingImageType)
+	opts.Vendor = vendor
+	return opts, nil
+}
+
+// installDir returns where install puts JDKs: the install-dir setting, or versions/ in the data directory.
+func installDir(cfg *config.Config) (string, error) {
+	v, err := cfg.GetSetting(config.SettingInstallDir)
+	if err != nil {
+		return "", configError(err)
+	}
+	if v.Value != "" {
+		return v.Value, nil
+	}
+	return paths.VersionsDir()
+}
+
+// isWithin reports whether path is dir or lies inside it.
+func isWithin(dir, path string) bool {
+	rel, err := filepath.Rel(dir, path)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+func runConfigRepair(a *app, dryRun bool) error {
+	report, err := config.Repair(dryRun)
+	if err != nil {
+		return configError(err)
+	}
+
+	if !report.Changed() {
+		a.infof("%s is healthy.\n", report.Path)
+		return nil
+	}
+	for _, note := range report.Recovered {
+		fmt.Fprintf(a.stdout, "recovered: %s\n", note)
+	}
+	for _, p := range report.Fixed {
+		fmt.Fprintf(a.stdout, "fixed: %s %s\n", p.Message, p.Fix)
+	}
+	if dryRun {
+		a.infof("Dry run: %s was not changed.\n", report.Path)
+		return nil
+	}
+	a.infof("Repaired %s.\n", report.Path)
+	if len(report.Recovered) > 0 {
+		a.infof("The damaged file was kept as %s.corrupt.\n", report.Path)
+	}
+
+	// Keep the environment in step with a selection that may have changed.
+	if path := report.Config.CurrentVersionPath(report.Config.CurrentVersion); path != "" {
+		if err := switcher.Switch(path); err != nil {
+			a.warnf("could not relink the current version: %v\n", err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,157 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+	"github.com/user/jswitch/pkg/config"
+	"github.com/user/jswitch/pkg/dedupe"
+	"github.com/user/jswitch/pkg/output"
+)
+
+func newDedupeCmd(a *app) *cobra.Command {
+	var opts dedupe.Options
+	cmd := &cobra.Command{
+		Use:   "dedupe",
+		Short: "Hardlink identical files across installed JDKs",
+		Long: "Find files that are identical in several installed JDKs (by size, permissions and SHA-256)\n" +
+			"and replace them with hardlinks to a single copy, or with copy-on-write clones with --reflink.\n" +
+			"Builds of the same feature release typically share most of their files.\n\n" +
+			"Only install-dir (versions/ in the jswitch data directory by default) is changed. Set\n" +
+			"dedupe-after-install to run it after every install, and see the result with 'jswitch du'.",
+		Args: exactArgs(0),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runDedupe(a, opts)
+		},
+	}
+	cmd.Flags().BoolVar(&opts.DryRun, "dry-run", false, "report the duplicates and the space they take without changing anything")
+	cmd.Flags().BoolVar(&opts.Reflink, "reflink", false, "use copy-on-write clones instead of hardlinks (Btrfs, XFS)")
+	return cmd
+}
+
+func runDedupe(a *app, opts dedupe.Options) error {
+	cfg, err := loadConfig()
+	if err != nil {
+		return err
+	}
+	dir, err := installDir(cfg)
+	if err != nil {
+		return err
+	}
+	if _, err := os.Stat(dir); os.IsNotExist(err) {
+		a.infof("Nothing to deduplicate: %s does not exist.\n", dir)
+		return nil
+	}
+
+	report, err := dedupe.Dedupe(dir, opts)
+	if errors.Is(err, dedupe.ErrUnsupported) {
+		return environmentError(err)
+	}
+	link := "hardlinks"
+	if opts.Reflink {
+		link = "reflinks"
+	}
+	switch {
+	case err != nil:
+		if report.Replaced > 0 {
+			a.infof("Replaced %d files with %s, saving %s, before failing.\n", report.Replaced, link, dedupe.FormatBytes(report.Saved))
+		}
+		return environmentError(err)
+	case report.Replaced == 0:
+		a.infof("No duplicate files among the %d files in %s.\n", report.Files, dir)
+	case opts.DryRun:
+		a.infof("Dry run: %d of %d files would be replaced with %s, saving %s.\n", report.Replaced, report.Files, link, dedupe.FormatBytes(report.Saved))
+	default:
+		a.infof("Replaced %d of %d files with %s, saving %s.\n", report.Replaced, report.Files, link, dedupe.FormatBytes(report.Saved))
+	}
+	return nil
+}
+
+// dedupeAfterInstall applies the dedupe-after-install setting to dir after an install.
+// Failures are reported as warnings: the install itself has already succeeded.
+func dedupeAfterInstall(a *app, dir string) {
+	cfg, err := config.LoadConfig()
+	if err != nil || !cfg.SettingBool(config.SettingDedupe) {
+		return
+	}
+	report, err := dedupe.Dedupe(dir, dedupe.Options{})
+	if err != nil {
+		a.warnf("could not deduplicate %s: %v (run 'jswitch dedupe' to retry)\n", dir, err)
+		return
+	}
+	// Like the download progress, this goes to stderr so that structured output stays clean.
+	if report.Replaced > 0 && !a.quiet {
+		fmt.Fprintf(a.stderr, "Hardlinked %d files identical to other installations, saving %s.\n", report.Replaced, dedupe.FormatBytes(report.Saved))
+	}
+}
+
+func newDuCmd(a *app) *cobra.Command {
+	var out outputFlags
+	cmd := &cobra.Command{
+		Use:   "du",
+		Short: "Show the disk usage of each installation",
+		Long: "Show the size of each installation, how much of it is shared with other installations through\n" +
+			"hardlinks (see 'jswitch dedupe') and how much is unique to it, which is what removing it frees.\n" +
+			"The total counts shared files once.",
+		Args: exactArgs(0),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			opts, err := out.options()
+			if err != nil {
+				return err
+			}
+			return runDu(a, opts)
+		},
+	}
+	out.register(cmd)
+	return cmd
+}
+
+func runDu(a *app, opts output.Options) error {
+	cfg, err := loadConfig()
+	if err != nil {
+		return err
+	}
+	dirs := make([]string, len(cfg.Installations))
+	for i, inst := range cfg.Installations {
+		dirs[i] = inst.Path
+	}
+	usage, total, err := dedupe.DiskUsage(dirs)
+	if err != nil {
+		return err
+	}
+
+	report := output.DiskUsage{Installations: []output.InstallationUsage{}, Total: total}
+	for i, u := range usage {
+		report.Installations = append(report.Installations, output.InstallationUsage{
+			Version: cfg.Installations[i].Version,
+			Path:    u.Path,
+			Size:    u.Size,
+			Shared:  u.Shared,
+			Unique:  u.Unique(),
+		})
+		report.Saved += u.Size
+	}
+	report.Saved -= total
+
+	if opts.Structured() {
+		return opts.Write(a.stdout, output.KindDiskUsage, report)
+	}
+	if len(report.Installations) == 0 {
+		a.infof("No Java installations registered.\n")
+		return nil
+	}
+
+	w := tabwriter.NewWriter(a.stdout, 0, 0, 3, ' ', 0)
+	fmt.Fprintln(w, "VERSION\tSIZE\tSHARED\tUNIQUE\tPATH")
+	for _, u := range report.Installations {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", u.Version, dedupe.FormatBytes(u.Size), dedupe.FormatBytes(u.Shared), dedupe.FormatBytes(u.Unique), u.Path)
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+	fmt.Fprintf(a.stdout, "\nTotal on disk: %s (%s saved by hardlinks)\n", dedupe.FormatBytes(report.Total), dedupe.FormatBytes(report.Saved))
+	return nil
+}
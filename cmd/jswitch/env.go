@@ -0,0 +1,230 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+	"github.com/user/jswitch/pkg/config"
+	"github.com/user/jswitch/pkg/models"
+	"github.com/user/jswitch/pkg/shell"
+)
+
+func newEnvCmd(a *app) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "env",
+		Short: "Manage environment variables attached to installations and aliases",
+		Long: "Attach environment variables, such as JAVA_TOOL_OPTIONS or JDK_JAVA_OPTIONS, to an installation or\n" +
+			"alias. The shell integration ('jswitch setup') sets them whenever a matching JDK is active and unsets\n" +
+			"them when switching away.\n\n" +
+			"Variables attach to what you name: a major version (21) applies to every 21 build, a vendor and\n" +
+			"major version (temurin-21) to that vendor's, an exact version or path to one build, and an alias to\n" +
+			"whatever it points at. When several apply, the more specific one wins.",
+		Args: exactArgs(0),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runEnvList(a, "")
+		},
+	}
+
+	cmd.AddCommand(
+		&cobra.Command{
+			Use:               "set <version|alias> <NAME=VALUE>...",
+			Short:             "Attach environment variables to an installation or alias",
+			Args:              minimumArgs(2),
+			ValidArgsFunction: a.completeVersionArg(0),
+			RunE: func(cmd *cobra.Command, args []string) error {
+				return runEnvSet(a, args[0], args[1:])
+			},
+		},
+		&cobra.Command{
+			Use:               "unset <version|alias> <NAME>...",
+			Aliases:           []string{"rm"},
+			Short:             "Detach environment variables from an installation or alias",
+			Args:              minimumArgs(2),
+			ValidArgsFunction: a.completeEnvUnset,
+			RunE: func(cmd *cobra.Command, args []string) error {
+				return runEnvUnset(a, args[0], args[1:])
+			},
+		},
+		&cobra.Command{
+			Use:               "list [version|alias]",
+			Aliases:           []string{"ls"},
+			Short:             "List the attached variables, or those that apply while one installation is active",
+			Args:              rangeArgs(0, 1),
+			ValidArgsFunction: a.completeVersionArg(0),
+			RunE: func(cmd *cobra.Command, args []string) error {
+				spec := ""
+				if len(args) > 0 {
+					spec = args[0]
+				}
+				return runEnvList(a, spec)
+			},
+		},
+		&cobra.Command{
+			Use:       "shell <shell>",
+			Short:     "Print the commands that apply the variables of the JDK at $JAVA_HOME",
+			Long:      "Print the commands the shell integration evaluates to set the variables of the JDK at $JAVA_HOME and unset\nthose it set for the previous one. 'jswitch setup' adds the calls to your shell profile.",
+			Args:      exactArgs(1),
+			ValidArgs: shell.Names,
+			RunE: func(cmd *cobra.Command, args []string) error {
+				return runEnvShell(a, args[0])
+			},
+		},
+	)
+	return cmd
+}
+
+func runEnvSet(a *app, spec string, assignments []string) error {
+	vars := make(map[string]string)
+	var names []string
+	for _, arg := range assignments {
+		name, value, ok := strings.Cut(arg, "=")
+		if !ok {
+			return usageErrorf("expected NAME=VALUE, got %q", arg)
+		}
+		if err := config.ValidateEnvName(name); err != nil {
+			return usageErrorf("%v", err)
+		}
+		if _, seen := vars[name]; !seen {
+			names = append(names, name)
+		}
+		vars[name] = value
+	}
+
+	err := updateConfig(func(c *config.Config) error {
+		for _, name := range names {
+			if err := c.SetEnv(spec, name, vars[name]); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	for _, name := range names {
+		a.infof("Set %s=%s for %s.\n", name, vars[name], spec)
+	}
+	return nil
+}
+
+func runEnvUnset(a *app, spec string, names []string) error {
+	var missing []string
+	err := updateConfig(func(c *config.Config) error {
+		for _, name := range names {
+			if !c.UnsetEnv(spec, name) {
+				missing = append(missing, name)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	for _, name := range names {
+		if !slices.Contains(missing, name) {
+			a.infof("Unset %s for %s.\n", name, spec)
+		}
+	}
+	if len(missing) > 0 {
+		return &cliError{code: exitNotFound, err: fmt.Errorf("%s not set for %s; see 'jswitch env list'", strings.Join(missing, ", "), spec)}
+	}
+	return nil
+}
+
+func runEnvList(a *app, spec string) error {
+	cfg, err := loadConfig()
+	if err != nil {
+		return err
+	}
+
+	if spec != "" {
+		inst, err := cfg.Resolve(spec)
+		if err != nil {
+			return err
+		}
+		env := cfg.EnvFor(inst)
+		if len(env) == 0 {
+			a.infof("No variables apply while Java %s is active.\n", inst.Version)
+			return nil
+		}
+		for _, name := range sortedKeys(env) {
+			fmt.Fprintf(a.stdout, "%s=%s\n", name, env[name])
+		}
+		return nil
+	}
+
+	specs := cfg.EnvSpecs()
+	if len(specs) == 0 {
+		a.infof("No environment variables attached. Attach one with 'jswitch env set <version|alias> NAME=VALUE'.\n")
+		return nil
+	}
+	w := tabwriter.NewWriter(a.stdout, 0, 0, 3, ' ', 0)
+	fmt.Fprintln(w, "FOR\tNAME\tVALUE\tACTIVE WITH")
+	for _, spec := range specs {
+		var versions []string
+		for _, inst := range cfg.Installations {
+			if cfg.EnvMatches(spec, inst) {
+				versions = append(versions, inst.Version)
+			}
+		}
+		active := strings.Join(versions, ", ")
+		if active == "" {
+			active = "(no installation)"
+		}
+		vars := cfg.Env[spec]
+		for _, name := range sortedKeys(vars) {
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", spec, name, vars[name], active)
+		}
+	}
+	return w.Flush()
+}
+
+// runEnvShell prints the commands that make the shell's variables those of the installation
+// at $JAVA_HOME, or of the current version when JAVA_HOME is not set.
+func runEnvShell(a *app, name string) error {
+	vars := map[string]string{}
+	if cfg, err := config.LoadConfig(); err == nil {
+		if inst, ok := activeForEnv(cfg, os.Getenv("JAVA_HOME")); ok {
+			vars = cfg.EnvFor(inst)
+		}
+	}
+	script, err := shell.EnvScript(name, shell.Applied(), vars)
+	if err != nil {
+		return usageErrorf("%v", err)
+	}
+	fmt.Fprint(a.stdout, script)
+	return nil
+}
+
+// activeForEnv returns the installation at home, following the current link.
+func activeForEnv(cfg *config.Config, home string) (models.JavaInstallation, bool) {
+	if home == "" {
+		inst, err := cfg.Resolve(cfg.CurrentVersion)
+		return inst, err == nil
+	}
+	target, err := filepath.EvalSymlinks(home)
+	if err != nil {
+		return models.JavaInstallation{}, false
+	}
+	for _, inst := range cfg.Installations {
+		if path, err := filepath.EvalSymlinks(inst.Path); err == nil && path == target {
+			return inst, true
+		}
+	}
+	return models.JavaInstallation{}, false
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
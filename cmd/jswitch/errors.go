@@ -0,0 +1,139 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/spf13/cobra"
+	"github.com/user/jswitch/pkg/config"
+	"github.com/user/jswitch/pkg/resolver"
+)
+
+// Exit codes. These are part of the CLI contract and documented in README.md.
+const (
+	exitOK          = 0
+	exitError       = 1 // unspecified failure, or doctor found errors
+	exitUsage       = 2 // bad command, flag or argument
+	exitNotFound    = 3 // version, alias or tool could not be resolved
+	exitConfig      = 4 // config could not be read or written
+	exitNetwork     = 5 // download or remote API failure
+	exitEnvironment = 6 // JAVA_HOME/PATH/symlink could not be updated
+)
+
+// cliError attaches an exit code to an error.
+// A nil err means the failure has already been reported and nothing more should be printed.
+type cliError struct {
+	code int
+	err  error
+}
+
+func (e *cliError) Error() string {
+	if e.err == nil {
+		return fmt.Sprintf("exit status %d", e.code)
+	}
+	return e.err.Error()
+}
+
+func (e *cliError) Unwrap() error {
+	return e.err
+}
+
+func usageErrorf(format string, args ...any) error {
+	return &cliError{code: exitUsage, err: fmt.Errorf(format, args...)}
+}
+
+func configError(err error) error {
+	return &cliError{code: exitConfig, err: err}
+}
+
+func networkError(err error) error {
+	return &cliError{code: exitNetwork, err: err}
+}
+
+func environmentError(err error) error {
+	return &cliError{code: exitEnvironment, err: err}
+}
+
+// silentError fails with code without printing anything further.
+func silentError(code int) error {
+	return &cliError{code: code}
+}
+
+func isSilent(err error) bool {
+	var ce *cliError
+	return errors.As(err, &ce) && ce.err == nil
+}
+
+// exitCode maps an error returned by a command to the process exit status.
+func exitCode(err error) int {
+	if err == nil {
+		return exitOK
+	}
+
+	var ce *cliError
+	if errors.As(err, &ce) {
+		return ce.code
+	}
+	var pe *config.ParseError
+	if errors.As(err, &pe) || errors.Is(err, config.ErrNewerSchema) {
+		return exitConfig
+	}
+	var nf *config.NotFoundError
+	if errors.As(err, &nf) || errors.Is(err, resolver.ErrNoVersion) {
+		return exitNotFound
+	}
+	return exitError
+}
+
+// exactArgs is cobra.ExactArgs reporting a usage error.
+func exactArgs(n int) cobra.PositionalArgs {
+	return func(cmd *cobra.Command, args []string) error {
+		if len(args) != n {
+			return usageErrorf("%s expects %d argument(s), got %d\nUsage: %s", cmd.Name(), n, len(args), cmd.UseLine())
+		}
+		return nil
+	}
+}
+
+// rangeArgs is cobra.RangeArgs reporting a usage error.
+func rangeArgs(min, max int) cobra.PositionalArgs {
+	return func(cmd *cobra.Command, args []string) error {
+		if len(args) < min || len(args) > max {
+			return usageErrorf("%s expects %d to %d argument(s), got %d\nUsage: %s", cmd.Name(), min, max, len(args), cmd.UseLine())
+		}
+		return nil
+	}
+}
+
+// minimumArgs is cobra.MinimumNArgs reporting a usage error.
+func minimumArgs(n int) cobra.PositionalArgs {
+	return func(cmd *cobra.Command, args []string) error {
+		if len(args) < n {
+			return usageErrorf("%s expects at least %d argument(s), got %d\nUsage: %s", cmd.Name(), n, len(args), cmd.UseLine())
+		}
+		return nil
+	}
+}
+
+// loadConfig loads the config, mapping failures to exitConfig.
+func loadConfig() (*config.Config, error) {
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		return nil, configError(err)
+	}
+	return cfg, nil
+}
+
+// updateConfig runs config.Update, mapping lock, load and save failures to exitConfig
+// while passing errors returned by fn through unchanged.
+func updateConfig(fn func(cfg *config.Config) error) error {
+	var fnErr error
+	err := config.Update(func(cfg *config.Config) error {
+		fnErr = fn(cfg)
+		return fnErr
+	})
+	if err != nil && fnErr == nil {
+		return configError(err)
+	}
+	return err
+}
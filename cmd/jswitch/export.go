@@ -0,0 +1,280 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/spf13/cobra"
+	"github.com/user/jswitch/pkg/config"
+	"github.com/user/jswitch/pkg/export"
+	"github.com/user/jswitch/pkg/models"
+	"github.com/user/jswitch/pkg/scanner"
+)
+
+func newExportCmd(a *app) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "export",
+		Short: "Register installations with build tools and IDEs",
+		Long: "Write the configured installations into the files build tools and IDEs read.\n" +
+			"Only entries written by jswitch are replaced; everything else in those files is kept.",
+		Args: exactArgs(0),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return cmd.Help()
+		},
+	}
+
+	var maven exportFlags
+	mavenCmd := &cobra.Command{
+		Use:   "maven-toolchains",
+		Short: "Write a JDK toolchain per installation to ~/.m2/toolchains.xml",
+		Long: "Write a JDK toolchain per installation to ~/.m2/toolchains.xml, for the\n" +
+			"maven-toolchains-plugin and Maven 4. Toolchains written by jswitch carry an id starting\n" +
+			"with 'jswitch-'; other toolchains, comments and formatting are left as they are.\n" +
+			"The previous file is kept as toolchains.xml.jswitch.bak.\n\n" +
+			"Set sync-maven-toolchains to true to refresh the file after every install and scan.",
+		Args: exactArgs(0),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cfg, err := loadConfig()
+			if err != nil {
+				return err
+			}
+			return runExport(a, maven, cfg.Installations, homeFile(export.MavenToolchainsFile), replaceAll(export.MavenToolchains))
+		},
+	}
+	maven.register(mavenCmd, "toolchains file to update (default ~/.m2/toolchains.xml)")
+
+	var gradle exportFlags
+	var importJDKs bool
+	gradleCmd := &cobra.Command{
+		Use:   "gradle",
+		Short: "Add installations to org.gradle.java.installations.paths in ~/.gradle/gradle.properties",
+		Long: "Add the installation directories to org.gradle.java.installations.paths in the user\n" +
+			"gradle.properties ($GRADLE_USER_HOME, default ~/.gradle), so Gradle toolchains can use them.\n" +
+			"Paths jswitch added are recorded in a '# jswitch-managed:' comment above the property;\n" +
+			"paths you added yourself and all other properties are kept.\n\n" +
+			"With --import-jdks, JDKs Gradle provisioned into its jdks/ directory are added to jswitch first.",
+		Args: exactArgs(0),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runExportGradle(a, gradle, importJDKs)
+		},
+	}
+	gradle.register(gradleCmd, "properties file to update (default ~/.gradle/gradle.properties)")
+	gradleCmd.Flags().BoolVar(&importJDKs, "import-jdks", false, "add JDKs provisioned by Gradle to the jswitch config first")
+
+	var intellij exportFlags
+	intellijCmd := &cobra.Command{
+		Use:   "intellij",
+		Short: "Register installations as JDKs in IntelliJ IDEA",
+		Long: "Add every installation to jdk.table.xml in each IntelliJ IDEA configuration directory\n" +
+			"found (Ultimate and Community). JDKs are matched on their home directory: one that is\n" +
+			"already registered is left as it is. --remove unregisters the JDKs whose home is a jswitch\n" +
+			"installation. Close the IDE first; it rewrites the file when it exits.",
+		Args: exactArgs(0),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cfg, err := loadConfig()
+			if err != nil {
+				return err
+			}
+			return runExport(a, intellij, cfg.Installations, export.IntelliJJDKTables, export.IntelliJJDKTable)
+		},
+	}
+	intellij.register(intellijCmd, "jdk.table.xml to update (default: every IntelliJ IDEA config directory)")
+
+	var vscode exportFlags
+	vscodeCmd := &cobra.Command{
+		Use:   "vscode",
+		Short: "Add installations to java.configuration.runtimes in VS Code",
+		Long: "Add the newest installation of each major version to java.configuration.runtimes in the\n" +
+			"user settings.json of VS Code (and Insiders or VSCodium, if installed). Runtimes are matched\n" +
+			"on their path: one that is already listed, or a version that already has a runtime, is left\n" +
+			"as it is. --remove drops the runtimes whose path is a jswitch installation. Comments and\n" +
+			"all other settings are kept.",
+		Args: exactArgs(0),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cfg, err := loadConfig()
+			if err != nil {
+				return err
+			}
+			return runExport(a, vscode, cfg.Installations, export.VSCodeSettingsFiles, export.VSCodeSettings)
+		},
+	}
+	vscode.register(vscodeCmd, "settings.json to update (default: the VS Code user settings)")
+
+	cmd.AddCommand(mavenCmd, gradleCmd, intellijCmd, vscodeCmd)
+	return cmd
+}
+
+// exportFlags are the flags shared by every export target.
+type exportFlags struct {
+	file   string
+	remove bool
+	dryRun bool
+}
+
+func (f *exportFlags) register(cmd *cobra.Command, fileUsage string) {
+	cmd.Flags().StringVar(&f.file, "file", "", fileUsage)
+	cmd.Flags().BoolVar(&f.remove, "remove", false, "remove the entries written by jswitch")
+	cmd.Flags().BoolVar(&f.dryRun, "dry-run", false, "print the resulting file instead of writing it")
+}
+
+// exporter updates the file at path to list installations, or with remove set, to no longer list them.
+type exporter func(path string, installations []models.JavaInstallation, remove, dryRun bool) (export.Result, []byte, error)
+
+// replaceAll adapts an export that rewrites all jswitch entries at once, where removing means
+// exporting no installations.
+func replaceAll(write func(string, []models.JavaInstallation, bool) (export.Result, []byte, error)) exporter {
+	return func(path string, installations []models.JavaInstallation, remove, dryRun bool) (export.Result, []byte, error) {
+		if remove {
+			installations = nil
+		}
+		return write(path, installations, dryRun)
+	}
+}
+
+// homeFile adapts a function naming a single file in the home directory.
+func homeFile(name func(home string) string) func(home string) ([]string, error) {
+	return func(home string) ([]string, error) {
+		return []string{name(home)}, nil
+	}
+}
+
+// runExport writes installations to the flagged file, or to the default files for the home directory.
+func runExport(a *app, flags exportFlags, installations []models.JavaInstallation, defaultFiles func(home string) ([]string, error), write exporter) error {
+	files := []string{flags.file}
+	if flags.file == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return environmentError(fmt.Errorf("could not determine the home directory: %w", err))
+		}
+		if files, err = defaultFiles(home); err != nil {
+			return environmentError(err)
+		}
+		if len(files) == 0 {
+			return &cliError{code: exitNotFound, err: fmt.Errorf("no configuration directory found; pass --file")}
+		}
+	}
+
+	for _, file := range files {
+		result, doc, err := write(file, installations, flags.remove, flags.dryRun)
+		if err != nil {
+			return err
+		}
+
+		switch {
+		case flags.dryRun:
+			if len(files) > 1 {
+				fmt.Fprintf(a.stdout, "==> %s <==\n", file)
+			}
+			a.stdout.Write(doc)
+		case !result.Changed:
+			a.infof("%s is up to date.\n", result.Path)
+		case flags.remove:
+			a.infof("Removed the jswitch entries from %s.\n", result.Path)
+		default:
+			a.infof("Wrote %d installation(s) to %s.\n", result.Entries, result.Path)
+		}
+	}
+	return nil
+}
+
+func runExportGradle(a *app, flags exportFlags, importJDKs bool) error {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return environmentError(fmt.Errorf("could not determine the home directory: %w", err))
+	}
+	jdks := filepath.Join(export.GradleUserHome(home), "jdks")
+
+	var cfg *config.Config
+	if importJDKs && !flags.remove {
+		cfg, err = importGradleJDKs(a, jdks)
+	} else {
+		cfg, err = loadConfig()
+	}
+	if err != nil {
+		return err
+	}
+
+	// Gradle finds the JDKs it provisioned on its own.
+	var installations []models.JavaInstallation
+	for _, inst := range cfg.Installations {
+		if !isWithin(jdks, inst.Path) {
+			installations = append(installations, inst)
+		}
+	}
+	gradleFile := func(home string) string {
+		return filepath.Join(export.GradleUserHome(home), "gradle.properties")
+	}
+	return runExport(a, flags, installations, homeFile(gradleFile), replaceAll(export.GradleProperties))
+}
+
+// importGradleJDKs adds the JDKs found in Gradle's jdks directory to the config and returns it.
+func importGradleJDKs(a *app, dir string) (*config.Config, error) {
+	cfg, err := loadConfig()
+	if err != nil {
+		return nil, err
+	}
+	found, err := scanner.ScanSystem([]string{dir}, cfg.SettingInt(config.SettingParallelism))
+	if err != nil {
+		a.warnf("error scanning %s: %v\n", dir, err)
+	}
+	if len(found) == 0 {
+		a.infof("No JDKs found in %s.\n", dir)
+		return cfg, nil
+	}
+
+	err = updateConfig(func(c *config.Config) error {
+		cfg = c
+		for _, inst := range found {
+			addFound(a, c, inst, inst.Path)
+		}
+		return nil
+	})
+	return cfg, err
+}
+
+// addFound adds an installation found on disk to c and reports it as imported from from.
+// Versions must be unique, so a version that is already installed elsewhere is skipped.
+// Returns whether inst was added.
+func addFound(a *app, c *config.Config, inst models.JavaInstallation, from string) bool {
+	if existing := c.CurrentVersionPath(inst.Version); existing != "" {
+		if existing != inst.Path {
+			a.infof("Skipped Java %s at %s: already installed at %s.\n", inst.Version, inst.Path, existing)
+		}
+		return false
+	}
+	c.AddInstallation(inst)
+	a.infof("Imported Java %s from %s.\n", inst.Version, from)
+	return true
+}
+
+// syncExports refreshes the exports enabled by sync-* settings after the installation list
+// changed. Failures are reported as warnings: the command itself has already succeeded.
+func syncExports(a *app) {
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		return
+	}
+	if cfg.SettingBool(config.SettingSyncMavenToolchains) {
+		syncExport(a, "maven-toolchains", func(home string) (export.Result, error) {
+			result, _, err := export.MavenToolchains(export.MavenToolchainsFile(home), cfg.Installations, false)
+			return result, err
+		})
+	}
+}
+
+func syncExport(a *app, name string, run func(home string) (export.Result, error)) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		a.warnf("could not sync %s: %v\n", name, err)
+		return
+	}
+	result, err := run(home)
+	if err != nil {
+		a.warnf("could not sync %s: %v (run 'jswitch export %s' to retry)\n", name, err, name)
+		return
+	}
+	if result.Changed {
+		a.infof("Updated %s.\n", result.Path)
+	}
+}
@@ -0,0 +1,205 @@
+package main
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/user/jswitch/pkg/config"
+	"github.com/user/jswitch/pkg/fileutil"
+	"github.com/user/jswitch/pkg/importer"
+	"github.com/user/jswitch/pkg/models"
+	"github.com/user/jswitch/pkg/resolver"
+	"github.com/user/jswitch/pkg/scanner"
+)
+
+func newImportCmd(a *app) *cobra.Command {
+	var projects []string
+	var dryRun bool
+	cmd := &cobra.Command{
+		Use:   "import <" + strings.Join(importer.ToolNames(), "|") + ">",
+		Short: "Take over the JDKs and defaults of another version manager",
+		Long: "Register the JDKs another version manager installed, without copying them:\n" +
+			"  sdkman  ~/.sdkman/candidates/java ($SDKMAN_DIR); the default is candidates/java/current\n" +
+			"  jenv    ~/.jenv/versions ($JENV_ROOT); the default is ~/.jenv/version\n" +
+			"  asdf    ~/.asdf/installs/java ($ASDF_DATA_DIR); the default is ~/.tool-versions\n" +
+			"  jabba   ~/.jabba/jdk ($JABBA_HOME); aliases are ~/.jabba/<name>.alias\n" +
+			"The tool's default becomes the 'default' alias and jabba aliases become aliases, unless you\n" +
+			"already have aliases of those names.\n\n" +
+			"--rewrite-projects DIR finds the tool's project files under DIR (.sdkmanrc, jenv's .java-version,\n" +
+			".tool-versions, .jabbarc) and writes the JDK they select to .java-version next to them.",
+		Args:      exactArgs(1),
+		ValidArgs: importer.ToolNames(),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			tool, ok := importer.Lookup(args[0])
+			if !ok {
+				return usageErrorf("unknown tool %q (supported: %s)", args[0], strings.Join(importer.ToolNames(), ", "))
+			}
+			return runImport(a, tool, projects, dryRun)
+		},
+	}
+	cmd.Flags().StringArrayVar(&projects, "rewrite-projects", nil, "rewrite the tool's project files under this directory into .java-version (repeatable)")
+	cmd.Flags().BoolVar(&dryRun, "dry-run", false, "show what would be imported and rewritten without changing anything")
+	return cmd
+}
+
+func runImport(a *app, tool importer.Tool, projects []string, dryRun bool) error {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return fmt.Errorf("finding home directory: %w", err)
+	}
+	state, err := tool.Load(home)
+	if err != nil {
+		return &cliError{code: exitNotFound, err: err}
+	}
+
+	// Run each JDK's java -version before taking the config lock.
+	found := make(map[string]models.JavaInstallation)
+	for _, name := range state.Names() {
+		inst, err := scanner.Inspect(state.Homes[name])
+		if err != nil {
+			a.warnf("skipping %s %s: %v\n", tool.Name, name, err)
+			continue
+		}
+		if vendor := importer.Vendor(name); vendor != "" {
+			inst.Vendor = vendor
+		}
+		found[name] = inst
+	}
+	if len(found) == 0 {
+		a.infof("No JDKs found in %s.\n", state.Root)
+		return nil
+	}
+
+	// versions maps the tool's JDK names to jswitch versions.
+	versions := make(map[string]string)
+	apply := func(c *config.Config) error {
+		added := 0
+		for _, name := range state.Names() {
+			if inst, ok := found[name]; ok {
+				if addFound(a, c, inst, fmt.Sprintf("%s %s", tool.Name, name)) {
+					added++
+				}
+				versions[name] = inst.Version
+			}
+		}
+		if added == 0 {
+			a.infof("The %d JDK(s) in %s are already registered.\n", len(found), state.Root)
+		}
+		if state.Default != "" {
+			importAlias(a, c, tool.Name, config.DefaultAlias, state.Default, versions)
+		}
+		aliases := make([]string, 0, len(state.Aliases))
+		for alias := range state.Aliases {
+			aliases = append(aliases, alias)
+		}
+		sort.Strings(aliases)
+		for _, alias := range aliases {
+			importAlias(a, c, tool.Name, alias, state.Aliases[alias], versions)
+		}
+		return nil
+	}
+
+	if dryRun {
+		cfg, err := loadConfig()
+		if err != nil {
+			return err
+		}
+		apply(cfg)
+	} else {
+		if err := updateConfig(apply); err != nil {
+			return err
+		}
+		syncExports(a)
+	}
+
+	for _, dir := range projects {
+		if err := rewriteProjects(a, tool, dir, versions, dryRun); err != nil {
+			return err
+		}
+	}
+	if dryRun {
+		a.infof("Dry run: nothing was changed.\n")
+	}
+	return nil
+}
+
+// importAlias points alias at the JDK the tool calls name, keeping an alias the user already has.
+func importAlias(a *app, c *config.Config, tool, alias, name string, versions map[string]string) {
+	version, ok := versions[name]
+	if !ok {
+		a.warnf("%s %s points at %s, which was not imported\n", tool, alias, name)
+		return
+	}
+	if existing, ok := c.Aliases[alias]; ok {
+		if existing.Target != version {
+			a.infof("Kept alias %s -> %s (%s has it as %s).\n", alias, existing.Target, tool, version)
+		}
+		return
+	}
+	if _, err := c.SetAlias(alias, version); err != nil {
+		a.warnf("could not import %s alias %s: %v\n", tool, alias, err)
+		return
+	}
+	a.infof("Alias %s -> Java %s.\n", alias, version)
+}
+
+// rewriteProjects writes a .java-version next to every project file of tool under dir.
+// An existing .java-version is left alone unless it is the project file itself (jenv).
+func rewriteProjects(a *app, tool importer.Tool, dir string, versions map[string]string, dryRun bool) error {
+	var written []string
+	defer func() { rememberProjects(a, written) }()
+	return filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			a.warnf("%v\n", err)
+			return nil
+		}
+		if d.IsDir() {
+			if path != dir && (d.Name() == ".git" || d.Name() == "node_modules") {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if d.Name() != tool.ProjectFile {
+			return nil
+		}
+
+		data, err := os.ReadFile(path)
+		if err != nil {
+			a.warnf("%v\n", err)
+			return nil
+		}
+		name, ok := tool.ReadProject(data)
+		if !ok {
+			return nil
+		}
+		version, ok := versions[name]
+		if !ok {
+			a.warnf("%s selects %s, which was not imported\n", path, name)
+			return nil
+		}
+
+		target := filepath.Join(filepath.Dir(path), resolver.ProjectFileName)
+		if existing, err := resolver.ReadProjectFile(target); err == nil && existing != "" {
+			if existing == version {
+				return nil
+			}
+			if target != path {
+				a.infof("Kept %s (%s); %s selects Java %s.\n", target, existing, path, version)
+				return nil
+			}
+		}
+		if !dryRun {
+			if err := fileutil.WriteFile(target, []byte(version+"\n"), 0644); err != nil {
+				return fmt.Errorf("writing %s: %w", target, err)
+			}
+			written = append(written, filepath.Dir(target))
+		}
+		a.infof("Wrote %s to %s (from %s).\n", version, target, name)
+		return nil
+	})
+}
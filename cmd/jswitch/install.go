@@ -0,0 +1,170 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/spf13/cobra"
+	"github.com/user/jswitch/pkg/config"
+	"github.com/user/jswitch/pkg/fetcher"
+	"github.com/user/jswitch/pkg/models"
+	"github.com/user/jswitch/pkg/output"
+	"github.com/user/jswitch/pkg/prune"
+	"github.com/user/jswitch/pkg/tui"
+)
+
+func newInstallCmd(a *app) *cobra.Command {
+	var out outputFlags
+	var system bool
+	cmd := &cobra.Command{
+		Use:   "install <version>",
+		Short: "Download and install a Java version (e.g. 17)",
+		Long: "Download the latest Eclipse Temurin build of a feature release and register it.\n" +
+			"With --output json|yaml|template the download runs without the interactive UI.\n\n" +
+			"With --system, install into the shared root (/opt/jswitch, or $JSWITCH_SYSTEM_HOME) and register\n" +
+			"it in the system config, so every user of the machine can use it.",
+		Args:              exactArgs(1),
+		ValidArgsFunction: a.completeInstall,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			version, err := strconv.Atoi(args[0])
+			if err != nil {
+				return usageErrorf("version must be an integer (e.g. 17)")
+			}
+			opts, err := out.options()
+			if err != nil {
+				return err
+			}
+			if system {
+				return runInstallSystem(a, version, opts)
+			}
+			return runInstall(a, version, opts)
+		},
+	}
+	out.register(cmd)
+	cmd.Flags().BoolVar(&system, "system", false, "install into the shared root for every user")
+	return cmd
+}
+
+func runInstall(a *app, version int, opts output.Options) error {
+	cfg, err := loadConfig()
+	if err != nil {
+		return err
+	}
+	fetchOpts, err := fetchOptions(cfg)
+	if err != nil {
+		return configError(err)
+	}
+	dest, err := installDir(cfg)
+	if err != nil {
+		return err
+	}
+
+	if opts.Structured() || a.quiet {
+		return installHeadless(a, version, fetchOpts, dest, opts)
+	}
+
+	m := tui.NewDownloadModel(version, fetchOpts, dest)
+	p := tea.NewProgram(m)
+	finalModel, err := p.Run()
+	if err != nil {
+		return fmt.Errorf("running installer: %w", err)
+	}
+	m, ok := finalModel.(tui.DownloadModel)
+	if ok && m.Err() != nil {
+		// The TUI has already shown the error.
+		return silentError(exitNetwork)
+	}
+	if inst, ok := m.Installed(); ok {
+		if err := prunePatches(a, inst, dest); err != nil {
+			return err
+		}
+		syncExports(a)
+		applyCertsAfterInstall(a, inst)
+		dedupeAfterInstall(a, dest)
+	}
+	return nil
+}
+
+// installHeadless installs without the TUI and prints the new installation as a document.
+func installHeadless(a *app, version int, fetchOpts fetcher.Options, dest string, opts output.Options) error {
+	url, semver, err := fetcher.GetLatestVersion(version, fetchOpts)
+	if err != nil {
+		return networkError(err)
+	}
+
+	if err := os.MkdirAll(dest, 0755); err != nil {
+		return fmt.Errorf("creating %s: %w", dest, err)
+	}
+
+	if !a.quiet {
+		fmt.Fprintf(a.stderr, "Downloading Java %s...\n", semver)
+	}
+	path, err := fetcher.DownloadAndExtract(url, dest, nil)
+	if err != nil {
+		return networkError(err)
+	}
+
+	inst := fetcher.NewInstallation(semver, path)
+	var cfg *config.Config
+	err = updateConfig(func(c *config.Config) error {
+		cfg = c
+		c.AddInstallation(inst)
+		for _, name := range c.ReconcileAliases() {
+			if !a.quiet {
+				fmt.Fprintf(a.stderr, "Alias %s now points at %s.\n", name, c.Aliases[name].Target)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	if err := prunePatches(a, inst, dest); err != nil {
+		return err
+	}
+	syncExports(a)
+	applyCertsAfterInstall(a, inst)
+	dedupeAfterInstall(a, dest)
+
+	if opts.Structured() {
+		return opts.Write(a.stdout, output.KindInstallation, installationViews(cfg, []models.JavaInstallation{inst})[0])
+	}
+	return nil
+}
+
+// prunePatches applies the keep-patches setting after inst was installed into dest: older
+// patch releases of the same vendor and major version in dest are deleted so that only the
+// newest ones are kept. Installations 'jswitch prune' protects are never deleted.
+func prunePatches(a *app, inst models.JavaInstallation, dest string) error {
+	return updateConfig(func(cfg *config.Config) error {
+		keep := cfg.SettingInt(config.SettingKeepPatches)
+		if keep <= 0 {
+			return nil
+		}
+
+		var siblings []models.JavaInstallation
+		for _, other := range cfg.Installations {
+			if other.Vendor == inst.Vendor && other.MajorVersion == inst.MajorVersion && isWithin(dest, other.Path) {
+				siblings = append(siblings, other)
+			}
+		}
+		protected, _ := protectedInstallations(cfg)
+
+		for _, d := range prune.Plan(siblings, prune.Options{Keep: keep, Protected: protected, System: cfg.IsSystem}) {
+			old := d.Installation
+			if !d.Remove {
+				a.debugf("keeping %s: %s\n", old.Version, d.Reason)
+				continue
+			}
+			if err := os.RemoveAll(old.Path); err != nil {
+				a.warnf("could not remove %s: %v\n", old.Path, err)
+				continue
+			}
+			cfg.RemoveInstallation(old)
+			a.infof("Removed Java %s (keep-patches=%d).\n", old.Version, keep)
+		}
+		return nil
+	})
+}
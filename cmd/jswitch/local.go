@@ -0,0 +1,153 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/user/jswitch/pkg/config"
+	"github.com/user/jswitch/pkg/fileutil"
+	"github.com/user/jswitch/pkg/output"
+	"github.com/user/jswitch/pkg/resolver"
+)
+
+func newLocalCmd(a *app) *cobra.Command {
+	var detect, unset bool
+	cmd := &cobra.Command{
+		Use:   "local [version|alias]",
+		Short: "Pin the Java version for the current directory (.java-version)",
+		Long: "Without arguments, show the version pinned for the current directory and the file it comes from.\n" +
+			"With a version or alias, write it to .java-version in the current directory.\n\n" +
+			"--detect reads the Java version the build files declare (pom.xml, build.gradle(.kts), Dockerfile,\n" +
+			"GitHub workflows using setup-java), writes it to .java-version and offers to install a matching\n" +
+			"JDK if none is installed.",
+		Args:              rangeArgs(0, 1),
+		ValidArgsFunction: a.completeVersionArg(0),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			switch {
+			case detect && (unset || len(args) > 0):
+				return usageErrorf("--detect cannot be combined with --unset or a version")
+			case unset && len(args) > 0:
+				return usageErrorf("--unset does not take a version")
+			case detect:
+				return runLocalDetect(a)
+			case unset:
+				return runLocalUnset(a)
+			case len(args) == 1:
+				return runLocalSet(a, args[0])
+			default:
+				return runLocalShow(a)
+			}
+		},
+	}
+	cmd.Flags().BoolVar(&detect, "detect", false, "pin the version declared by the project's build files")
+	cmd.Flags().BoolVar(&unset, "unset", false, "remove .java-version from the current directory")
+	return cmd
+}
+
+func runLocalShow(a *app) error {
+	cfg, err := loadConfig()
+	if err != nil {
+		return err
+	}
+	pv, ok := resolver.FindProject(".", cfg.SettingBool(config.SettingDetectBuildFiles))
+	if !ok {
+		return &cliError{code: exitNotFound, err: fmt.Errorf("no %s or build file declaring a Java version here; run 'jswitch local <version>' or 'jswitch local --detect'", resolver.ProjectFileName)}
+	}
+	fmt.Fprintln(a.stdout, pv.Spec)
+	if pv.Detail != "" {
+		a.infof("  required by %s (%s)\n", pv.File, pv.Detail)
+	} else {
+		a.infof("  set by %s\n", pv.File)
+	}
+	return nil
+}
+
+func runLocalSet(a *app, spec string) error {
+	cfg, err := loadConfig()
+	if err != nil {
+		return err
+	}
+	inst, err := cfg.Resolve(spec)
+	if err != nil {
+		return err
+	}
+	if err := writeProjectFile(a, spec); err != nil {
+		return err
+	}
+	a.infof("Java %s (%s) now applies in this directory.\n", inst.Version, spec)
+	return nil
+}
+
+func runLocalUnset(a *app) error {
+	if err := os.Remove(resolver.ProjectFileName); err != nil {
+		if os.IsNotExist(err) {
+			a.infof("No %s in this directory.\n", resolver.ProjectFileName)
+			return nil
+		}
+		return fmt.Errorf("removing %s: %w", resolver.ProjectFileName, err)
+	}
+	a.infof("Removed %s.\n", resolver.ProjectFileName)
+	return nil
+}
+
+func runLocalDetect(a *app) error {
+	reqs := resolver.FindBuildRequirements(".")
+	if len(reqs) == 0 {
+		return &cliError{code: exitNotFound, err: fmt.Errorf("no build file in this directory or up to the repository root declares a Java version")}
+	}
+	req := reqs[0]
+	a.infof("Detected Java %d from %s (%s).\n", req.Major, req.File, req.Detail)
+	for _, other := range reqs[1:] {
+		if other.Major != req.Major {
+			a.warnf("%s asks for Java %d (%s)\n", other.File, other.Major, other.Detail)
+		}
+	}
+
+	if previous, err := resolver.ReadProjectFile(resolver.ProjectFileName); err == nil && previous != "" && previous != req.Spec() {
+		a.infof("Replacing %s in %s.\n", previous, resolver.ProjectFileName)
+	}
+	if err := writeProjectFile(a, req.Spec()); err != nil {
+		return err
+	}
+	a.infof("Wrote %s to %s.\n", req.Spec(), resolver.ProjectFileName)
+
+	cfg, err := loadConfig()
+	if err != nil {
+		return err
+	}
+	if _, err := cfg.Resolve(req.Spec()); err != nil {
+		var nf *config.NotFoundError
+		if !errors.As(err, &nf) {
+			return err
+		}
+		return offerInstall(a, req.Major)
+	}
+	return nil
+}
+
+// offerInstall asks whether to install the given feature release, or explains how to when stdin is not a terminal.
+func offerInstall(a *app, major int) error {
+	if !a.isInteractive() || a.quiet {
+		a.infof("No installed JDK matches Java %d; run 'jswitch install %d' to get one.\n", major, major)
+		return nil
+	}
+	fmt.Fprintf(a.stdout, "No installed JDK matches Java %d. Install it now? [y/N] ", major)
+	var answer string
+	fmt.Fscanln(a.stdin, &answer)
+	if !strings.EqualFold(answer, "y") && !strings.EqualFold(answer, "yes") {
+		a.infof("Skipped. Run 'jswitch install %d' at any time.\n", major)
+		return nil
+	}
+	return runInstall(a, major, output.Options{})
+}
+
+func writeProjectFile(a *app, spec string) error {
+	if err := fileutil.WriteFile(resolver.ProjectFileName, []byte(spec+"\n"), 0644); err != nil {
+		return fmt.Errorf("writing %s: %w", resolver.ProjectFileName, err)
+	}
+	rememberProject(a, ".")
+	return nil
+}
@@ -0,0 +1,254 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+
+	"github.com/spf13/cobra"
+	"github.com/user/jswitch/pkg/config"
+	"github.com/user/jswitch/pkg/fetcher"
+	"github.com/user/jswitch/pkg/lockfile"
+	"github.com/user/jswitch/pkg/models"
+	"github.com/user/jswitch/pkg/resolver"
+)
+
+// lockPlatforms are recorded in a new lockfile besides the one jswitch runs on.
+var lockPlatforms = map[string]bool{
+	"linux-x64":        true,
+	"linux-aarch64":    true,
+	"alpine-linux-x64": true,
+	"mac-x64":          true,
+	"mac-aarch64":      true,
+	"windows-x64":      true,
+	"windows-aarch64":  true,
+}
+
+func newLockCmd(a *app) *cobra.Command {
+	var update bool
+	cmd := &cobra.Command{
+		Use:   "lock [version]",
+		Short: "Pin the project to an exact JDK build (jswitch.lock)",
+		Long: "Write jswitch.lock in the current directory, recording the vendor, the exact version and, for\n" +
+			"each platform, the download URL and SHA-256 checksum of the newest build of a feature release.\n" +
+			"Without a version, the feature release of the project's current version is used.\n\n" +
+			"--update moves the nearest jswitch.lock to the newest build of its feature release.\n" +
+			"Commit the file, and run 'jswitch sync' to install the locked build.",
+		Args: rangeArgs(0, 1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if update {
+				if len(args) > 0 {
+					return usageErrorf("--update does not take a version; run 'jswitch lock <version>' to change the feature release")
+				}
+				return runLockUpdate(a)
+			}
+			feature := 0
+			if len(args) == 1 {
+				n, err := strconv.Atoi(args[0])
+				if err != nil || n <= 0 {
+					return usageErrorf("version must be a feature release (e.g. 21)")
+				}
+				feature = n
+			}
+			return runLock(a, feature)
+		},
+	}
+	cmd.Flags().BoolVar(&update, "update", false, "update the nearest jswitch.lock to the newest build")
+	return cmd
+}
+
+func newSyncCmd(a *app) *cobra.Command {
+	return &cobra.Command{
+		Use:   "sync",
+		Short: "Install the JDK pinned by jswitch.lock",
+		Long: "Find the nearest jswitch.lock, download the locked build for this platform if it is not\n" +
+			"installed, verify its SHA-256 checksum and register it. The project then uses it: jswitch.lock\n" +
+			"takes precedence over .java-version and build files in the same directory.",
+		Args: exactArgs(0),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runSync(a)
+		},
+	}
+}
+
+func runLock(a *app, feature int) error {
+	cfg, err := loadConfig()
+	if err != nil {
+		return err
+	}
+	if feature == 0 {
+		pv, ok := resolver.FindProject(".", cfg.SettingBool(config.SettingDetectBuildFiles))
+		if !ok {
+			return usageErrorf("no project version here; run 'jswitch lock <version>', e.g. 'jswitch lock 21'")
+		}
+		if inst, err := cfg.Resolve(pv.Spec); err == nil {
+			feature = inst.MajorVersion
+		} else {
+			feature = models.ParseMajorVersion(pv.Spec)
+		}
+		if feature <= 0 {
+			return usageErrorf("cannot tell the feature release of %s (from %s); run 'jswitch lock <version>'", pv.Spec, pv.File)
+		}
+	}
+
+	l, err := fetchLock(cfg, feature)
+	if err != nil {
+		return err
+	}
+	path, err := filepath.Abs(lockfile.FileName)
+	if err != nil {
+		return err
+	}
+	if previous, err := lockfile.Read(path); err == nil && previous.Version != l.Version {
+		a.infof("Replacing Java %s in %s.\n", previous.Version, lockfile.FileName)
+	}
+	if err := lockfile.Write(path, l); err != nil {
+		return err
+	}
+	rememberProject(a, ".")
+	a.infof("Locked Java %s for %d platforms in %s.\n", l.Version, len(l.Platforms), lockfile.FileName)
+	a.infof("Run 'jswitch sync' to install it.\n")
+	return nil
+}
+
+func runLockUpdate(a *app) error {
+	path, ok := lockfile.Find(".")
+	if !ok {
+		return &cliError{code: exitNotFound, err: fmt.Errorf("no %s here or in a parent directory; create one with 'jswitch lock <version>'", lockfile.FileName)}
+	}
+	current, err := lockfile.Read(path)
+	if err != nil {
+		return configError(err)
+	}
+	cfg, err := loadConfig()
+	if err != nil {
+		return err
+	}
+	feature := current.Feature
+	if feature <= 0 {
+		feature = models.ParseMajorVersion(current.Version)
+	}
+
+	l, err := fetchLock(cfg, feature)
+	if err != nil {
+		return err
+	}
+	if l.Version == current.Version && len(l.Platforms) == len(current.Platforms) {
+		a.infof("%s is up to date (Java %s).\n", path, current.Version)
+		return nil
+	}
+	if err := lockfile.Write(path, l); err != nil {
+		return err
+	}
+	rememberProject(a, filepath.Dir(path))
+	a.infof("Updated %s: Java %s -> %s.\n", path, current.Version, l.Version)
+	a.infof("Run 'jswitch sync' to install it.\n")
+	return nil
+}
+
+// fetchLock looks up the newest build of a feature release and describes it as a lockfile.
+func fetchLock(cfg *config.Config, feature int) (*lockfile.Lock, error) {
+	fetchOpts, err := fetchOptions(cfg)
+	if err != nil {
+		return nil, configError(err)
+	}
+	release, err := fetcher.GetLatestRelease(feature, fetchOpts)
+	if err != nil {
+		return nil, networkError(err)
+	}
+
+	l := &lockfile.Lock{
+		Feature:   feature,
+		Vendor:    "temurin",
+		ImageType: fetchOpts.ImageType,
+		Version:   release.VersionData.Semver,
+		Platforms: make(map[string]lockfile.Artifact),
+	}
+	for _, b := range release.Binaries {
+		platform := b.Platform()
+		if b.Package.Checksum == "" || (!lockPlatforms[platform] && platform != fetcher.Platform()) {
+			continue
+		}
+		l.Platforms[platform] = lockfile.Artifact{URL: b.Package.Link, Name: b.Package.Name, SHA256: b.Package.Checksum}
+	}
+	if len(l.Platforms) == 0 {
+		return nil, networkError(fmt.Errorf("Java %s has no downloads with checksums", l.Version))
+	}
+	return l, nil
+}
+
+func runSync(a *app) error {
+	path, ok := lockfile.Find(".")
+	if !ok {
+		return &cliError{code: exitNotFound, err: fmt.Errorf("no %s here or in a parent directory; create one with 'jswitch lock <version>'", lockfile.FileName)}
+	}
+	l, err := lockfile.Read(path)
+	if err != nil {
+		return configError(err)
+	}
+	rememberProject(a, filepath.Dir(path))
+	cfg, err := loadConfig()
+	if err != nil {
+		return err
+	}
+
+	// Another vendor's build, or a JRE, of the same version is not the locked build.
+	if inst, ok := cfg.FindBuild(l.Version, l.Vendor, l.ImageType); ok {
+		a.infof("Java %s is already installed at %s.\n", l.Version, inst.Path)
+	} else if err := installLocked(a, cfg, l); err != nil {
+		return err
+	}
+
+	cfg, err = loadConfig()
+	if err != nil {
+		return err
+	}
+	r, err := resolver.Active(cfg, ".")
+	switch {
+	case err != nil:
+		return err
+	case r.Origin != path:
+		a.warnf("%s applies here instead of %s: Java %s is %s\n", r.Spec, path, r.Installation.Version, r.Reason())
+	default:
+		a.infof("Java %s now applies in %s.\n", l.Version, filepath.Dir(path))
+	}
+	return nil
+}
+
+// installLocked downloads the locked build for this platform, checks it against the
+// lockfile's checksum and registers it.
+func installLocked(a *app, cfg *config.Config, l *lockfile.Lock) error {
+	artifact, err := l.Artifact(fetcher.Platform())
+	if err != nil {
+		return &cliError{code: exitNotFound, err: err}
+	}
+	dest, err := installDir(cfg)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(dest, 0755); err != nil {
+		return fmt.Errorf("creating %s: %w", dest, err)
+	}
+
+	a.infof("Downloading Java %s...\n", l.Version)
+	path, err := fetcher.DownloadVerifyAndExtract(artifact.URL, artifact.SHA256, dest, nil)
+	if err != nil {
+		return networkError(err)
+	}
+	inst := fetcher.NewInstallation(l.Version, path)
+	if err := updateConfig(func(c *config.Config) error {
+		c.AddInstallation(inst)
+		for _, name := range c.ReconcileAliases() {
+			a.infof("Alias %s now points at %s.\n", name, c.Aliases[name].Target)
+		}
+		return nil
+	}); err != nil {
+		return err
+	}
+	a.infof("Installed Java %s at %s (SHA-256 verified).\n", l.Version, path)
+	syncExports(a)
+	applyCertsAfterInstall(a, inst)
+	dedupeAfterInstall(a, dest)
+	return nil
+}
@@ -2,213 +2,158 @@ package main
 
 import (
 	"fmt"
+	"io"
 	"os"
-	"path/filepath"
-	"runtime"
-	"strconv"
-	"text/tabwriter"
 
-	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/muesli/termenv"
+	"github.com/spf13/cobra"
 	"github.com/user/jswitch/pkg/config"
-	"github.com/user/jswitch/pkg/scanner"
-	"github.com/user/jswitch/pkg/switcher"
-	"github.com/user/jswitch/pkg/tui"
 )
 
-func main() {
-	if len(os.Args) < 2 {
-		// Default to UI if no args provided (friendly for double-clicking)
-		handleUI()
-		return
-	}
+// version is overridden at build time with -ldflags "-X main.version=..."
+var version = "dev"
 
-	command := os.Args[1]
-
-	switch command {
-	case "scan":
-		// Allow passing custom paths after "scan"
-		customPaths := os.Args[2:]
-		handleScan(customPaths)
-	case "list":
-		handleList()
-	case "use":
-		if len(os.Args) < 3 {
-			fmt.Println("Usage: jswitch use <version>")
-			return
-		}
-		version := os.Args[2]
-		handleUse(version)
-	case "ui", "select":
-		handleUI()
-	case "install":
-		if len(os.Args) < 3 {
-			fmt.Println("Usage: jswitch install <version>")
-			return
-		}
-		versionStr := os.Args[2]
-		version, err := strconv.Atoi(versionStr)
-		if err != nil {
-			fmt.Println("Version must be an integer (e.g. 17)")
-			return
-		}
-		handleInstall(version)
-	default:
-		printUsage()
-	}
-}
+// app carries global flag state and the I/O streams shared by every command.
+type app struct {
+	stdin  io.Reader
+	stdout io.Writer
+	stderr io.Writer
 
-func printUsage() {
-	fmt.Println("Usage: jswitch <command> [arguments]")
-	fmt.Println("Resulting Binary: jswitch")
-	fmt.Println("\nCommands:")
-	fmt.Println("  ui                Open interactive selection menu")
-	fmt.Println("  scan [paths...]   Scan system for Java installations")
-	fmt.Println("  list              List discovered Java versions")
-	fmt.Println("  use <version>     Select a Java version to use")
-	fmt.Println("  install <version> Download and install a Java version (e.g. 17)")
+	configPath string
+	verbose    bool
+	quiet      bool
+	noColor    bool
 }
 
-func handleScan(customPaths []string) {
-	var pathsToScan []string
-	if len(customPaths) > 0 {
-		pathsToScan = customPaths
-	} else {
-		if runtime.GOOS == "windows" {
-			pathsToScan = []string{
-				`C:\Program Files\Java`,
-				`C:\Program Files (x86)\Java`,
-			}
-		} else {
-			pathsToScan = []string{
-				"/usr/lib/jvm",
-				"/usr/java",
-				"/Library/Java/JavaVirtualMachines",
-			}
-		}
-	}
-
-	fmt.Printf("Scanning paths: %v\n", pathsToScan)
-
-	installations, err := scanner.ScanSystem(pathsToScan)
-	if err != nil {
-		fmt.Fprintf(os.Stderr, "Error scanning: %v\n", err)
-	}
-
-	cfg, err := config.LoadConfig()
-	if err != nil {
-		// If load fails, start fresh
-		cfg = &config.Config{}
-	}
-
-	if len(installations) > 0 {
-		fmt.Printf("Found %d Java installations.\n", len(installations))
-		cfg.Installations = installations
-		if err := config.SaveConfig(cfg); err != nil {
-			fmt.Fprintf(os.Stderr, "Error saving config: %v\n", err)
-		} else {
-			home, _ := os.UserHomeDir()
-			fmt.Printf("Config saved to %s\n", filepath.Join(home, ".jswitch", "config.json"))
-		}
-	} else {
-		fmt.Println("No Java installations found.")
+// infof prints progress and confirmation messages, unless --quiet is set.
+func (a *app) infof(format string, args ...any) {
+	if !a.quiet {
+		fmt.Fprintf(a.stdout, format, args...)
 	}
 }
 
-func handleList() {
-	cfg, err := config.LoadConfig()
-	if err != nil {
-		fmt.Fprintf(os.Stderr, "Error loading config: %v\n", err)
-		return
-	}
-
-	if len(cfg.Installations) == 0 {
-		fmt.Println("No installations found. Run 'jswitch scan' first.")
-		return
+// debugf prints extra detail when --verbose is set.
+func (a *app) debugf(format string, args ...any) {
+	if a.verbose {
+		fmt.Fprintf(a.stderr, format, args...)
 	}
-
-	w := tabwriter.NewWriter(os.Stdout, 0, 0, 3, ' ', 0)
-	fmt.Fprintln(w, "CURRENT\tVENDOR\tVERSION\tPATH")
-
-	for _, inst := range cfg.Installations {
-		marker := " "
-		if inst.Version == cfg.CurrentVersion {
-			marker = "*"
-		}
-
-		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", marker, inst.Vendor, inst.Version, inst.Path)
-	}
-	w.Flush()
 }
 
-func handleUse(version string) {
-	cfg, err := config.LoadConfig()
-	if err != nil {
-		fmt.Fprintf(os.Stderr, "Error loading config: %v\n", err)
-		return
-	}
-
-	found := false
-	for _, inst := range cfg.Installations {
-		if inst.Version == version {
-			cfg.CurrentVersion = inst.Version
-			found = true
-			break
-		}
-	}
-
-	if !found {
-		fmt.Printf("Version %s not found. Run 'jswitch list' to see options.\n", version)
-		return
-	}
-
-	if err := config.SaveConfig(cfg); err != nil {
-		fmt.Fprintf(os.Stderr, "Error saving config: %v\n", err)
-		return
-	}
-
-	fmt.Printf("Target set to Java %s.\n", version)
-
-	// Apply system changes
-	if err := switcher.Switch(cfg.CurrentVersionPath(version)); err != nil {
-		fmt.Fprintf(os.Stderr, "Error switching system environment: %v\n", err)
-	}
+// warnf prints warnings to stderr; they are shown even with --quiet.
+func (a *app) warnf(format string, args ...any) {
+	fmt.Fprintf(a.stderr, "Warning: "+format, args...)
 }
 
-func handleUI() {
-	cfg, err := config.LoadConfig()
-	if err != nil {
-		fmt.Fprintf(os.Stderr, "Error loading config: %v\n", err)
-		return
+// applyColor sets the color profile from --no-color, NO_COLOR and the color setting, in that order.
+func (a *app) applyColor() {
+	mode := "auto"
+	if cfg, err := config.LoadConfig(); err == nil {
+		mode = cfg.SettingString(config.SettingColor)
 	}
-
-	if len(cfg.Installations) == 0 {
-		fmt.Println("No installations found. Run 'jswitch scan' first.")
-		return
+	switch {
+	case a.noColor || os.Getenv("NO_COLOR") != "" || mode == "never":
+		lipgloss.SetColorProfile(termenv.Ascii)
+	case mode == "always":
+		lipgloss.SetColorProfile(termenv.ANSI256)
 	}
+}
 
-	initialModel := tui.NewModel(cfg.Installations, cfg.CurrentVersion)
-	p := tea.NewProgram(initialModel)
-
-	// Run the program
-	finalModel, err := p.Run()
-	if err != nil {
-		fmt.Fprintf(os.Stderr, "Error running TUI: %v\n", err)
-		return
+// checkSystemConfig warns when the system config cannot be loaded; config.LoadConfig
+// then carries on with the user config alone.
+func (a *app) checkSystemConfig() {
+	if _, err := config.LoadSystemConfig(); err != nil {
+		a.warnf("ignoring the system config: %v\n", err)
 	}
+}
 
-	// Assert back to our specific model
-	m, ok := finalModel.(tui.Model)
-	if ok && m.SelectedID != "" {
-		// Reuse handleUse logic to apply switch
-		fmt.Printf("Selected via UI: %s\n", m.SelectedID)
-		handleUse(m.SelectedID)
+func main() {
+	a := &app{stdin: os.Stdin, stdout: os.Stdout, stderr: os.Stderr}
+	err := newRootCmd(a).Execute()
+	if err != nil && !isSilent(err) {
+		fmt.Fprintf(a.stderr, "Error: %v\n", err)
 	}
+	os.Exit(exitCode(err))
 }
 
-func handleInstall(version int) {
-	m := tui.NewDownloadModel(version)
-	p := tea.NewProgram(m)
-	if _, err := p.Run(); err != nil {
-		fmt.Fprintf(os.Stderr, "Error running installer: %v\n", err)
-	}
+func newRootCmd(a *app) *cobra.Command {
+	root := &cobra.Command{
+		Use:   "jswitch",
+		Short: "Manage and switch between Java (JDK) installations",
+		Long: "J-Switch finds, installs and switches between Java installations.\n" +
+			"Running jswitch without a command opens the interactive selection menu.",
+		Version:       version,
+		SilenceUsage:  true,
+		SilenceErrors: true,
+		Args: func(cmd *cobra.Command, args []string) error {
+			if len(args) > 0 {
+				return usageErrorf("unknown command %q for %q", args[0], cmd.CommandPath())
+			}
+			return nil
+		},
+		PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+			if a.quiet && a.verbose {
+				return usageErrorf("--quiet and --verbose cannot be used together")
+			}
+			config.SetPath(a.configPath)
+			// An explicit --config is a sandbox; leave the real state alone.
+			if a.configPath == "" && cmd.Name() != cobra.ShellCompRequestCmd {
+				migrateLegacyHome(a)
+			}
+			a.applyColor()
+			if cmd.Name() != cobra.ShellCompRequestCmd {
+				a.checkSystemConfig()
+			}
+			return nil
+		},
+		// Default to UI if no args provided (friendly for double-clicking)
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runUI(a)
+		},
+	}
+
+	root.SetIn(a.stdin)
+	root.SetOut(a.stdout)
+	root.SetErr(a.stderr)
+	root.SetFlagErrorFunc(func(cmd *cobra.Command, err error) error {
+		return &cliError{code: exitUsage, err: err}
+	})
+
+	flags := root.PersistentFlags()
+	flags.StringVar(&a.configPath, "config", "", "path to the config file (default: $JSWITCH_HOME/config.json or the platform config directory)")
+	flags.BoolVarP(&a.verbose, "verbose", "v", false, "print extra detail to stderr")
+	flags.BoolVarP(&a.quiet, "quiet", "q", false, "only print errors and requested data")
+	flags.BoolVar(&a.noColor, "no-color", false, "disable colored output")
+
+	root.AddCommand(
+		newUICmd(a),
+		newScanCmd(a),
+		newListCmd(a),
+		newCurrentCmd(a),
+		newWhichCmd(a),
+		newHomeCmd(a),
+		newListRemoteCmd(a),
+		newUseCmd(a),
+		newLocalCmd(a),
+		newAliasCmd(a),
+		newInstallCmd(a),
+		newLockCmd(a),
+		newSyncCmd(a),
+		newDedupeCmd(a),
+		newDuCmd(a),
+		newPruneCmd(a),
+		newPinCmd(a),
+		newUnpinCmd(a),
+		newCertsCmd(a),
+		newEnvCmd(a),
+		newImportCmd(a),
+		newHistoryCmd(a),
+		newSetupCmd(a),
+		newDoctorCmd(a),
+		newConfigCmd(a),
+		newExportCmd(a),
+		newCompletionCmd(a),
+	)
+
+	return root
 }
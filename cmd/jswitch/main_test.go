@@ -0,0 +1,425 @@
+package main
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/user/jswitch/pkg/config"
+	"github.com/user/jswitch/pkg/fetcher"
+	"github.com/user/jswitch/pkg/lockfile"
+	"github.com/user/jswitch/pkg/models"
+	"github.com/user/jswitch/pkg/paths"
+	"github.com/user/jswitch/pkg/resolver"
+)
+
+// sandbox points every jswitch directory and $HOME at a temporary directory and changes
+// into an empty working directory, so project files of the checkout do not apply.
+func sandbox(t *testing.T) string {
+	t.Helper()
+	dir := t.TempDir()
+	t.Setenv(paths.EnvHome, filepath.Join(dir, "jswitch"))
+	t.Setenv(paths.EnvSystemHome, filepath.Join(dir, "system"))
+	t.Setenv("HOME", filepath.Join(dir, "home"))
+	t.Setenv("NO_COLOR", "1")
+	t.Setenv(resolver.EnvVar, "")
+	work := filepath.Join(dir, "work")
+	if err := os.MkdirAll(work, 0755); err != nil {
+		t.Fatal(err)
+	}
+	t.Chdir(work)
+	return dir
+}
+
+// fakeJDK creates a directory that passes switcher.Validate.
+func fakeJDK(t *testing.T, dir, name string) string {
+	t.Helper()
+	exe := "java"
+	if runtime.GOOS == "windows" {
+		exe = "java.exe"
+	}
+	home := filepath.Join(dir, "jdks", name)
+	if err := os.MkdirAll(filepath.Join(home, "bin"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(home, "bin", exe), []byte("#!/bin/sh\n"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	return home
+}
+
+// seedConfig registers Temurin 17.0.2 and 21.0.1, with 17 selected globally.
+func seedConfig(t *testing.T, dir string) {
+	t.Helper()
+	cfg := &config.Config{
+		Installations: []models.JavaInstallation{
+			{Version: "17.0.2", MajorVersion: 17, Vendor: "Temurin", Path: fakeJDK(t, dir, "jdk-17")},
+			{Version: "21.0.1", MajorVersion: 21, Vendor: "Temurin", Path: fakeJDK(t, dir, "jdk-21")},
+		},
+		CurrentVersion: "17.0.2",
+	}
+	if err := config.SaveConfig(cfg); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// run executes the root command with buffered streams and returns its output and exit code.
+func run(args ...string) (stdout, stderr string, code int) {
+	var out, errOut bytes.Buffer
+	a := &app{stdin: strings.NewReader(""), stdout: &out, stderr: &errOut}
+	cmd := newRootCmd(a)
+	cmd.SetArgs(args)
+	err := cmd.Execute()
+	if err != nil && !isSilent(err) {
+		errOut.WriteString("Error: " + err.Error() + "\n")
+	}
+	config.SetPath("")
+	return out.String(), errOut.String(), exitCode(err)
+}
+
+func TestRootCommand(t *testing.T) {
+	tests := []struct {
+		name       string
+		setup      func(t *testing.T, dir string)
+		args       []string
+		wantCode   int
+		wantStdout []string
+		wantStderr []string
+	}{
+		{
+			name:       "unknown command",
+			args:       []string{"frobnicate"},
+			wantCode:   exitUsage,
+			wantStderr: []string{`unknown command "frobnicate"`},
+		},
+		{
+			name:       "unknown flag",
+			args:       []string{"list", "--frobnicate"},
+			wantCode:   exitUsage,
+			wantStderr: []string{"unknown flag: --frobnicate"},
+		},
+		{
+			name:       "too many arguments",
+			args:       []string{"use", "17", "21"},
+			wantCode:   exitUsage,
+			wantStderr: []string{"use expects 1 argument(s), got 2"},
+		},
+		{
+			name:       "too few arguments",
+			args:       []string{"env", "set", "17"},
+			wantCode:   exitUsage,
+			wantStderr: []string{"set expects at least 2 argument(s), got 1"},
+		},
+		{
+			name:       "quiet and verbose",
+			args:       []string{"list", "-q", "-v"},
+			wantCode:   exitUsage,
+			wantStderr: []string{"--quiet and --verbose cannot be used together"},
+		},
+		{
+			name:       "use unknown version",
+			setup:      seedConfig,
+			args:       []string{"use", "11"},
+			wantCode:   exitNotFound,
+			wantStderr: []string{"Error:"},
+		},
+		{
+			name:       "use - without history",
+			setup:      seedConfig,
+			args:       []string{"use", "-"},
+			wantCode:   exitNotFound,
+			wantStderr: []string{"no previous version to switch back to"},
+		},
+		{
+			name:     "current without selection",
+			args:     []string{"current"},
+			wantCode: exitNotFound,
+		},
+		{
+			name: "corrupt config",
+			setup: func(t *testing.T, dir string) {
+				path, err := paths.ConfigFile()
+				if err != nil {
+					t.Fatal(err)
+				}
+				writeFile(t, path, "{not json")
+			},
+			args:       []string{"list"},
+			wantCode:   exitConfig,
+			wantStderr: []string{"config.json"},
+		},
+		{
+			name: "corrupt system config",
+			setup: func(t *testing.T, dir string) {
+				seedConfig(t, dir)
+				system, err := config.SystemPath()
+				if err != nil {
+					t.Fatal(err)
+				}
+				writeFile(t, system, "{not json")
+			},
+			args:       []string{"list"},
+			wantCode:   exitOK,
+			wantStdout: []string{"Temurin   17.0.2"},
+			wantStderr: []string{"Warning: ignoring the system config:"},
+		},
+		{
+			name:       "list empty",
+			args:       []string{"list"},
+			wantCode:   exitOK,
+			wantStdout: []string{"No installations found. Run 'jswitch scan' first."},
+		},
+		{
+			name:     "list",
+			setup:    seedConfig,
+			args:     []string{"list"},
+			wantCode: exitOK,
+			wantStdout: []string{
+				"CURRENT   VENDOR    VERSION   ALIASES   PATH",
+				"*         Temurin   17.0.2",
+				"          Temurin   21.0.1",
+			},
+		},
+		{
+			name:       "current",
+			setup:      seedConfig,
+			args:       []string{"current"},
+			wantCode:   exitOK,
+			wantStdout: []string{"Temurin 17.0.2 (", "selected globally with 'jswitch use'"},
+		},
+		{
+			name:       "current from JSWITCH_VERSION",
+			setup:      func(t *testing.T, dir string) { seedConfig(t, dir); t.Setenv(resolver.EnvVar, "21") },
+			args:       []string{"current"},
+			wantCode:   exitOK,
+			wantStdout: []string{"Temurin 21.0.1 (", "set by $" + resolver.EnvVar},
+		},
+		{
+			name:       "pin",
+			setup:      seedConfig,
+			args:       []string{"pin", "21"},
+			wantCode:   exitOK,
+			wantStdout: []string{"Pinned Java 21.0.1 (Temurin); prune keeps it."},
+		},
+		{
+			name:       "unpin without pin",
+			setup:      seedConfig,
+			args:       []string{"unpin", "21"},
+			wantCode:   exitOK,
+			wantStdout: []string{"Java 21.0.1 (Temurin) is not pinned."},
+		},
+		{
+			name:     "pin unknown version",
+			setup:    seedConfig,
+			args:     []string{"pin", "11"},
+			wantCode: exitNotFound,
+		},
+		{
+			name:       "use",
+			setup:      seedConfig,
+			args:       []string{"use", "21"},
+			wantCode:   exitOK,
+			wantStdout: []string{"Target set to Java 21.0.1."},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.name == "use" && runtime.GOOS == "windows" {
+				t.Skip("switching updates the user environment in the registry")
+			}
+			dir := sandbox(t)
+			if tt.setup != nil {
+				tt.setup(t, dir)
+			}
+			stdout, stderr, code := run(tt.args...)
+			if code != tt.wantCode {
+				t.Errorf("exit code = %d, want %d\nstdout:\n%s\nstderr:\n%s", code, tt.wantCode, stdout, stderr)
+			}
+			for _, want := range tt.wantStdout {
+				if !strings.Contains(stdout, want) {
+					t.Errorf("stdout does not contain %q:\n%s", want, stdout)
+				}
+			}
+			for _, want := range tt.wantStderr {
+				if !strings.Contains(stderr, want) {
+					t.Errorf("stderr does not contain %q:\n%s", want, stderr)
+				}
+			}
+		})
+	}
+}
+
+func TestUseUpdatesCurrent(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("switching updates the user environment in the registry")
+	}
+	dir := sandbox(t)
+	seedConfig(t, dir)
+
+	if _, stderr, code := run("use", "21"); code != exitOK {
+		t.Fatalf("use 21: exit code %d\n%s", code, stderr)
+	}
+	stdout, stderr, code := run("current")
+	if code != exitOK {
+		t.Fatalf("current: exit code %d\n%s", code, stderr)
+	}
+	if !strings.HasPrefix(stdout, "Temurin 21.0.1 ("+filepath.Join(dir, "jdks", "jdk-21")+")") {
+		t.Errorf("current after 'use 21' = %q", stdout)
+	}
+
+	link, err := paths.CurrentLink()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if target, err := os.Readlink(link); err != nil || target != filepath.Join(dir, "jdks", "jdk-21") {
+		t.Errorf("current link points at %q (%v), want the 21 installation", target, err)
+	}
+}
+
+func TestPruneKeepsPinnedAndLockedBuilds(t *testing.T) {
+	dir := sandbox(t)
+	temurin := func(version string) models.JavaInstallation {
+		return models.JavaInstallation{Version: version, MajorVersion: 17, Vendor: "Temurin", Path: fakeJDK(t, dir, "temurin-"+version)}
+	}
+	zulu := func(version string) models.JavaInstallation {
+		return models.JavaInstallation{Version: version, MajorVersion: 17, Vendor: "Zulu", Path: fakeJDK(t, dir, "zulu-"+version)}
+	}
+	// The project locks the Zulu JDK of a version Temurin has too.
+	locked := zulu("17.0.3")
+	writeFile(t, filepath.Join(locked.Path, "bin", "javac"), "")
+	project := filepath.Join(dir, "project")
+	if err := os.MkdirAll(project, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := lockfile.Write(filepath.Join(project, lockfile.FileName), &lockfile.Lock{Feature: 17, Vendor: "zulu", ImageType: "jdk", Version: "17.0.3"}); err != nil {
+		t.Fatal(err)
+	}
+	pinned := temurin("17.0.1")
+	cfg := &config.Config{
+		Installations:  []models.JavaInstallation{pinned, temurin("17.0.2"), temurin("17.0.3"), temurin("17.0.5"), locked, zulu("17.0.4")},
+		CurrentVersion: "17.0.5",
+		Settings:       map[string]string{config.SettingInstallDir: filepath.Join(dir, "jdks")},
+		Projects:       []string{project},
+		Pinned:         []string{pinned.Path},
+	}
+	if err := config.SaveConfig(cfg); err != nil {
+		t.Fatal(err)
+	}
+
+	stdout, stderr, code := run("prune", "--dry-run")
+	if code != exitOK {
+		t.Fatalf("prune: exit code %d\n%s", code, stderr)
+	}
+	for _, want := range []string{
+		"Keeping Java 17.0.1: it is pinned with 'jswitch pin'.",
+		"Keeping Java 17.0.3: locked by " + filepath.Join(project, lockfile.FileName) + ".",
+		"Would remove Java 17.0.2 (Temurin)",
+		"Would remove Java 17.0.3 (Temurin)",
+	} {
+		if !strings.Contains(stdout, want) {
+			t.Errorf("stdout does not contain %q:\n%s", want, stdout)
+		}
+	}
+	if strings.Contains(stdout, "Would remove Java 17.0.3 (Zulu)") {
+		t.Errorf("prune removes the locked build:\n%s", stdout)
+	}
+}
+
+// jdkServer serves an Adoptium API with one release of Java 21, a tar.gz of a fake JDK.
+func jdkServer(t *testing.T, semver string) *httptest.Server {
+	t.Helper()
+	var archive bytes.Buffer
+	gz := gzip.NewWriter(&archive)
+	tw := tar.NewWriter(gz)
+	for name, content := range map[string]string{"bin/java": "#!/bin/sh\n", "release": "JAVA_VERSION=\"21\"\n"} {
+		tw.WriteHeader(&tar.Header{Name: "jdk-" + semver + "/" + name, Mode: 0755, Size: int64(len(content)), Typeflag: tar.TypeReg})
+		tw.Write([]byte(content))
+	}
+	tw.Close()
+	gz.Close()
+
+	var srv *httptest.Server
+	srv = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/v3/assets/feature_releases/21/ga":
+			json.NewEncoder(w).Encode([]fetcher.Release{{
+				Binaries:    []fetcher.Binary{{Package: fetcher.Package{Link: srv.URL + "/jdk.tar.gz"}}},
+				VersionData: fetcher.VersionData{Semver: semver},
+			}})
+		case "/jdk.tar.gz":
+			w.Write(archive.Bytes())
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestInstallSystemReplacesDamagedInstallation(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("the fake JDK archive is a tar.gz")
+	}
+	sandbox(t)
+	const semver = "21.0.2+13"
+	srv := jdkServer(t, semver)
+	if err := config.SaveConfig(&config.Config{Settings: map[string]string{config.SettingMirror: srv.URL}}); err != nil {
+		t.Fatal(err)
+	}
+	layout, err := paths.System()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Another user deleted bin/ of the shared installation.
+	damaged := filepath.Join(layout.VersionsDir(), "jdk-"+semver)
+	writeFile(t, filepath.Join(damaged, "release"), "")
+	err = config.UpdateSystem(func(c *config.Config) error {
+		c.AddInstallation(fetcher.NewInstallation(semver, damaged))
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	stdout, stderr, code := run("install", "--system", "21")
+	if code != exitOK {
+		t.Fatalf("install --system: exit code %d\n%s%s", code, stdout, stderr)
+	}
+	if !strings.Contains(stderr, "is damaged, installing it again") || !strings.Contains(stdout, "Installed Java "+semver) {
+		t.Errorf("install --system did not reinstall the damaged JDK:\n%s%s", stdout, stderr)
+	}
+	if _, err := os.Stat(filepath.Join(damaged, "bin", "java")); err != nil {
+		t.Errorf("bin/java is still missing: %v", err)
+	}
+	system, err := config.LoadSystemConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(system.Installations) != 1 || system.Installations[0].Path != damaged {
+		t.Errorf("system installations = %+v", system.Installations)
+	}
+
+	stdout, stderr, code = run("install", "--system", "21")
+	if code != exitOK || !strings.Contains(stdout, "is already installed for all users") {
+		t.Errorf("second install --system: exit code %d\n%s%s", code, stdout, stderr)
+	}
+}
+
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
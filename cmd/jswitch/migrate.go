@@ -0,0 +1,65 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"runtime"
+
+	"github.com/user/jswitch/pkg/config"
+	"github.com/user/jswitch/pkg/paths"
+	"github.com/user/jswitch/pkg/shell"
+	"github.com/user/jswitch/pkg/switcher"
+)
+
+// migrateLegacyHome moves a legacy ~/.jswitch into the platform layout once, then points
+// the config, the current link and any managed shell profiles at the new locations.
+// Failures are warnings: the legacy directory simply stays in use.
+func migrateLegacyHome(a *app) {
+	m, err := paths.MigrateLegacy()
+	if err != nil {
+		a.warnf("could not move ~/.jswitch to the new layout: %v\n"+
+			"Set %s to the old directory to keep using it.\n", err, paths.EnvHome)
+		return
+	}
+	if m == nil {
+		return
+	}
+
+	if !a.quiet {
+		fmt.Fprintf(a.stderr, "Moved jswitch data out of %s: config in %s, JDKs in %s.\n", m.From.Config, m.To.Config, m.To.Data)
+	}
+
+	var cfg *config.Config
+	err = config.Update(func(c *config.Config) error {
+		cfg = c
+		c.RelocateInstallations(m.From.VersionsDir(), m.To.VersionsDir())
+		return nil
+	})
+	if err != nil {
+		a.warnf("could not update installation paths: %v\n", err)
+		return
+	}
+
+	if current := cfg.CurrentVersionPath(cfg.CurrentVersion); current != "" {
+		if err := switcher.Switch(current); err != nil {
+			a.warnf("could not relink %s: %v\n", m.To.CurrentLink(), err)
+		}
+	}
+
+	if runtime.GOOS == "windows" {
+		return
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return
+	}
+	for _, sh := range shell.Detect(home) {
+		if !sh.IsConfigured() {
+			continue
+		}
+		sh.AutoSwitch = cfg.SettingBool(config.SettingAutoSwitch)
+		if _, err := sh.Install(m.To.CurrentLink()); err != nil {
+			a.warnf("could not update %s: %v\n", sh.Profile, err)
+		}
+	}
+}
@@ -0,0 +1,333 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/user/jswitch/pkg/config"
+	"github.com/user/jswitch/pkg/dedupe"
+	"github.com/user/jswitch/pkg/lockfile"
+	"github.com/user/jswitch/pkg/models"
+	"github.com/user/jswitch/pkg/prune"
+	"github.com/user/jswitch/pkg/resolver"
+)
+
+// staleAfter is how long a leftover must have been untouched before prune removes it, so
+// that downloads and installs running right now are left alone.
+const staleAfter = time.Hour
+
+func newPruneCmd(a *app) *cobra.Command {
+	var keep int
+	var dryRun bool
+	cmd := &cobra.Command{
+		Use:   "prune",
+		Short: "Remove old JDK builds and the leftovers of interrupted installs",
+		Long: "Remove the installed JDKs beyond the newest --keep builds of each vendor and feature release,\n" +
+			"partial downloads, directories of failed extractions, and temporary files of interrupted\n" +
+			"'jswitch dedupe' runs that have not been touched for an hour.\n\n" +
+			"Only JDKs in install-dir are removed, and these are always kept: the current version, the one\n" +
+			"active in this directory, JDKs pinned with 'jswitch pin', alias targets, and the builds the\n" +
+			"jswitch.lock or .java-version of known projects select. Projects become known when jswitch\n" +
+			"writes those files (local, lock, sync, import --rewrite-projects); prune forgets projects whose\n" +
+			"files are gone.\n\n" +
+			"--keep defaults to the keep-patches setting, or 1 when that is 0.",
+		Args: exactArgs(0),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if cmd.Flags().Changed("keep") && keep < 1 {
+				return usageErrorf("--keep must be at least 1")
+			}
+			return runPrune(a, keep, dryRun)
+		},
+	}
+	cmd.Flags().IntVar(&keep, "keep", 0, "builds to keep per vendor and feature release (default: keep-patches, or 1)")
+	cmd.Flags().BoolVar(&dryRun, "dry-run", false, "list what would be removed and the space it takes without removing anything")
+	return cmd
+}
+
+func newPinCmd(a *app) *cobra.Command {
+	return &cobra.Command{
+		Use:   "pin [version|alias]",
+		Short: "Keep an installation from being pruned",
+		Long: "Pin an installation so that 'jswitch prune' and the keep-patches setting never remove it.\n" +
+			"Without arguments, list the pinned installations.",
+		Args:              rangeArgs(0, 1),
+		ValidArgsFunction: a.completeVersionArg(0),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) == 0 {
+				return runPinList(a)
+			}
+			return runPin(a, args[0], true)
+		},
+	}
+}
+
+func newUnpinCmd(a *app) *cobra.Command {
+	return &cobra.Command{
+		Use:               "unpin <version|alias>",
+		Short:             "Let prune remove a pinned installation again",
+		Args:              exactArgs(1),
+		ValidArgsFunction: a.completeVersionArg(0),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runPin(a, args[0], false)
+		},
+	}
+}
+
+func runPinList(a *app) error {
+	cfg, err := loadConfig()
+	if err != nil {
+		return err
+	}
+	if len(cfg.Pinned) == 0 {
+		a.infof("No pinned installations. Pin one with 'jswitch pin <version>'.\n")
+		return nil
+	}
+	for _, path := range cfg.Pinned {
+		fmt.Fprintln(a.stdout, path)
+	}
+	return nil
+}
+
+func runPin(a *app, spec string, pin bool) error {
+	var inst models.JavaInstallation
+	var changed bool
+	err := updateConfig(func(c *config.Config) error {
+		var err error
+		inst, err = c.Resolve(spec)
+		if err != nil {
+			return err
+		}
+		if pin {
+			changed = c.Pin(inst.Path)
+		} else {
+			changed = c.Unpin(inst.Path)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	switch {
+	case pin && changed:
+		a.infof("Pinned Java %s (%s); prune keeps it.\n", inst.Version, inst.Vendor)
+	case pin:
+		a.infof("Java %s (%s) is pinned already.\n", inst.Version, inst.Vendor)
+	case changed:
+		a.infof("Unpinned Java %s (%s).\n", inst.Version, inst.Vendor)
+	default:
+		a.infof("Java %s (%s) is not pinned.\n", inst.Version, inst.Vendor)
+	}
+	return nil
+}
+
+func runPrune(a *app, keep int, dryRun bool) error {
+	cfg, err := loadConfig()
+	if err != nil {
+		return err
+	}
+	dir, err := installDir(cfg)
+	if err != nil {
+		return err
+	}
+	if keep == 0 {
+		keep = max(cfg.SettingInt(config.SettingKeepPatches), 1)
+	}
+
+	leftovers, err := prune.Leftovers(dir, os.TempDir(), func(path string) bool {
+		for _, inst := range cfg.Installations {
+			if isWithin(path, inst.Path) {
+				return true
+			}
+		}
+		return false
+	}, staleAfter)
+	if err != nil {
+		a.warnf("could not look for leftovers in %s: %v\n", dir, err)
+	}
+
+	var removed []models.JavaInstallation
+	var freed int64
+	apply := func(c *config.Config) error {
+		protected, gone := protectedInstallations(c)
+		var managed []models.JavaInstallation
+		for _, inst := range c.Installations {
+			if isWithin(dir, inst.Path) {
+				managed = append(managed, inst)
+			}
+		}
+		for _, d := range prune.Plan(managed, prune.Options{Keep: keep, Protected: protected, System: c.IsSystem}) {
+			if !d.Remove {
+				a.infof("Keeping Java %s: %s.\n", d.Installation.Version, d.Reason)
+				continue
+			}
+			removed = append(removed, d.Installation)
+		}
+		freed = reclaimable(a, managed, removed)
+
+		for _, project := range gone {
+			if dryRun {
+				a.infof("Would forget project %s: its %s and %s are gone.\n", project, lockfile.FileName, resolver.ProjectFileName)
+				continue
+			}
+			c.RemoveProject(project)
+			a.infof("Forgot project %s: its %s and %s are gone.\n", project, lockfile.FileName, resolver.ProjectFileName)
+		}
+		if dryRun {
+			for _, inst := range removed {
+				a.infof("Would remove Java %s (%s) at %s.\n", inst.Version, inst.Vendor, inst.Path)
+			}
+			return nil
+		}
+
+		kept := removed[:0]
+		for _, inst := range removed {
+			if err := os.RemoveAll(inst.Path); err != nil {
+				a.warnf("could not remove %s: %v\n", inst.Path, err)
+				continue
+			}
+			c.RemoveInstallation(inst)
+			kept = append(kept, inst)
+			a.infof("Removed Java %s (%s) at %s.\n", inst.Version, inst.Vendor, inst.Path)
+		}
+		removed = kept
+		return nil
+	}
+	if dryRun {
+		apply(cfg)
+	} else if err := updateConfig(apply); err != nil {
+		return err
+	}
+
+	for _, l := range leftovers {
+		if dryRun {
+			a.infof("Would remove %s %s (%s).\n", l.Kind, l.Path, dedupe.FormatBytes(l.Size))
+		} else if err := os.RemoveAll(l.Path); err != nil {
+			a.warnf("could not remove %s: %v\n", l.Path, err)
+			continue
+		} else {
+			a.infof("Removed %s %s (%s).\n", l.Kind, l.Path, dedupe.FormatBytes(l.Size))
+		}
+		freed += l.Size
+	}
+
+	switch {
+	case len(removed) == 0 && len(leftovers) == 0:
+		a.infof("Nothing to prune (keeping %d build(s) per vendor and feature release).\n", keep)
+	case dryRun:
+		a.infof("Dry run: %s reclaimable. Nothing was removed.\n", dedupe.FormatBytes(freed))
+	default:
+		a.infof("Freed %s.\n", dedupe.FormatBytes(freed))
+	}
+	if len(removed) > 0 && !dryRun {
+		syncExports(a)
+	}
+	return nil
+}
+
+// reclaimable returns the space removing some of the installations frees. Files hardlinked
+// into an installation that stays are not counted.
+func reclaimable(a *app, all, remove []models.JavaInstallation) int64 {
+	if len(remove) == 0 {
+		return 0
+	}
+	removing := make(map[string]bool)
+	for _, inst := range remove {
+		removing[inst.Path] = true
+	}
+	var allDirs, keptDirs []string
+	for _, inst := range all {
+		allDirs = append(allDirs, inst.Path)
+		if !removing[inst.Path] {
+			keptDirs = append(keptDirs, inst.Path)
+		}
+	}
+	_, before, err := dedupe.DiskUsage(allDirs)
+	if err != nil {
+		a.warnf("could not measure the installations: %v\n", err)
+		return 0
+	}
+	_, after, err := dedupe.DiskUsage(keptDirs)
+	if err != nil {
+		a.warnf("could not measure the installations: %v\n", err)
+		return 0
+	}
+	return before - after
+}
+
+// protectedInstallations returns, by path, the installations prune must keep and why, and
+// the known projects that have neither a jswitch.lock nor a .java-version any more.
+func protectedInstallations(cfg *config.Config) (map[string]string, []string) {
+	protected := make(map[string]string)
+	protect := func(inst models.JavaInstallation, reason string) {
+		if _, ok := protected[inst.Path]; !ok {
+			protected[inst.Path] = reason
+		}
+	}
+
+	if path := cfg.CurrentVersionPath(cfg.CurrentVersion); path != "" {
+		protected[path] = "it is the current version"
+	}
+	if r, err := resolver.Active(cfg, "."); err == nil {
+		protect(r.Installation, "it is active here, "+r.Reason())
+	}
+	for _, path := range cfg.Pinned {
+		protect(models.JavaInstallation{Path: path}, "it is pinned with 'jswitch pin'")
+	}
+	for _, name := range cfg.AliasNames() {
+		if inst, err := cfg.Resolve(name); err == nil {
+			protect(inst, fmt.Sprintf("alias %s points at it", name))
+		}
+	}
+
+	var gone []string
+	for _, dir := range cfg.Projects {
+		found := false
+		lockPath := filepath.Join(dir, lockfile.FileName)
+		if l, err := lockfile.Read(lockPath); err == nil {
+			found = true
+			if inst, ok := cfg.FindBuild(l.Version, l.Vendor, l.ImageType); ok {
+				protect(inst, "locked by "+lockPath)
+			}
+		} else if !os.IsNotExist(err) {
+			found = true
+		}
+		versionPath := filepath.Join(dir, resolver.ProjectFileName)
+		if spec, err := resolver.ReadProjectFile(versionPath); err == nil {
+			found = true
+			if inst, err := cfg.Resolve(spec); err == nil {
+				protect(inst, "selected by "+versionPath)
+			}
+		} else if !os.IsNotExist(err) {
+			found = true
+		}
+		if !found {
+			gone = append(gone, dir)
+		}
+	}
+	return protected, gone
+}
+
+// rememberProject records dir as a project whose jswitch.lock or .java-version prune respects.
+func rememberProject(a *app, dir string) {
+	rememberProjects(a, []string{dir})
+}
+
+func rememberProjects(a *app, dirs []string) {
+	if len(dirs) == 0 {
+		return
+	}
+	err := updateConfig(func(c *config.Config) error {
+		for _, dir := range dirs {
+			if abs, err := filepath.Abs(dir); err == nil {
+				c.AddProject(abs)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		a.warnf("could not remember the project for 'jswitch prune': %v\n", err)
+	}
+}
@@ -0,0 +1,366 @@
+package main
+
+import (
+	"fmt"
+	"runtime"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+	"github.com/user/jswitch/pkg/config"
+	"github.com/user/jswitch/pkg/fetcher"
+	"github.com/user/jswitch/pkg/models"
+	"github.com/user/jswitch/pkg/output"
+	"github.com/user/jswitch/pkg/resolver"
+	"github.com/user/jswitch/pkg/scanner"
+)
+
+// outputFlags are the --output/--format flags shared by query commands.
+type outputFlags struct {
+	format   string
+	template string
+}
+
+func (f *outputFlags) register(cmd *cobra.Command) {
+	cmd.Flags().StringVarP(&f.format, "output", "o", "table", "output format: table, json, yaml or template")
+	cmd.Flags().StringVar(&f.template, "format", "", "Go template rendered per item, e.g. '{{.Path}}' (implies --output template)")
+	cmd.RegisterFlagCompletionFunc("output", cobra.FixedCompletions(
+		[]string{"table", "json", "yaml", "template"}, cobra.ShellCompDirectiveNoFileComp))
+}
+
+func (f *outputFlags) options() (output.Options, error) {
+	opts, err := output.NewOptions(f.format, f.template)
+	if err != nil {
+		return opts, &cliError{code: exitUsage, err: err}
+	}
+	return opts, nil
+}
+
+// installationViews annotates installations with current/alias state for structured output.
+func installationViews(cfg *config.Config, installations []models.JavaInstallation) []output.Installation {
+	views := make([]output.Installation, 0, len(installations))
+	for _, inst := range installations {
+		views = append(views, output.Installation{
+			JavaInstallation: inst,
+			Current:          inst.Version == cfg.CurrentVersion,
+			Aliases:          cfg.AliasesFor(inst.Version),
+		})
+	}
+	return views
+}
+
+func newScanCmd(a *app) *cobra.Command {
+	var out outputFlags
+	cmd := &cobra.Command{
+		Use:   "scan [paths...]",
+		Short: "Scan system for Java installations",
+		Long:  "Scan the given paths (or the platform's usual JDK locations and install-dir) and record every installation found.",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			opts, err := out.options()
+			if err != nil {
+				return err
+			}
+			return runScan(a, args, opts)
+		},
+	}
+	out.register(cmd)
+	return cmd
+}
+
+func runScan(a *app, customPaths []string, opts output.Options) error {
+	var pathsToScan []string
+	if len(customPaths) > 0 {
+		pathsToScan = customPaths
+	} else {
+		if runtime.GOOS == "windows" {
+			pathsToScan = []string{
+				`C:\Program Files\Java`,
+				`C:\Program Files (x86)\Java`,
+			}
+		} else {
+			pathsToScan = []string{
+				"/usr/lib/jvm",
+				"/usr/java",
+				"/Library/Java/JavaVirtualMachines",
+			}
+		}
+		// The JDKs jswitch installed, so that a scan after a lost config finds them again.
+		if cfg, err := config.LoadConfig(); err == nil {
+			if dir, err := installDir(cfg); err == nil {
+				pathsToScan = append(pathsToScan, dir)
+			}
+		}
+	}
+
+	// In structured mode stdout carries only the document; progress goes to stderr.
+	log := a.stdout
+	if opts.Structured() {
+		log = a.stderr
+	}
+	logf := func(format string, args ...any) {
+		if !a.quiet {
+			fmt.Fprintf(log, format, args...)
+		}
+	}
+
+	logf("Scanning paths: %v\n", pathsToScan)
+
+	workers := 1
+	if cfg, err := config.LoadConfig(); err == nil {
+		workers = cfg.SettingInt(config.SettingParallelism)
+	}
+	installations, err := scanner.ScanSystem(pathsToScan, workers)
+	if err != nil {
+		a.warnf("error scanning: %v\n", err)
+	}
+
+	if len(installations) == 0 {
+		logf("No Java installations found.\n")
+		if opts.Structured() {
+			return opts.Write(a.stdout, output.KindInstallationList, []output.Installation{})
+		}
+		return nil
+	}
+
+	logf("Found %d Java installations.\n", len(installations))
+	var cfg *config.Config
+	err = updateConfig(func(c *config.Config) error {
+		cfg = c
+		c.Installations = installations
+		for _, name := range c.ReconcileAliases() {
+			logf("Alias %s now points at %s.\n", name, c.Aliases[name].Target)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	path, _ := config.Path()
+	logf("Config saved to %s\n", path)
+	syncExports(a)
+
+	if opts.Structured() {
+		return opts.Write(a.stdout, output.KindInstallationList, installationViews(cfg, installations))
+	}
+	return nil
+}
+
+func newListCmd(a *app) *cobra.Command {
+	var out outputFlags
+	cmd := &cobra.Command{
+		Use:     "list",
+		Aliases: []string{"ls"},
+		Short:   "List discovered Java versions",
+		Args:    exactArgs(0),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			opts, err := out.options()
+			if err != nil {
+				return err
+			}
+			return runList(a, opts)
+		},
+	}
+	out.register(cmd)
+	return cmd
+}
+
+func runList(a *app, opts output.Options) error {
+	cfg, err := loadConfig()
+	if err != nil {
+		return err
+	}
+
+	if opts.Structured() {
+		return opts.Write(a.stdout, output.KindInstallationList, installationViews(cfg, cfg.Installations))
+	}
+
+	if len(cfg.Installations) == 0 {
+		a.infof("No installations found. Run 'jswitch scan' first.\n")
+		return nil
+	}
+
+	w := tabwriter.NewWriter(a.stdout, 0, 0, 3, ' ', 0)
+	fmt.Fprintln(w, "CURRENT\tVENDOR\tVERSION\tALIASES\tPATH")
+
+	for _, inst := range cfg.Installations {
+		marker := " "
+		if inst.Version == cfg.CurrentVersion {
+			marker = "*"
+		}
+
+		aliases := strings.Join(cfg.AliasesFor(inst.Version), ",")
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", marker, inst.Vendor, inst.Version, aliases, inst.Path)
+	}
+	return w.Flush()
+}
+
+func newCurrentCmd(a *app) *cobra.Command {
+	var out outputFlags
+	cmd := &cobra.Command{
+		Use:   "current",
+		Short: "Show the active Java installation and why",
+		Long: "Show the installation that applies in the current directory and where the choice came from.\n" +
+			"Precedence: $JSWITCH_VERSION, the nearest jswitch.lock, .java-version or build file declaring a Java version\n" +
+			"(pom.xml, build.gradle(.kts), Dockerfile, GitHub workflows), the global selection, the default alias.",
+		Args: exactArgs(0),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			opts, err := out.options()
+			if err != nil {
+				return err
+			}
+			return runCurrent(a, opts)
+		},
+	}
+	out.register(cmd)
+	return cmd
+}
+
+func runCurrent(a *app, opts output.Options) error {
+	cfg, err := loadConfig()
+	if err != nil {
+		return err
+	}
+
+	res, err := resolver.Active(cfg, ".")
+	if err != nil {
+		return err
+	}
+
+	if opts.Structured() {
+		doc := output.Resolution{
+			Installation: installationViews(cfg, []models.JavaInstallation{res.Installation})[0],
+			Source:       string(res.Source),
+			Spec:         res.Spec,
+			Origin:       res.Origin,
+			Alias:        res.Alias,
+			Detail:       res.Detail,
+		}
+		return opts.Write(a.stdout, output.KindInstallation, doc)
+	}
+
+	inst := res.Installation
+	fmt.Fprintf(a.stdout, "%s %s (%s)\n", inst.Vendor, inst.Version, inst.Path)
+	a.infof("  %s\n", res.Reason())
+	if res.Source != resolver.SourceGlobal && cfg.CurrentVersion != "" && cfg.CurrentVersion != inst.Version {
+		a.infof("  note: the global selection (%s) is what JAVA_HOME points at\n", cfg.CurrentVersion)
+	}
+	return nil
+}
+
+func newWhichCmd(a *app) *cobra.Command {
+	return &cobra.Command{
+		Use:               "which <tool> [version]",
+		Short:             "Print the path to a JDK tool (javac, jshell, ...)",
+		Args:              rangeArgs(1, 2),
+		ValidArgsFunction: a.completeWhich,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			spec := ""
+			if len(args) == 2 {
+				spec = args[1]
+			}
+			inst, err := resolveSpecOrActive(spec)
+			if err != nil {
+				return err
+			}
+			path, err := resolver.Tool(inst, args[0])
+			if err != nil {
+				return &cliError{code: exitNotFound, err: err}
+			}
+			fmt.Fprintln(a.stdout, path)
+			return nil
+		},
+	}
+}
+
+func newHomeCmd(a *app) *cobra.Command {
+	return &cobra.Command{
+		Use:               "home [version]",
+		Short:             "Print JAVA_HOME for a version (default: active)",
+		Args:              rangeArgs(0, 1),
+		ValidArgsFunction: a.completeVersionArg(0),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			spec := ""
+			if len(args) == 1 {
+				spec = args[0]
+			}
+			inst, err := resolveSpecOrActive(spec)
+			if err != nil {
+				return err
+			}
+			fmt.Fprintln(a.stdout, inst.Path)
+			return nil
+		},
+	}
+}
+
+// resolveSpecOrActive resolves spec, or the active version when spec is empty.
+func resolveSpecOrActive(spec string) (models.JavaInstallation, error) {
+	cfg, err := loadConfig()
+	if err != nil {
+		return models.JavaInstallation{}, err
+	}
+
+	if spec != "" {
+		return cfg.Resolve(spec)
+	}
+
+	res, err := resolver.Active(cfg, ".")
+	if err != nil {
+		return models.JavaInstallation{}, err
+	}
+	return res.Installation, nil
+}
+
+func newListRemoteCmd(a *app) *cobra.Command {
+	var out outputFlags
+	cmd := &cobra.Command{
+		Use:   "list-remote",
+		Short: "List Java versions available for download",
+		Args:  exactArgs(0),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			opts, err := out.options()
+			if err != nil {
+				return err
+			}
+			return runListRemote(a, opts)
+		},
+	}
+	out.register(cmd)
+	return cmd
+}
+
+func runListRemote(a *app, opts output.Options) error {
+	cfg, err := loadConfig()
+	if err != nil {
+		return err
+	}
+	mirror, err := cfg.GetSetting(config.SettingMirror)
+	if err != nil {
+		return configError(err)
+	}
+	available, err := fetcher.ListAvailableReleases(fetcher.Options{APIBase: mirror.Value})
+	if err != nil {
+		return networkError(err)
+	}
+
+	releases := make([]output.RemoteRelease, 0, len(available.Releases))
+	for i := len(available.Releases) - 1; i >= 0; i-- {
+		v := available.Releases[i]
+		releases = append(releases, output.RemoteRelease{FeatureVersion: v, LTS: available.IsLTS(v)})
+	}
+
+	if opts.Structured() {
+		return opts.Write(a.stdout, output.KindRemoteList, releases)
+	}
+
+	w := tabwriter.NewWriter(a.stdout, 0, 0, 3, ' ', 0)
+	fmt.Fprintln(w, "VERSION\tLTS")
+	for _, r := range releases {
+		lts := ""
+		if r.LTS {
+			lts = "yes"
+		}
+		fmt.Fprintf(w, "%d\t%s\n", r.FeatureVersion, lts)
+	}
+	return w.Flush()
+}
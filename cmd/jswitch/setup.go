@@ -0,0 +1,182 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+	"github.com/user/jswitch/pkg/config"
+	"github.com/user/jswitch/pkg/doctor"
+	"github.com/user/jswitch/pkg/output"
+	"github.com/user/jswitch/pkg/paths"
+	"github.com/user/jswitch/pkg/shell"
+)
+
+func newSetupCmd(a *app) *cobra.Command {
+	var remove bool
+	cmd := &cobra.Command{
+		Use:   "setup [shells...]",
+		Short: "Configure (or unconfigure) shell profiles",
+		Long: "Add a managed block exporting JAVA_HOME and PATH, and the variables 'jswitch env' attached to\n" +
+			"the active JDK, to your shell profile(s).\n" +
+			"Shells are detected automatically unless given explicitly (bash, zsh, sh, fish, pwsh).\n" +
+			"The profile as it was before jswitch first changed it is kept as <profile>.jswitch.bak.",
+		ValidArgsFunction: completeShells,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runSetup(a, args, remove)
+		},
+	}
+	cmd.Flags().BoolVar(&remove, "remove", false, "remove the managed block instead of adding it")
+	return cmd
+}
+
+// runSetup installs or removes the managed jswitch block in shell profiles.
+func runSetup(a *app, names []string, remove bool) error {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return fmt.Errorf("finding home directory: %w", err)
+	}
+
+	var shells []shell.Shell
+	if len(names) > 0 {
+		for _, name := range names {
+			sh, err := shell.ForName(home, name)
+			if err != nil {
+				return &cliError{code: exitUsage, err: err}
+			}
+			shells = append(shells, sh)
+		}
+	} else {
+		shells = shell.Detect(home)
+	}
+
+	if len(shells) == 0 {
+		return &cliError{code: exitNotFound, err: fmt.Errorf("no supported shell detected; pass one explicitly, e.g. 'jswitch setup bash'")}
+	}
+
+	linkPath, err := paths.CurrentLink()
+	if err != nil {
+		return err
+	}
+	autoSwitch := false
+	if cfg, err := config.LoadConfig(); err == nil {
+		autoSwitch = cfg.SettingBool(config.SettingAutoSwitch)
+	}
+
+	failed := 0
+	for _, sh := range shells {
+		sh.AutoSwitch = autoSwitch
+		var changed bool
+		if remove {
+			changed, err = sh.Remove()
+		} else {
+			changed, err = sh.Install(linkPath)
+		}
+		if err != nil {
+			fmt.Fprintf(a.stderr, "Error updating %s: %v\n", sh.Profile, err)
+			failed++
+			continue
+		}
+
+		switch {
+		case !changed && remove:
+			a.infof("%s: nothing to remove (%s)\n", sh.Name, sh.Profile)
+		case !changed:
+			a.infof("%s: already configured (%s)\n", sh.Name, sh.Profile)
+		case remove:
+			a.infof("%s: removed jswitch block from %s\n", sh.Name, sh.Profile)
+		default:
+			a.infof("%s: configured %s\n", sh.Name, sh.Profile)
+		}
+	}
+
+	if failed > 0 {
+		return environmentError(fmt.Errorf("%d shell profile(s) could not be updated", failed))
+	}
+	if !remove {
+		a.infof("Open a new shell for the changes to take effect.\n")
+	}
+	return nil
+}
+
+func newDoctorCmd(a *app) *cobra.Command {
+	var fix bool
+	var out outputFlags
+	cmd := &cobra.Command{
+		Use:   "doctor",
+		Short: "Diagnose environment problems (and apply safe fixes)",
+		Long: "Check PATH, JAVA_HOME, shell profiles, the current symlink and the config for problems.\n" +
+			"Exits with status 1 when any error-level finding remains.",
+		Args: exactArgs(0),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			opts, err := out.options()
+			if err != nil {
+				return err
+			}
+			return runDoctor(a, fix, opts)
+		},
+	}
+	cmd.Flags().BoolVar(&fix, "fix", false, "apply safe automatic fixes")
+	out.register(cmd)
+	return cmd
+}
+
+// runDoctor prints diagnostics and fails if any errors remain.
+func runDoctor(a *app, fix bool, opts output.Options) error {
+	report := doctor.Run()
+
+	if fix {
+		applied := 0
+		for _, f := range report.Findings {
+			if f.Severity <= doctor.SeverityInfo || !f.CanFix() {
+				continue
+			}
+			if err := f.ApplyFix(); err != nil {
+				fmt.Fprintf(a.stderr, "Failed to fix %s: %v\n", f.Check, err)
+				continue
+			}
+			applied++
+		}
+		if applied > 0 {
+			if !opts.Structured() {
+				a.infof("Applied %d fix(es). Re-checking...\n\n", applied)
+			}
+			report = doctor.Run()
+		}
+	}
+
+	if opts.Structured() {
+		doc := output.DoctorReport{OK: !report.HasErrors(), Findings: []output.Finding{}}
+		for _, f := range report.Findings {
+			doc.Findings = append(doc.Findings, output.Finding{
+				Check:    f.Check,
+				Severity: f.Severity.String(),
+				Message:  f.Message,
+				Fix:      f.Fix,
+				Fixable:  f.CanFix(),
+			})
+		}
+		if err := opts.Write(a.stdout, output.KindDoctorReport, doc); err != nil {
+			return err
+		}
+	} else {
+		for _, f := range report.Findings {
+			if a.quiet && f.Severity < doctor.SeverityWarning {
+				continue
+			}
+			fmt.Fprintf(a.stdout, "[%-5s] %s\n", f.Severity, f.Message)
+			if f.Fix != "" && f.Severity > doctor.SeverityInfo {
+				hint := f.Fix
+				if f.CanFix() && !fix {
+					hint += " (fixable with --fix)"
+				}
+				fmt.Fprintf(a.stdout, "        fix: %s\n", hint)
+			}
+		}
+	}
+
+	if report.HasErrors() {
+		return silentError(exitError)
+	}
+	return nil
+}
@@ -0,0 +1,186 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/user/jswitch/pkg/alternatives"
+	"github.com/user/jswitch/pkg/config"
+	"github.com/user/jswitch/pkg/fetcher"
+	"github.com/user/jswitch/pkg/fileutil"
+	"github.com/user/jswitch/pkg/history"
+	"github.com/user/jswitch/pkg/models"
+	"github.com/user/jswitch/pkg/output"
+	"github.com/user/jswitch/pkg/paths"
+	"github.com/user/jswitch/pkg/switcher"
+)
+
+// systemInstallTimeout bounds the wait for another user's install of the same feature release.
+const systemInstallTimeout = 30 * time.Minute
+
+// systemFlags select the system-wide scope of 'use'.
+type systemFlags struct {
+	system   bool
+	dryRun   bool
+	altDir   string
+	adminDir string
+}
+
+func (f *systemFlags) register(cmd *cobra.Command) {
+	cmd.Flags().BoolVar(&f.system, "system", false, "switch the system-wide java via update-alternatives")
+	cmd.Flags().BoolVar(&f.dryRun, "dry-run", false, "print the update-alternatives commands instead of running them")
+	cmd.Flags().StringVar(&f.altDir, "altdir", "", "alternatives directory passed to update-alternatives")
+	cmd.Flags().StringVar(&f.adminDir, "admindir", "", "administrative directory passed to update-alternatives")
+}
+
+// runUseSystem registers the installation spec resolves to with update-alternatives and
+// selects it, so that /usr/bin/java and friends run it for every user.
+func runUseSystem(a *app, spec string, flags systemFlags) error {
+	cfg, err := loadConfig()
+	if err != nil {
+		return err
+	}
+	inst, err := cfg.Resolve(spec)
+	if err != nil {
+		return err
+	}
+
+	m, err := alternatives.Find()
+	if err != nil {
+		return environmentError(err)
+	}
+	m.AltDir, m.AdminDir = flags.altDir, flags.adminDir
+	m.Sudo = os.Geteuid() != 0 && flags.adminDir == ""
+	m.DryRun = flags.dryRun
+	m.Out = a.stdout
+
+	// Remember what was selected before, as a version when jswitch knows it.
+	previous, _ := m.Current()
+	for _, i := range cfg.Installations {
+		if i.Path == previous {
+			previous = i.Version
+			break
+		}
+	}
+
+	if err := m.Register(inst); err != nil {
+		return environmentError(err)
+	}
+	if err := m.Set(inst); err != nil {
+		return environmentError(err)
+	}
+	if flags.dryRun {
+		return nil
+	}
+
+	if previous != inst.Version {
+		if err := history.Record(history.Entry{From: previous, To: inst.Version, Scope: history.ScopeSystem}); err != nil {
+			a.warnf("could not record history: %v\n", err)
+		}
+	}
+	a.infof("System Java set to %s (priority %d).\n", inst.Version, alternatives.Priority(inst.Version))
+	return nil
+}
+
+// runInstallSystem installs the latest build of a feature release into the shared root and
+// registers it in the system config. Installs of the same feature release are serialised
+// by a lock in the root, so concurrent users download it once and the others reuse it.
+func runInstallSystem(a *app, version int, opts output.Options) error {
+	cfg, err := loadConfig()
+	if err != nil {
+		return err
+	}
+	fetchOpts, err := fetchOptions(cfg)
+	if err != nil {
+		return configError(err)
+	}
+	layout, err := paths.System()
+	if err != nil {
+		return configError(err)
+	}
+	dest := layout.VersionsDir()
+	for _, dir := range []string{layout.Data, dest} {
+		if err := fileutil.MkdirShared(dir); err != nil {
+			return environmentError(fmt.Errorf("creating %s: %w; an administrator must create %s, group-writable for the users of jswitch", dir, err, layout.Data))
+		}
+	}
+
+	lockPath := filepath.Join(layout.Data, fmt.Sprintf("install-%d.lock", version))
+	lock, err := fileutil.LockWithin(lockPath, 0)
+	if errors.Is(err, fileutil.ErrLocked) {
+		a.infof("Waiting for another install of Java %d to finish...\n", version)
+		lock, err = fileutil.LockWithin(lockPath, systemInstallTimeout)
+	}
+	if err != nil {
+		return environmentError(err)
+	}
+	defer lock.Unlock()
+
+	url, semver, err := fetcher.GetLatestVersion(version, fetchOpts)
+	if err != nil {
+		return networkError(err)
+	}
+
+	system, err := config.LoadSystemConfig()
+	if err != nil {
+		return configError(err)
+	}
+	inst := fetcher.NewInstallation(semver, "")
+	existing := system.CurrentVersionPath(semver)
+	// A directory another user removed or only partly deleted is installed again.
+	var broken string
+	if existing != "" {
+		if err := switcher.Validate(existing); err != nil {
+			a.warnf("the shared Java %s is damaged, installing it again: %v\n", semver, err)
+			if isWithin(dest, existing) {
+				if err := os.RemoveAll(existing); err != nil {
+					return environmentError(fmt.Errorf("removing %s: %w", existing, err))
+				}
+			}
+			broken, existing = existing, ""
+		}
+	}
+	if existing != "" {
+		inst.Path = existing
+		a.infof("Java %s is already installed for all users at %s.\n", semver, existing)
+	} else {
+		a.infof("Downloading Java %s...\n", semver)
+		path, err := fetcher.DownloadAndExtract(url, dest, nil)
+		if err != nil {
+			return networkError(err)
+		}
+		if err := fileutil.ShareTree(path); err != nil {
+			a.warnf("could not make %s group-writable: %v\n", path, err)
+		}
+		inst.Path = path
+		if err := config.UpdateSystem(func(c *config.Config) error {
+			kept := c.Installations[:0]
+			for _, other := range c.Installations {
+				if broken == "" || other.Path != broken {
+					kept = append(kept, other)
+				}
+			}
+			c.Installations = kept
+			c.AddInstallation(inst)
+			return nil
+		}); err != nil {
+			return configError(err)
+		}
+		a.infof("Installed Java %s for all users at %s.\n", semver, path)
+		syncExports(a)
+		dedupeAfterInstall(a, dest)
+	}
+
+	if opts.Structured() {
+		cfg, err := loadConfig()
+		if err != nil {
+			return err
+		}
+		return opts.Write(a.stdout, output.KindInstallation, installationViews(cfg, []models.JavaInstallation{inst})[0])
+	}
+	return nil
+}
@@ -0,0 +1,344 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"runtime"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/spf13/cobra"
+	"github.com/user/jswitch/pkg/config"
+	"github.com/user/jswitch/pkg/history"
+	"github.com/user/jswitch/pkg/models"
+	"github.com/user/jswitch/pkg/paths"
+	"github.com/user/jswitch/pkg/shell"
+	"github.com/user/jswitch/pkg/switcher"
+	"github.com/user/jswitch/pkg/tui"
+	"golang.org/x/term"
+)
+
+func newUseCmd(a *app) *cobra.Command {
+	var sys systemFlags
+	cmd := &cobra.Command{
+		Use:   "use <version|alias|->",
+		Short: "Select a Java version to use ('-' for the previous one)",
+		Long: "Select the global Java version. The argument may be an exact version, a major version (17),\n" +
+			"a vendor and major version (corretto-17), an alias, or '-' for the previously active version.\n\n" +
+			"With --system, switch /usr/bin/java, javac, jar and jshell for every user instead, by registering\n" +
+			"the installation with update-alternatives and selecting it. The commands run through sudo\n" +
+			"unless jswitch runs as root or --admindir is given; --dry-run prints them instead.",
+		Args:              exactArgs(1),
+		ValidArgsFunction: a.completeUse,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if !sys.system && (sys.dryRun || sys.altDir != "" || sys.adminDir != "") {
+				return usageErrorf("--dry-run, --altdir and --admindir require --system")
+			}
+			scope := history.ScopeGlobal
+			if sys.system {
+				scope = history.ScopeSystem
+			}
+			spec := args[0]
+			if spec == "-" {
+				prev, ok := history.Previous(scope)
+				if !ok {
+					return &cliError{code: exitNotFound, err: fmt.Errorf("no previous version to switch back to")}
+				}
+				spec = prev
+			}
+			if sys.system {
+				return runUseSystem(a, spec, sys)
+			}
+			return runUse(a, spec)
+		},
+	}
+	sys.register(cmd)
+	return cmd
+}
+
+func runUse(a *app, spec string) error {
+	var cfg *config.Config
+	var previous string
+	var inst models.JavaInstallation
+	err := updateConfig(func(c *config.Config) error {
+		cfg = c
+		previous = c.CurrentVersion
+		var err error
+		inst, err = c.Resolve(spec)
+		if err != nil {
+			return err
+		}
+
+		// Apply system changes first so a failed switch leaves the config untouched
+		if err := switcher.Switch(inst.Path); err != nil {
+			return environmentError(fmt.Errorf("switching system environment: %w", err))
+		}
+		c.CurrentVersion = inst.Version
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	version := inst.Version
+
+	if previous != version {
+		if err := history.Record(history.Entry{From: previous, To: version, Scope: history.ScopeGlobal}); err != nil {
+			a.warnf("could not record history: %v\n", err)
+		}
+	}
+
+	a.infof("Target set to Java %s.\n", version)
+	a.debugf("JAVA_HOME -> %s\n", inst.Path)
+
+	if runtime.GOOS != "windows" {
+		offerShellSetup(a, cfg)
+	}
+	return nil
+}
+
+// offerShellSetup asks once whether to run 'jswitch setup' when no shell profile is configured yet.
+func offerShellSetup(a *app, cfg *config.Config) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return
+	}
+	shells := shell.Detect(home)
+	for _, sh := range shells {
+		if sh.IsConfigured() {
+			return
+		}
+	}
+
+	linkPath, err := paths.CurrentLink()
+	if err != nil {
+		return
+	}
+	if cfg.ShellSetupOffered || !a.isInteractive() {
+		a.infof("Run 'jswitch setup' to add JAVA_HOME=%s to your shell profile.\n", linkPath)
+		return
+	}
+
+	err = config.Update(func(cfg *config.Config) error {
+		cfg.ShellSetupOffered = true
+		return nil
+	})
+	if err != nil {
+		a.warnf("could not save config: %v\n", err)
+	}
+
+	fmt.Fprint(a.stdout, "Your shell is not configured for jswitch yet. Configure it now? [y/N] ")
+	var answer string
+	fmt.Fscanln(a.stdin, &answer)
+	if !strings.EqualFold(answer, "y") && !strings.EqualFold(answer, "yes") {
+		a.infof("Skipped. You can run 'jswitch setup' at any time.\n")
+		return
+	}
+	if err := runSetup(a, nil, false); err != nil {
+		a.warnf("%v\n", err)
+	}
+}
+
+// isInteractive reports whether stdin and stdout are terminals, so a prompt can be shown
+// and answered.
+func (a *app) isInteractive() bool {
+	return isTerminal(a.stdin) && isTerminal(a.stdout)
+}
+
+func isTerminal(v any) bool {
+	f, ok := v.(*os.File)
+	return ok && term.IsTerminal(int(f.Fd()))
+}
+
+func newUICmd(a *app) *cobra.Command {
+	return &cobra.Command{
+		Use:     "ui",
+		Aliases: []string{"select"},
+		Short:   "Open interactive selection menu",
+		Args:    exactArgs(0),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runUI(a)
+		},
+	}
+}
+
+func runUI(a *app) error {
+	cfg, err := loadConfig()
+	if err != nil {
+		return err
+	}
+
+	if len(cfg.Installations) == 0 {
+		a.infof("No installations found. Run 'jswitch scan' first.\n")
+		return nil
+	}
+
+	previous, _ := history.Previous(history.ScopeGlobal)
+	initialModel := tui.NewModel(cfg.Installations, cfg.CurrentVersion, previous)
+	p := tea.NewProgram(initialModel)
+
+	// Run the program
+	finalModel, err := p.Run()
+	if err != nil {
+		return fmt.Errorf("running TUI: %w", err)
+	}
+
+	// Assert back to our specific model
+	m, ok := finalModel.(tui.Model)
+	if ok && m.SelectedID != "" {
+		// Reuse the use logic to apply switch
+		a.infof("Selected via UI: %s\n", m.SelectedID)
+		return runUse(a, m.SelectedID)
+	}
+	return nil
+}
+
+func newHistoryCmd(a *app) *cobra.Command {
+	var limit int
+	cmd := &cobra.Command{
+		Use:   "history [count]",
+		Short: "Show recent version switches",
+		Args:  rangeArgs(0, 1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) == 1 {
+				n, err := strconv.Atoi(args[0])
+				if err != nil {
+					return usageErrorf("count must be a positive integer")
+				}
+				limit = n
+			}
+			if limit <= 0 {
+				return usageErrorf("count must be a positive integer")
+			}
+			return runHistory(a, limit)
+		},
+	}
+	cmd.Flags().IntVarP(&limit, "limit", "n", 20, "number of entries to show")
+	return cmd
+}
+
+func runHistory(a *app, limit int) error {
+	entries, err := history.Load()
+	if err != nil {
+		return err
+	}
+
+	if len(entries) == 0 {
+		a.infof("No switches recorded yet.\n")
+		return nil
+	}
+
+	w := tabwriter.NewWriter(a.stdout, 0, 0, 3, ' ', 0)
+	fmt.Fprintln(w, "TIME\tFROM\tTO\tSCOPE")
+
+	// Newest first
+	for i := len(entries) - 1; i >= 0 && i >= len(entries)-limit; i-- {
+		e := entries[i]
+		from := e.From
+		if from == "" {
+			from = "-"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", e.Time.Local().Format("2006-01-02 15:04:05"), from, e.To, e.Scope)
+	}
+	return w.Flush()
+}
+
+func newAliasCmd(a *app) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "alias",
+		Short: "Manage version aliases",
+		Long: "Aliases name an installation (e.g. 'work' -> corretto-17) and can be used anywhere a version is accepted.\n" +
+			"The 'default' alias is the fallback when no project file or global selection applies.",
+		Args: exactArgs(0),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runAliasList(a)
+		},
+	}
+
+	cmd.AddCommand(
+		&cobra.Command{
+			Use:     "list",
+			Aliases: []string{"ls"},
+			Short:   "List aliases",
+			Args:    exactArgs(0),
+			RunE: func(cmd *cobra.Command, args []string) error {
+				return runAliasList(a)
+			},
+		},
+		&cobra.Command{
+			Use:               "set <name> <version>",
+			Short:             "Point an alias at an installation",
+			Args:              exactArgs(2),
+			ValidArgsFunction: a.completeAliasSet,
+			RunE: func(cmd *cobra.Command, args []string) error {
+				return runAliasSet(a, args[0], args[1])
+			},
+		},
+		&cobra.Command{
+			Use:               "rm <name>",
+			Aliases:           []string{"unset", "remove"},
+			Short:             "Remove an alias",
+			Args:              exactArgs(1),
+			ValidArgsFunction: a.completeAliasNames,
+			RunE: func(cmd *cobra.Command, args []string) error {
+				return runAliasRemove(a, args[0])
+			},
+		},
+	)
+	return cmd
+}
+
+func runAliasList(a *app) error {
+	cfg, err := loadConfig()
+	if err != nil {
+		return err
+	}
+
+	if len(cfg.Aliases) == 0 {
+		a.infof("No aliases defined. Create one with 'jswitch alias set <name> <version>'.\n")
+		return nil
+	}
+	w := tabwriter.NewWriter(a.stdout, 0, 0, 3, ' ', 0)
+	fmt.Fprintln(w, "ALIAS\tVERSION\tVENDOR")
+	for _, name := range cfg.AliasNames() {
+		alias := cfg.Aliases[name]
+		fmt.Fprintf(w, "%s\t%s\t%s\n", name, alias.Target, alias.Vendor)
+	}
+	return w.Flush()
+}
+
+func runAliasSet(a *app, name, spec string) error {
+	var inst models.JavaInstallation
+	var nothingSelected bool
+	err := updateConfig(func(cfg *config.Config) error {
+		var err error
+		inst, err = cfg.SetAlias(name, spec)
+		nothingSelected = cfg.CurrentVersionPath(cfg.CurrentVersion) == ""
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	a.infof("Alias %s -> %s (%s)\n", name, inst.Version, inst.Vendor)
+
+	// With nothing selected yet, the default alias becomes the active version.
+	if name == config.DefaultAlias && nothingSelected {
+		return runUse(a, config.DefaultAlias)
+	}
+	return nil
+}
+
+func runAliasRemove(a *app, name string) error {
+	err := updateConfig(func(cfg *config.Config) error {
+		if !cfg.RemoveAlias(name) {
+			return &cliError{code: exitNotFound, err: fmt.Errorf("alias %s does not exist", name)}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	a.infof("Removed alias %s.\n", name)
+	return nil
+}
@@ -0,0 +1,222 @@
+// Package alternatives registers JDKs with the update-alternatives system of Debian, Ubuntu,
+// Fedora, RHEL and SUSE, so that /usr/bin/java and friends can be switched for every user.
+package alternatives
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/user/jswitch/pkg/models"
+)
+
+// Tools are the commands registered as alternatives, each with its man page as a slave.
+// Tools an installation does not ship (javac in a JRE, jshell before Java 9) are skipped.
+var Tools = []string{"java", "javac", "jar", "jshell"}
+
+// Manager runs update-alternatives.
+type Manager struct {
+	// Binary is the update-alternatives executable.
+	Binary string
+	// AltDir and AdminDir are passed as --altdir and --admindir when set.
+	AltDir   string
+	AdminDir string
+	// Sudo runs the commands that change the system through sudo.
+	Sudo bool
+	// LinkDir and ManDir hold the generic links, /usr/bin and /usr/share/man/man1 by default.
+	LinkDir string
+	ManDir  string
+	// DryRun writes the commands that would change the system to Out instead of running them.
+	DryRun bool
+	Out    io.Writer
+}
+
+// Default locations of the generic links update-alternatives maintains.
+const (
+	DefaultLinkDir = "/usr/bin"
+	DefaultManDir  = "/usr/share/man/man1"
+)
+
+// New returns a Manager running binary, with the generic links in linkDir and manDir.
+// Empty directories select DefaultLinkDir and DefaultManDir.
+func New(binary, linkDir, manDir string) *Manager {
+	if linkDir == "" {
+		linkDir = DefaultLinkDir
+	}
+	if manDir == "" {
+		manDir = DefaultManDir
+	}
+	return &Manager{Binary: binary, LinkDir: linkDir, ManDir: manDir, Out: os.Stdout}
+}
+
+// Find returns a Manager for the update-alternatives on PATH (Debian's update-alternatives,
+// or alternatives on Red Hat systems) with the default link directories.
+func Find() (*Manager, error) {
+	for _, name := range []string{"update-alternatives", "alternatives"} {
+		if path, err := exec.LookPath(name); err == nil {
+			return New(path, "", ""), nil
+		}
+	}
+	return nil, fmt.Errorf("update-alternatives not found; --system needs a Linux distribution that uses it (Debian, Ubuntu, Fedora, RHEL, SUSE)")
+}
+
+// Priority derives an alternatives priority from a version so that newer JDKs rank higher
+// in auto mode: 17.0.9 becomes 1700009, 1.8.0_392 becomes 800392.
+func Priority(version string) int {
+	nums := numbers(version)
+	major := models.ParseMajorVersion(version)
+	if len(nums) > 1 && nums[0] == 1 {
+		// 1.8.0_392: the update is the last number.
+		return major*100000 + min(nums[len(nums)-1], 99999)
+	}
+	minor, patch := 0, 0
+	if len(nums) > 1 {
+		minor = nums[1]
+	}
+	if len(nums) > 2 {
+		patch = nums[2]
+	}
+	return major*100000 + min(minor, 99)*1000 + min(patch, 999)
+}
+
+// numbers returns the leading dot/underscore separated numbers of a version, stopping at a
+// build or pre-release suffix.
+func numbers(version string) []int {
+	if i := strings.IndexAny(version, "+-"); i >= 0 {
+		version = version[:i]
+	}
+	var nums []int
+	for _, part := range strings.FieldsFunc(version, func(r rune) bool { return r == '.' || r == '_' }) {
+		n := 0
+		for _, r := range part {
+			if r < '0' || r > '9' {
+				break
+			}
+			n = n*10 + int(r-'0')
+		}
+		nums = append(nums, n)
+	}
+	return nums
+}
+
+// tools returns the Tools inst ships.
+func tools(inst models.JavaInstallation) []string {
+	var out []string
+	for _, tool := range Tools {
+		if info, err := os.Stat(filepath.Join(inst.Path, "bin", tool)); err == nil && !info.IsDir() {
+			out = append(out, tool)
+		}
+	}
+	return out
+}
+
+// Register adds inst's tools as alternatives, with their man pages as slaves.
+// Registering again is harmless: update-alternatives updates the existing entry.
+func (m *Manager) Register(inst models.JavaInstallation) error {
+	priority := fmt.Sprint(Priority(inst.Version))
+	for _, tool := range tools(inst) {
+		args := []string{"--install", filepath.Join(m.LinkDir, tool), tool, filepath.Join(inst.Path, "bin", tool), priority}
+		for _, page := range []string{tool + ".1", tool + ".1.gz"} {
+			if man := filepath.Join(inst.Path, "man", "man1", page); exists(man) {
+				args = append(args, "--slave", filepath.Join(m.ManDir, page), page, man)
+				break
+			}
+		}
+		if err := m.change(args...); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Set selects inst's tools in manual mode.
+func (m *Manager) Set(inst models.JavaInstallation) error {
+	for _, tool := range tools(inst) {
+		if err := m.change("--set", tool, filepath.Join(inst.Path, "bin", tool)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Current returns the JDK home /usr/bin/java points at, or "" if java is not an alternative.
+// It only reads, so it also runs in dry-run mode and without sudo.
+func (m *Manager) Current() (string, error) {
+	out, err := exec.Command(m.Binary, m.args("--query", "java")...).Output()
+	if err != nil {
+		return "", nil
+	}
+	sc := bufio.NewScanner(bytes.NewReader(out))
+	for sc.Scan() {
+		if value, ok := strings.CutPrefix(sc.Text(), "Value: "); ok {
+			// <home>/bin/java
+			return filepath.Dir(filepath.Dir(strings.TrimSpace(value))), nil
+		}
+	}
+	return "", sc.Err()
+}
+
+// Command returns the command line change would run, for display.
+func (m *Manager) Command(args ...string) []string {
+	cmd := append([]string{m.Binary}, m.args(args...)...)
+	if m.Sudo {
+		cmd = append([]string{"sudo"}, cmd...)
+	}
+	return cmd
+}
+
+func (m *Manager) args(args ...string) []string {
+	var out []string
+	if m.AltDir != "" {
+		out = append(out, "--altdir", m.AltDir)
+	}
+	if m.AdminDir != "" {
+		out = append(out, "--admindir", m.AdminDir)
+	}
+	return append(out, args...)
+}
+
+// change runs a command that modifies the alternatives, or prints it in dry-run mode.
+func (m *Manager) change(args ...string) error {
+	command := m.Command(args...)
+	if m.DryRun {
+		fmt.Fprintln(m.Out, Quote(command))
+		return nil
+	}
+	cmd := exec.Command(command[0], command[1:]...)
+	var stderr bytes.Buffer
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = io.Discard
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		msg := strings.TrimSpace(stderr.String())
+		if msg == "" {
+			msg = err.Error()
+		}
+		return fmt.Errorf("%s failed: %s", Quote(command), msg)
+	}
+	return nil
+}
+
+// Quote joins a command line for a POSIX shell, quoting arguments that need it.
+func Quote(args []string) string {
+	quoted := make([]string, len(args))
+	for i, arg := range args {
+		if arg != "" && strings.Trim(arg, "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789-_./=+:@%,") == "" {
+			quoted[i] = arg
+		} else {
+			quoted[i] = "'" + strings.ReplaceAll(arg, "'", `'\''`) + "'"
+		}
+	}
+	return strings.Join(quoted, " ")
+}
+
+func exists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
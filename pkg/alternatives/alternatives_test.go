@@ -0,0 +1,133 @@
+package alternatives
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/user/jswitch/pkg/models"
+)
+
+func TestPriority(t *testing.T) {
+	tests := []struct {
+		version string
+		want    int
+	}{
+		{"17.0.9", 1700009},
+		{"21", 2100000},
+		{"21.0.1+12", 2100001},
+		{"1.8.0_392", 800392},
+		{"11.0.22-ea", 1100022},
+	}
+	for _, tt := range tests {
+		if got := Priority(tt.version); got != tt.want {
+			t.Errorf("Priority(%q) = %d, want %d", tt.version, got, tt.want)
+		}
+	}
+}
+
+// fakeBinary writes a shell script standing in for update-alternatives that appends its
+// arguments, one per line, and an empty line to log.
+func fakeBinary(t *testing.T, dir, log string) string {
+	t.Helper()
+	if runtime.GOOS == "windows" {
+		t.Skip("update-alternatives is Linux-only")
+	}
+	path := filepath.Join(dir, "update-alternatives")
+	script := "#!/bin/sh\nfor arg in \"$@\"; do printf '%s\\n' \"$arg\" >> '" + log + "'; done\necho >> '" + log + "'\n"
+	if err := os.WriteFile(path, []byte(script), 0755); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// calls returns the argument lists the fake binary logged.
+func calls(t *testing.T, log string) [][]string {
+	t.Helper()
+	data, err := os.ReadFile(log)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var out [][]string
+	for _, call := range strings.Split(strings.TrimSuffix(string(data), "\n\n"), "\n\n") {
+		out = append(out, strings.Split(call, "\n"))
+	}
+	return out
+}
+
+func touch(t *testing.T, path string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, nil, 0755); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestRegister(t *testing.T) {
+	dir := t.TempDir()
+	log := filepath.Join(dir, "calls.log")
+	home := filepath.Join(dir, "jdk-17.0.9")
+	// A JDK shipping java and javac with man pages (one compressed) and jar without one.
+	touch(t, filepath.Join(home, "bin", "java"))
+	touch(t, filepath.Join(home, "bin", "javac"))
+	touch(t, filepath.Join(home, "bin", "jar"))
+	touch(t, filepath.Join(home, "man", "man1", "java.1"))
+	touch(t, filepath.Join(home, "man", "man1", "javac.1.gz"))
+
+	links, man := filepath.Join(dir, "bin"), filepath.Join(dir, "man1")
+	m := New(fakeBinary(t, dir, log), links, man)
+	m.AltDir = filepath.Join(dir, "alternatives")
+	m.AdminDir = filepath.Join(dir, "admin")
+	inst := models.JavaInstallation{Version: "17.0.9", MajorVersion: 17, Path: home}
+	if err := m.Register(inst); err != nil {
+		t.Fatal(err)
+	}
+
+	prefix := []string{"--altdir", m.AltDir, "--admindir", m.AdminDir}
+	want := [][]string{
+		append(slices.Clone(prefix), "--install", filepath.Join(links, "java"), "java", filepath.Join(home, "bin", "java"), "1700009",
+			"--slave", filepath.Join(man, "java.1"), "java.1", filepath.Join(home, "man", "man1", "java.1")),
+		append(slices.Clone(prefix), "--install", filepath.Join(links, "javac"), "javac", filepath.Join(home, "bin", "javac"), "1700009",
+			"--slave", filepath.Join(man, "javac.1.gz"), "javac.1.gz", filepath.Join(home, "man", "man1", "javac.1.gz")),
+		append(slices.Clone(prefix), "--install", filepath.Join(links, "jar"), "jar", filepath.Join(home, "bin", "jar"), "1700009"),
+	}
+	got := calls(t, log)
+	if len(got) != len(want) {
+		t.Fatalf("got %d calls, want %d: %q", len(got), len(want), got)
+	}
+	for i := range want {
+		if !slices.Equal(got[i], want[i]) {
+			t.Errorf("call %d:\n got  %q\n want %q", i, got[i], want[i])
+		}
+	}
+}
+
+func TestDryRun(t *testing.T) {
+	dir := t.TempDir()
+	log := filepath.Join(dir, "calls.log")
+	home := filepath.Join(dir, "jdk 21")
+	touch(t, filepath.Join(home, "bin", "java"))
+
+	var out bytes.Buffer
+	m := New(fakeBinary(t, dir, log), "", "")
+	m.DryRun, m.Sudo, m.Out = true, true, &out
+	if err := m.Set(models.JavaInstallation{Version: "21", Path: home}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(log); !os.IsNotExist(err) {
+		t.Errorf("dry run ran the binary")
+	}
+	want := "sudo " + m.Binary + " --set java '" + filepath.Join(home, "bin", "java") + "'\n"
+	if out.String() != want {
+		t.Errorf("dry run printed %q, want %q", out.String(), want)
+	}
+	if m.LinkDir != DefaultLinkDir || m.ManDir != DefaultManDir {
+		t.Errorf("New with empty dirs = %q, %q", m.LinkDir, m.ManDir)
+	}
+}
@@ -0,0 +1,290 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/user/jswitch/pkg/models"
+)
+
+// DefaultAlias is the alias used as the global fallback when nothing more specific applies.
+const DefaultAlias = "default"
+
+// Alias is a user-defined name for an installation.
+// Vendor and MajorVersion are remembered so the alias can be re-pointed
+// at the replacement build when the original installation goes away.
+type Alias struct {
+	Target       string `json:"target"`
+	Vendor       string `json:"vendor,omitempty"`
+	MajorVersion int    `json:"major_version,omitempty"`
+}
+
+// NotFoundError is returned when a version specifier matches no installation.
+type NotFoundError struct {
+	Spec   string
+	Reason string
+}
+
+func (e *NotFoundError) Error() string {
+	return e.Reason
+}
+
+// vendorNames maps common vendor shorthands to substrings of JavaInstallation.Vendor.
+var vendorNames = map[string][]string{
+	"temurin":  {"temurin", "adoptium"},
+	"adoptium": {"temurin", "adoptium"},
+	"openjdk":  {"openjdk"},
+	"oracle":   {"oracle"},
+	"zulu":     {"zulu"},
+	"azul":     {"zulu", "azul"},
+	"corretto": {"corretto", "amazon"},
+}
+
+// Resolve turns a version specifier into a configured installation.
+// Accepted specifiers, in order of precedence:
+//   - an alias name ("work", "default")
+//   - an exact version ("17.0.2", "1.8.0_392")
+//   - an installation path
+//   - a major version ("17"), picking the newest matching build of the
+//     default-vendor setting if there is one, otherwise of any vendor
+//   - a vendor and major version ("corretto-17", "temurin-21")
+//
+// An alias resolves to its target build from its vendor. If that is no longer installed,
+// it resolves to the installation ReconcileAliases would re-point it at; Resolve itself
+// never changes c.
+func (c *Config) Resolve(spec string) (models.JavaInstallation, error) {
+	if alias, ok := c.Aliases[spec]; ok {
+		if inst, ok := c.FindBuild(alias.Target, alias.Vendor, ""); ok {
+			return inst, nil
+		}
+		if inst, ok := c.retarget(alias); ok {
+			return inst, nil
+		}
+		return models.JavaInstallation{}, &NotFoundError{Spec: spec, Reason: fmt.Sprintf("alias %q points at %s, which is no longer installed", spec, alias.Target)}
+	}
+
+	return c.resolveInstallation(spec)
+}
+
+// resolveInstallation resolves everything except aliases.
+func (c *Config) resolveInstallation(spec string) (models.JavaInstallation, error) {
+	if inst, ok := c.findVersion(spec); ok {
+		return inst, nil
+	}
+	for _, inst := range c.Installations {
+		if inst.Path == spec {
+			return inst, nil
+		}
+	}
+
+	vendor, majorStr := "", spec
+	if i := strings.LastIndex(spec, "-"); i > 0 {
+		vendor, majorStr = strings.ToLower(spec[:i]), spec[i+1:]
+	}
+	major, err := strconv.Atoi(majorStr)
+	if err != nil {
+		return models.JavaInstallation{}, &NotFoundError{Spec: spec, Reason: fmt.Sprintf("version %s not found. Run 'jswitch list' to see options", spec)}
+	}
+
+	if vendor == "" {
+		if preferred := c.SettingString(SettingDefaultVendor); preferred != "" {
+			if inst, ok := c.newest(func(inst models.JavaInstallation) bool {
+				return majorOf(inst) == major && matchesVendor(inst.Vendor, preferred)
+			}); ok {
+				return inst, nil
+			}
+		}
+	}
+
+	inst, ok := c.newest(func(inst models.JavaInstallation) bool {
+		return majorOf(inst) == major && (vendor == "" || matchesVendor(inst.Vendor, vendor))
+	})
+	if !ok {
+		return models.JavaInstallation{}, &NotFoundError{Spec: spec, Reason: fmt.Sprintf("no installation matches %s. Run 'jswitch list' to see options", spec)}
+	}
+	return inst, nil
+}
+
+// SetAlias points name at the installation resolved from spec.
+func (c *Config) SetAlias(name, spec string) (models.JavaInstallation, error) {
+	if err := validateAliasName(name); err != nil {
+		return models.JavaInstallation{}, err
+	}
+	if _, ok := c.findVersion(name); ok {
+		return models.JavaInstallation{}, fmt.Errorf("alias %q would shadow an installed version", name)
+	}
+
+	inst, err := c.Resolve(spec)
+	if err != nil {
+		return models.JavaInstallation{}, err
+	}
+
+	if c.Aliases == nil {
+		c.Aliases = make(map[string]Alias)
+	}
+	c.Aliases[name] = Alias{
+		Target:       inst.Version,
+		Vendor:       inst.Vendor,
+		MajorVersion: majorOf(inst),
+	}
+	return inst, nil
+}
+
+// RemoveAlias deletes an alias and the environment variables attached to it. Returns false if
+// it did not exist.
+func (c *Config) RemoveAlias(name string) bool {
+	if _, ok := c.Aliases[name]; !ok {
+		return false
+	}
+	delete(c.Aliases, name)
+	delete(c.Env, name)
+	return true
+}
+
+// AliasNames returns the alias names in sorted order.
+func (c *Config) AliasNames() []string {
+	names := make([]string, 0, len(c.Aliases))
+	for name := range c.Aliases {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// AliasesFor returns the names of all aliases pointing at version.
+func (c *Config) AliasesFor(version string) []string {
+	var names []string
+	for _, name := range c.AliasNames() {
+		if c.Aliases[name].Target == version {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// ReconcileAliases re-points aliases whose target is no longer installed at the newest
+// installation of the same vendor and major version. Returns the names that changed.
+func (c *Config) ReconcileAliases() []string {
+	var changed []string
+	for _, name := range c.AliasNames() {
+		alias := c.Aliases[name]
+		if _, ok := c.FindBuild(alias.Target, alias.Vendor, ""); ok {
+			continue
+		}
+		inst, ok := c.retarget(alias)
+		if !ok {
+			continue
+		}
+		alias.Target = inst.Version
+		c.Aliases[name] = alias
+		changed = append(changed, name)
+	}
+	return changed
+}
+
+// retarget returns the installation ReconcileAliases would re-point alias at: the newest
+// of the same vendor and major version.
+func (c *Config) retarget(alias Alias) (models.JavaInstallation, bool) {
+	if alias.MajorVersion == 0 {
+		return models.JavaInstallation{}, false
+	}
+	return c.newest(func(inst models.JavaInstallation) bool {
+		return majorOf(inst) == alias.MajorVersion && strings.EqualFold(inst.Vendor, alias.Vendor)
+	})
+}
+
+func (c *Config) findVersion(version string) (models.JavaInstallation, bool) {
+	for _, inst := range c.Installations {
+		if inst.Version == version {
+			return inst, true
+		}
+	}
+	return models.JavaInstallation{}, false
+}
+
+// FindBuild returns the installation of exactly version from vendor with the given image
+// type ("jdk" or "jre"), the build a jswitch.lock pins. An empty vendor or image type
+// matches any.
+func (c *Config) FindBuild(version, vendor, imageType string) (models.JavaInstallation, bool) {
+	for _, inst := range c.Installations {
+		if inst.Version != version {
+			continue
+		}
+		if vendor != "" && !matchesVendor(inst.Vendor, strings.ToLower(vendor)) {
+			continue
+		}
+		if imageType != "" && !strings.EqualFold(ImageType(inst), imageType) {
+			continue
+		}
+		return inst, true
+	}
+	return models.JavaInstallation{}, false
+}
+
+// ImageType reports whether inst is a "jdk" or a "jre", which ships no javac.
+func ImageType(inst models.JavaInstallation) string {
+	javac := "javac"
+	if runtime.GOOS == "windows" {
+		javac = "javac.exe"
+	}
+	if info, err := os.Stat(filepath.Join(inst.Path, "bin", javac)); err == nil && !info.IsDir() {
+		return "jdk"
+	}
+	return "jre"
+}
+
+// newest returns the installation with the highest version among those matching keep.
+func (c *Config) newest(keep func(models.JavaInstallation) bool) (models.JavaInstallation, bool) {
+	var best models.JavaInstallation
+	found := false
+	for _, inst := range c.Installations {
+		if !keep(inst) {
+			continue
+		}
+		if !found || models.CompareVersions(inst.Version, best.Version) > 0 {
+			best = inst
+			found = true
+		}
+	}
+	return best, found
+}
+
+// majorOf returns the major version, parsing it from Version for entries saved without one.
+func majorOf(inst models.JavaInstallation) int {
+	if inst.MajorVersion != 0 {
+		return inst.MajorVersion
+	}
+	return models.ParseMajorVersion(inst.Version)
+}
+
+func matchesVendor(vendor, want string) bool {
+	vendor = strings.ToLower(vendor)
+	needles, ok := vendorNames[want]
+	if !ok {
+		needles = []string{want}
+	}
+	for _, n := range needles {
+		if strings.Contains(vendor, n) {
+			return true
+		}
+	}
+	return false
+}
+
+func validateAliasName(name string) error {
+	if name == "" || name == "-" {
+		return fmt.Errorf("invalid alias name %q", name)
+	}
+	if _, err := strconv.Atoi(name); err == nil {
+		return fmt.Errorf("alias name %q would shadow a major version", name)
+	}
+	if strings.ContainsAny(name, " \t/\\") {
+		return fmt.Errorf("alias name %q must not contain spaces or slashes", name)
+	}
+	return nil
+}
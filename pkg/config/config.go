@@ -1,42 +1,120 @@
 package config
 
 import (
+	"bytes"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
 
+	"github.com/user/jswitch/pkg/fileutil"
 	"github.com/user/jswitch/pkg/models"
+	"github.com/user/jswitch/pkg/paths"
 )
 
+// Backup files kept next to the config file.
 const (
-	configDirName  = ".jswitch"
-	configFileName = "config.json"
+	backupSuffix  = ".bak"
+	corruptSuffix = ".corrupt"
+	lockSuffix    = ".lock"
 )
 
 // Config holds the persistent state of the application.
 type Config struct {
+	// SchemaVersion is the format of the file; see SchemaVersion and migrations.
+	SchemaVersion  int                       `json:"schema_version"`
 	CurrentVersion string                    `json:"current_version"`
 	Installations  []models.JavaInstallation `json:"installations"`
+	// Aliases maps user-defined names (including "default") to installations.
+	Aliases map[string]Alias `json:"aliases,omitempty"`
+	// Env holds environment variables by the version, alias or other spec they are attached
+	// to; the shell integration applies them while a matching installation is active (see EnvFor).
+	Env map[string]map[string]string `json:"env,omitempty"`
+	// Settings holds user preferences by key; see Settings for the registry.
+	Settings map[string]string `json:"settings,omitempty"`
+	// Projects are directories where jswitch wrote a .java-version or jswitch.lock. 'jswitch prune'
+	// keeps the installations they select.
+	Projects []string `json:"projects,omitempty"`
+	// Pinned are the paths of installations pinned with 'jswitch pin', which 'jswitch prune'
+	// and keep-patches never remove.
+	Pinned []string `json:"pinned,omitempty"`
+	// ShellSetupOffered records that the user has been asked about 'jswitch setup' once already.
+	ShellSetupOffered bool `json:"shell_setup_offered,omitempty"`
+
+	// system is the system config layered under this one by LoadConfig; see layer.
+	system *Config
+}
+
+// pathOverride replaces the default config location when set (see SetPath).
+var pathOverride string
+
+// SetPath makes LoadConfig and SaveConfig use path instead of the default location.
+// An empty path restores the default.
+func SetPath(path string) {
+	pathOverride = path
 }
 
-// getConfigPath returns the full path to the config file (e.g. ~/.jswitch/config.json).
+// getConfigPath returns the full path to the config file (see package paths for the default).
 func getConfigPath() (string, error) {
-	home, err := os.UserHomeDir()
-	if err != nil {
-		return "", fmt.Errorf("could not find user home directory: %w", err)
+	if pathOverride != "" {
+		return pathOverride, nil
 	}
-	return filepath.Join(home, configDirName, configFileName), nil
+	return paths.ConfigFile()
 }
 
-// LoadConfig reads the config file from disk.
-// Returns an empty config if the file does not exist.
+// LoadConfig reads the user config file from disk, with the system config (see
+// LoadSystemConfig) layered under it. Returns an empty config if neither file exists.
+// A system config that cannot be loaded is left out rather than failing every command;
+// the CLI warns about it on startup.
 func LoadConfig() (*Config, error) {
 	path, err := getConfigPath()
 	if err != nil {
 		return nil, err
 	}
+	cfg, err := loadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	cfg.layer(systemOrEmpty())
+	return cfg, nil
+}
+
+// SystemPath returns the location of the system config file, shared by every user.
+func SystemPath() (string, error) {
+	l, err := paths.System()
+	if err != nil {
+		return "", err
+	}
+	return l.ConfigFile(), nil
+}
 
+// LoadSystemConfig reads the system config on its own. A missing or unreadable file
+// yields an empty config: most machines have no shared installation root.
+func LoadSystemConfig() (*Config, error) {
+	path, err := SystemPath()
+	if err != nil {
+		return nil, err
+	}
+	cfg, err := loadFile(path)
+	if err != nil && errors.Is(err, os.ErrPermission) {
+		return &Config{Installations: []models.JavaInstallation{}}, nil
+	}
+	return cfg, err
+}
+
+// systemOrEmpty returns the system config, or an empty one if it cannot be loaded.
+func systemOrEmpty() *Config {
+	system, err := LoadSystemConfig()
+	if err != nil {
+		return &Config{Installations: []models.JavaInstallation{}}
+	}
+	return system
+}
+
+func loadFile(path string) (*Config, error) {
 	data, err := os.ReadFile(path)
 	if os.IsNotExist(err) {
 		// Return valid empty config if file doesn't exist
@@ -46,15 +124,18 @@ func LoadConfig() (*Config, error) {
 		return nil, fmt.Errorf("failed to read config file at %s: %w", path, err)
 	}
 
-	var cfg Config
-	if err := json.Unmarshal(data, &cfg); err != nil {
-		return nil, fmt.Errorf("failed to parse config file: %w", err)
+	cfg, err := decode(data)
+	if errors.Is(err, ErrNewerSchema) {
+		return nil, fmt.Errorf("config file %s: %w", path, err)
 	}
-
-	return &cfg, nil
+	if err != nil {
+		return nil, &ParseError{Path: path, Err: err}
+	}
+	return cfg, nil
 }
 
-// SaveConfig writes the config to disk.
+// SaveConfig writes the config to disk. Entries that come from the system config are
+// left out, so the user config only holds what the user changed.
 func SaveConfig(cfg *Config) error {
 	path, err := getConfigPath()
 	if err != nil {
@@ -66,19 +147,196 @@ func SaveConfig(cfg *Config) error {
 	if err := os.MkdirAll(dir, 0755); err != nil {
 		return fmt.Errorf("failed to create config directory %s: %w", dir, err)
 	}
+	return saveFile(path, cfg.userOnly(), 0644)
+}
 
+func saveFile(path string, cfg *Config, perm os.FileMode) error {
+	cfg.SchemaVersion = SchemaVersion
 	data, err := json.MarshalIndent(cfg, "", "  ")
 	if err != nil {
 		return fmt.Errorf("failed to marshal config: %w", err)
 	}
 
-	if err := os.WriteFile(path, data, 0644); err != nil {
+	if err := backup(path, data, perm); err != nil {
+		return fmt.Errorf("failed to back up config file: %w", err)
+	}
+	if err := fileutil.WriteFile(path, data, perm); err != nil {
 		return fmt.Errorf("failed to write config file to %s: %w", path, err)
 	}
 
 	return nil
 }
 
+// Update loads the config, applies fn and saves the result while holding an exclusive
+// lock on the config file, so concurrent jswitch processes cannot lose each other's
+// changes. Nothing is written if fn returns an error, which is returned unchanged.
+// fn must not call Update itself.
+func Update(fn func(cfg *Config) error) error {
+	lock, err := lockConfig()
+	if err != nil {
+		return err
+	}
+	defer lock.Unlock()
+
+	cfg, err := LoadConfig()
+	if err != nil {
+		return err
+	}
+	if err := fn(cfg); err != nil {
+		return err
+	}
+	return SaveConfig(cfg)
+}
+
+// UpdateSystem is Update for the system config. The file is kept group-writable so that
+// every member of the installation root's group can change it.
+func UpdateSystem(fn func(cfg *Config) error) error {
+	path, err := SystemPath()
+	if err != nil {
+		return err
+	}
+	if err := fileutil.MkdirShared(filepath.Dir(path)); err != nil {
+		return fmt.Errorf("failed to create %s: %w", filepath.Dir(path), err)
+	}
+	lock, err := lockFile(path)
+	if err != nil {
+		return err
+	}
+	defer lock.Unlock()
+
+	cfg, err := loadFile(path)
+	if err != nil {
+		return err
+	}
+	if err := fn(cfg); err != nil {
+		return err
+	}
+	return saveFile(path, cfg, fileutil.SharedFileMode)
+}
+
+// lockConfig takes the lock guarding read-modify-write cycles on the config file.
+func lockConfig() (*fileutil.FileLock, error) {
+	path, err := getConfigPath()
+	if err != nil {
+		return nil, err
+	}
+	return lockFile(path)
+}
+
+func lockFile(path string) (*fileutil.FileLock, error) {
+	lock, err := fileutil.Lock(path + lockSuffix)
+	if err != nil {
+		return nil, fmt.Errorf("failed to lock config: %w", err)
+	}
+	return lock, nil
+}
+
+// backup copies the file about to be replaced to <path>.bak, or to <path>.corrupt when it
+// does not decode, so a damaged file is kept without overwriting the last good backup.
+func backup(path string, replacement []byte, perm os.FileMode) error {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) || len(data) == 0 || bytes.Equal(data, replacement) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	suffix := backupSuffix
+	if _, err := decode(data); err != nil {
+		suffix = corruptSuffix
+	}
+	return fileutil.WriteFile(path+suffix, data, perm)
+}
+
+// AddInstallation records inst, replacing any existing entry at the same path.
+func (c *Config) AddInstallation(inst models.JavaInstallation) {
+	for i, existing := range c.Installations {
+		if existing.Path == inst.Path {
+			c.Installations[i] = inst
+			return
+		}
+	}
+	c.Installations = append(c.Installations, inst)
+}
+
+// AddProject remembers dir, an absolute path, as a project. Returns false if it was known already.
+func (c *Config) AddProject(dir string) bool {
+	for _, p := range c.Projects {
+		if p == dir {
+			return false
+		}
+	}
+	c.Projects = append(c.Projects, dir)
+	return true
+}
+
+// RemoveProject forgets dir.
+func (c *Config) RemoveProject(dir string) {
+	kept := c.Projects[:0]
+	for _, p := range c.Projects {
+		if p != dir {
+			kept = append(kept, p)
+		}
+	}
+	c.Projects = kept
+}
+
+// Pin protects the installation at path from pruning. Returns false if it was pinned already.
+func (c *Config) Pin(path string) bool {
+	if c.IsPinned(path) {
+		return false
+	}
+	c.Pinned = append(c.Pinned, path)
+	return true
+}
+
+// Unpin releases the pin of the installation at path. Returns false if it was not pinned.
+func (c *Config) Unpin(path string) bool {
+	kept := c.Pinned[:0]
+	for _, p := range c.Pinned {
+		if p != path {
+			kept = append(kept, p)
+		}
+	}
+	found := len(kept) < len(c.Pinned)
+	c.Pinned = kept
+	return found
+}
+
+// IsPinned reports whether the installation at path is pinned.
+func (c *Config) IsPinned(path string) bool {
+	for _, p := range c.Pinned {
+		if p == path {
+			return true
+		}
+	}
+	return false
+}
+
+// RelocateInstallations rewrites installation paths under oldDir to live under newDir,
+// after the directory has been moved. Returns the number of entries changed.
+func (c *Config) RelocateInstallations(oldDir, newDir string) int {
+	changed := 0
+	for i, inst := range c.Installations {
+		rel, err := filepath.Rel(oldDir, inst.Path)
+		if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			continue
+		}
+		if c.Unpin(inst.Path) {
+			c.Pin(filepath.Join(newDir, rel))
+		}
+		c.Installations[i].Path = filepath.Join(newDir, rel)
+		changed++
+	}
+	return changed
+}
+
+// Path returns the location of the config file.
+func Path() (string, error) {
+	return getConfigPath()
+}
+
 // CurrentVersionPath returns the path for the given version, or empty string.
 func (c *Config) CurrentVersionPath(version string) string {
 	for _, inst := range c.Installations {
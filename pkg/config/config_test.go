@@ -0,0 +1,250 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/user/jswitch/pkg/models"
+	"github.com/user/jswitch/pkg/paths"
+)
+
+// useTempConfig points the config, the jswitch home and the system root at a temporary
+// directory and returns the config path.
+func useTempConfig(t *testing.T) string {
+	t.Helper()
+	dir := t.TempDir()
+	t.Setenv(paths.EnvHome, filepath.Join(dir, "home"))
+	t.Setenv(paths.EnvSystemHome, filepath.Join(dir, "system"))
+	path := filepath.Join(dir, "home", "config.json")
+	SetPath(path)
+	t.Cleanup(func() { SetPath("") })
+	return path
+}
+
+func TestUpdateConcurrentWriters(t *testing.T) {
+	path := useTempConfig(t)
+
+	const writers = 40
+	var wg sync.WaitGroup
+	errs := make(chan error, writers)
+	for i := 0; i < writers; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			errs <- Update(func(cfg *Config) error {
+				cfg.AddProject(fmt.Sprintf("/projects/%d", i))
+				return nil
+			})
+		}(i)
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			t.Fatalf("Update: %v", err)
+		}
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var cfg Config
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		t.Fatalf("config does not decode after concurrent updates: %v", err)
+	}
+	if len(cfg.Projects) != writers {
+		t.Fatalf("got %d projects, want %d: updates were lost", len(cfg.Projects), writers)
+	}
+	seen := make(map[string]bool)
+	for _, p := range cfg.Projects {
+		seen[p] = true
+	}
+	for i := 0; i < writers; i++ {
+		if p := fmt.Sprintf("/projects/%d", i); !seen[p] {
+			t.Errorf("project %s is missing", p)
+		}
+	}
+}
+
+func TestLoadConfigDuringSave(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("replacing a file that is open for reading fails on Windows")
+	}
+	useTempConfig(t)
+	if err := SaveConfig(&Config{}); err != nil {
+		t.Fatal(err)
+	}
+
+	var done atomic.Bool
+	var wg sync.WaitGroup
+	errs := make(chan error, 4)
+	for r := 0; r < 4; r++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for !done.Load() {
+				cfg, err := LoadConfig()
+				if err != nil {
+					errs <- err
+					return
+				}
+				for i, p := range cfg.Projects {
+					if p != fmt.Sprintf("/projects/%d", i) {
+						errs <- fmt.Errorf("read a partial config: project %d is %q", i, p)
+						return
+					}
+				}
+			}
+		}()
+	}
+
+	cfg := &Config{}
+	for i := 0; i < 200; i++ {
+		cfg.Projects = append(cfg.Projects, fmt.Sprintf("/projects/%d", i))
+		if err := SaveConfig(cfg); err != nil {
+			t.Fatalf("SaveConfig: %v", err)
+		}
+	}
+	done.Store(true)
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Errorf("LoadConfig during SaveConfig: %v", err)
+	}
+}
+
+func TestRepairKeepsReferencesToSystemInstallations(t *testing.T) {
+	path := useTempConfig(t)
+	home := filepath.Join(t.TempDir(), "jdk-21")
+	if err := os.MkdirAll(filepath.Join(home, "bin"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(home, "bin", "java"), nil, 0755); err != nil {
+		t.Fatal(err)
+	}
+	shared := models.JavaInstallation{Version: "21.0.1", MajorVersion: 21, Vendor: "Temurin", Path: home}
+	systemPath, err := SystemPath()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.MkdirAll(filepath.Dir(systemPath), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := saveFile(systemPath, &Config{Installations: []models.JavaInstallation{shared}}, 0644); err != nil {
+		t.Fatal(err)
+	}
+	user := &Config{
+		Installations:  []models.JavaInstallation{},
+		CurrentVersion: "21.0.1",
+		Aliases:        map[string]Alias{"work": {Target: "21.0.1"}},
+		Settings:       map[string]string{"no-such-setting": "x"},
+	}
+	if err := SaveConfig(user); err != nil {
+		t.Fatal(err)
+	}
+
+	report, err := Repair(false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(report.Fixed) != 1 {
+		t.Fatalf("fixed %d problems, want only the unknown setting: %+v", len(report.Fixed), report.Fixed)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var saved Config
+	if err := json.Unmarshal(data, &saved); err != nil {
+		t.Fatal(err)
+	}
+	if saved.CurrentVersion != "21.0.1" || saved.Aliases["work"].Target != "21.0.1" {
+		t.Errorf("repair dropped references to the system installation: %s", data)
+	}
+	if len(saved.Installations) != 0 {
+		t.Errorf("repair copied system installations into the user config: %s", data)
+	}
+}
+
+func TestRepairSalvagesFields(t *testing.T) {
+	path := useTempConfig(t)
+	// current_version has the wrong type, so the document does not decode as a whole.
+	damaged := `{"settings": {"color": "never"}, "projects": ["/src/app"], "installations": [], "aliases": {}, "current_version": 17}`
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(damaged), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	report, err := Repair(true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := report.Config.Settings[SettingColor]; got != "never" {
+		t.Errorf("settings were not salvaged: color = %q", got)
+	}
+	if got := report.Config.Projects; len(got) != 1 || got[0] != "/src/app" {
+		t.Errorf("projects were not salvaged: %q", got)
+	}
+}
+
+func TestResolveDoesNotRetargetAliases(t *testing.T) {
+	cfg := &Config{
+		Installations: []models.JavaInstallation{{Version: "21.0.2", MajorVersion: 21, Vendor: "Temurin", Path: "/jdks/21.0.2"}},
+		Aliases:       map[string]Alias{"work": {Target: "21.0.1", Vendor: "Temurin", MajorVersion: 21}},
+	}
+
+	inst, err := cfg.Resolve("work")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if inst.Version != "21.0.2" {
+		t.Errorf("Resolve(work) = %s, want the replacement 21.0.2", inst.Version)
+	}
+	if got := cfg.Aliases["work"].Target; got != "21.0.1" {
+		t.Errorf("Resolve re-pointed the alias at %s", got)
+	}
+
+	if changed := cfg.ReconcileAliases(); len(changed) != 1 || cfg.Aliases["work"].Target != "21.0.2" {
+		t.Errorf("ReconcileAliases changed %v, alias now %s", changed, cfg.Aliases["work"].Target)
+	}
+}
+
+func TestResolveAliasKeepsVendor(t *testing.T) {
+	cfg := &Config{
+		Installations: []models.JavaInstallation{
+			{Version: "17.0.9", MajorVersion: 17, Vendor: "Temurin", Path: "/jdks/temurin-17.0.9"},
+			{Version: "17.0.9", MajorVersion: 17, Vendor: "Azul Zulu", Path: "/jdks/zulu-17.0.9"},
+			{Version: "17.0.8", MajorVersion: 17, Vendor: "Azul Zulu", Path: "/jdks/zulu-17.0.8"},
+		},
+	}
+	if _, err := cfg.SetAlias("work", "zulu-17"); err != nil {
+		t.Fatal(err)
+	}
+	inst, err := cfg.Resolve("work")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if inst.Path != "/jdks/zulu-17.0.9" {
+		t.Errorf("Resolve(work) = %s, want the Zulu build of 17.0.9", inst.Path)
+	}
+
+	// Without the Zulu 17.0.9, the alias falls back to the newest Zulu 17, not to the
+	// Temurin build of the same version.
+	cfg.Installations = append(cfg.Installations[:1], cfg.Installations[2:]...)
+	if inst, err := cfg.Resolve("work"); err != nil || inst.Path != "/jdks/zulu-17.0.8" {
+		t.Errorf("Resolve(work) = %s, %v, want the Zulu build of 17.0.8", inst.Path, err)
+	}
+	if changed := cfg.ReconcileAliases(); len(changed) != 1 || cfg.Aliases["work"].Target != "17.0.8" {
+		t.Errorf("ReconcileAliases changed %v, alias now %s", changed, cfg.Aliases["work"].Target)
+	}
+}
@@ -0,0 +1,136 @@
+package config
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/user/jswitch/pkg/models"
+)
+
+var envName = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// reservedEnv are the variables the shell integration manages itself.
+var reservedEnv = map[string]bool{"JAVA_HOME": true, "PATH": true, "_JSWITCH_ENV": true}
+
+// ValidateEnvName checks that name can be attached to an installation with SetEnv.
+func ValidateEnvName(name string) error {
+	if !envName.MatchString(name) {
+		return fmt.Errorf("invalid environment variable name %q", name)
+	}
+	if reservedEnv[strings.ToUpper(name)] {
+		return fmt.Errorf("%s is set by jswitch itself and cannot be attached to an installation", name)
+	}
+	return nil
+}
+
+// SetEnv attaches name=value to spec, which is anything Resolve accepts: the variable applies
+// while an installation spec matches is active (see EnvFor). spec must match an installation now.
+func (c *Config) SetEnv(spec, name, value string) error {
+	if err := ValidateEnvName(name); err != nil {
+		return err
+	}
+	if _, err := c.Resolve(spec); err != nil {
+		return err
+	}
+	if c.Env == nil {
+		c.Env = make(map[string]map[string]string)
+	}
+	if c.Env[spec] == nil {
+		c.Env[spec] = make(map[string]string)
+	}
+	c.Env[spec][name] = value
+	return nil
+}
+
+// UnsetEnv removes name from spec. Returns false if it was not set.
+func (c *Config) UnsetEnv(spec, name string) bool {
+	vars, ok := c.Env[spec]
+	if !ok {
+		return false
+	}
+	if _, ok := vars[name]; !ok {
+		return false
+	}
+	delete(vars, name)
+	if len(vars) == 0 {
+		delete(c.Env, spec)
+	}
+	return true
+}
+
+// EnvSpecs returns the specs with variables attached, in sorted order.
+func (c *Config) EnvSpecs() []string {
+	specs := make([]string, 0, len(c.Env))
+	for spec := range c.Env {
+		specs = append(specs, spec)
+	}
+	sort.Strings(specs)
+	return specs
+}
+
+// EnvFor returns the variables that apply while inst is active. Every spec matching inst
+// contributes, and more specific ones win: a major version ("21") is overridden by a vendor
+// and major version ("temurin-21"), that by an exact version or path, and that by an alias
+// pointing at inst.
+func (c *Config) EnvFor(inst models.JavaInstallation) map[string]string {
+	type match struct {
+		spec string
+		rank int
+	}
+	var matches []match
+	for _, spec := range c.EnvSpecs() {
+		if rank, ok := c.envMatch(spec, inst); ok {
+			matches = append(matches, match{spec, rank})
+		}
+	}
+	sort.SliceStable(matches, func(i, j int) bool { return matches[i].rank < matches[j].rank })
+
+	env := make(map[string]string)
+	for _, m := range matches {
+		for name, value := range c.Env[m.spec] {
+			env[name] = value
+		}
+	}
+	return env
+}
+
+// envMatch reports whether spec applies to inst, and how specifically.
+func (c *Config) envMatch(spec string, inst models.JavaInstallation) (int, bool) {
+	if alias, ok := c.Aliases[spec]; ok {
+		return 3, alias.Target == inst.Version
+	}
+	if spec == inst.Version || spec == inst.Path {
+		return 2, true
+	}
+	vendor, majorStr := "", spec
+	if i := strings.LastIndex(spec, "-"); i > 0 {
+		vendor, majorStr = strings.ToLower(spec[:i]), spec[i+1:]
+	}
+	major, err := strconv.Atoi(majorStr)
+	if err != nil || major != majorOf(inst) {
+		return 0, false
+	}
+	if vendor == "" {
+		return 0, true
+	}
+	return 1, matchesVendor(inst.Vendor, vendor)
+}
+
+// EnvMatches reports whether the variables attached to spec apply while inst is active.
+func (c *Config) EnvMatches(spec string, inst models.JavaInstallation) bool {
+	_, ok := c.envMatch(spec, inst)
+	return ok
+}
+
+// envApplies reports whether spec matches any installation.
+func (c *Config) envApplies(spec string) bool {
+	for _, inst := range c.Installations {
+		if c.EnvMatches(spec, inst) {
+			return true
+		}
+	}
+	return false
+}
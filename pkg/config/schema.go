@@ -0,0 +1,119 @@
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/user/jswitch/pkg/models"
+)
+
+// SchemaVersion is the config format written by this build.
+// Bump it together with a new entry in migrations whenever the format changes.
+const SchemaVersion = 1
+
+// migrations[i] upgrades a decoded document from schema version i to i+1.
+var migrations = []func(doc map[string]any) error{
+	migrateV0,
+}
+
+// ErrNewerSchema is returned for a config written by a newer jswitch. Such a file is
+// left alone rather than treated as damaged.
+var ErrNewerSchema = errors.New("config was written by a newer version of jswitch")
+
+// ParseError is returned when the config file cannot be decoded.
+type ParseError struct {
+	Path string
+	Err  error
+}
+
+func (e *ParseError) Error() string {
+	return fmt.Sprintf("config file %s is damaged: %v. Run 'jswitch config repair' to recover it", e.Path, e.Err)
+}
+
+func (e *ParseError) Unwrap() error {
+	return e.Err
+}
+
+// decode parses a config document of any known schema version.
+func decode(data []byte) (*Config, error) {
+	var doc map[string]any
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, err
+	}
+	if doc == nil {
+		return nil, fmt.Errorf("expected a JSON object")
+	}
+	if err := migrate(doc); err != nil {
+		return nil, err
+	}
+
+	migrated, err := json.Marshal(doc)
+	if err != nil {
+		return nil, err
+	}
+	var cfg Config
+	if err := json.Unmarshal(migrated, &cfg); err != nil {
+		return nil, err
+	}
+	if cfg.Installations == nil {
+		cfg.Installations = []models.JavaInstallation{}
+	}
+	return &cfg, nil
+}
+
+// migrate upgrades doc in place to SchemaVersion.
+func migrate(doc map[string]any) error {
+	version, err := schemaVersionOf(doc)
+	if err != nil {
+		return err
+	}
+	if version > SchemaVersion {
+		return fmt.Errorf("%w (schema version %d, this build supports %d); upgrade jswitch", ErrNewerSchema, version, SchemaVersion)
+	}
+	for ; version < SchemaVersion; version++ {
+		if err := migrations[version](doc); err != nil {
+			return fmt.Errorf("migrating from schema version %d: %w", version, err)
+		}
+	}
+	doc["schema_version"] = SchemaVersion
+	return nil
+}
+
+// schemaVersionOf returns the document's schema version; files from before versioning are 0.
+func schemaVersionOf(doc map[string]any) (int, error) {
+	v, ok := doc["schema_version"]
+	if !ok {
+		return 0, nil
+	}
+	f, ok := v.(float64)
+	if !ok || f < 0 || f != float64(int(f)) {
+		return 0, fmt.Errorf("invalid schema_version %v", v)
+	}
+	return int(f), nil
+}
+
+// migrateV0 upgrades unversioned files: a null installation list becomes empty and
+// entries saved before major_version was recorded get it parsed from the version.
+func migrateV0(doc map[string]any) error {
+	list, ok := doc["installations"].([]any)
+	if !ok {
+		if doc["installations"] != nil {
+			return fmt.Errorf("installations is not a list")
+		}
+		doc["installations"] = []any{}
+		return nil
+	}
+	for _, item := range list {
+		inst, ok := item.(map[string]any)
+		if !ok {
+			continue
+		}
+		if major, _ := inst["major_version"].(float64); major == 0 {
+			if version, ok := inst["version"].(string); ok {
+				inst["major_version"] = models.ParseMajorVersion(version)
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,269 @@
+package config
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Setting keys.
+const (
+	SettingDefaultVendor = "default-vendor"
+	SettingImageType     = "image-type"
+	SettingInstallDir    = "install-dir"
+	SettingAutoSwitch    = "auto-switch"
+	SettingColor         = "color"
+	SettingMirror        = "mirror"
+	SettingParallelism   = "parallelism"
+	SettingKeepPatches   = "keep-patches"
+	SettingDedupe        = "dedupe-after-install"
+	SettingTrustPassword = "truststore-password"
+
+	SettingSyncMavenToolchains = "sync-maven-toolchains"
+	SettingDetectBuildFiles    = "detect-build-files"
+)
+
+// Where an effective setting value came from.
+const (
+	SourceDefault = "default"
+	SourceConfig  = "config"
+	SourceSystem  = "system"
+	SourceEnv     = "env"
+)
+
+// Kind is the type of a setting's value.
+type Kind int
+
+const (
+	KindString Kind = iota
+	KindBool
+	KindInt
+	KindChoice
+	KindPath
+	KindURL
+)
+
+func (k Kind) String() string {
+	switch k {
+	case KindBool:
+		return "bool"
+	case KindInt:
+		return "int"
+	case KindChoice:
+		return "choice"
+	case KindPath:
+		return "path"
+	case KindURL:
+		return "url"
+	default:
+		return "string"
+	}
+}
+
+// Setting describes a user preference stored in the settings section of the config.
+type Setting struct {
+	Key         string
+	Kind        Kind
+	Default     string
+	Description string
+	// Choices lists the accepted values of a KindChoice setting.
+	Choices []string
+	// Min is the smallest accepted value of a KindInt setting.
+	Min int
+}
+
+// settings is the registry of known keys, in display order.
+var settings = []Setting{
+	{Key: SettingDefaultVendor, Kind: KindChoice,
+		Choices:     []string{"temurin", "corretto", "zulu", "openjdk", "oracle"},
+		Description: "vendor preferred when a bare major version (17) matches several installations; install only supports temurin"},
+	{Key: SettingImageType, Kind: KindChoice, Default: "jdk", Choices: []string{"jdk", "jre"},
+		Description: "package type downloaded by install"},
+	{Key: SettingInstallDir, Kind: KindPath,
+		Description: "where install puts downloaded JDKs (default: versions/ in the jswitch data directory)"},
+	{Key: SettingAutoSwitch, Kind: KindBool, Default: "false",
+		Description: "have the shell integration follow .java-version files on cd (bash, zsh, fish; re-run 'jswitch setup')"},
+	{Key: SettingColor, Kind: KindChoice, Default: "auto", Choices: []string{"auto", "always", "never"},
+		Description: "colored output; --no-color and NO_COLOR take precedence"},
+	{Key: SettingMirror, Kind: KindURL, Default: "https://api.adoptium.net",
+		Description: "base URL of the Adoptium API, for mirrors and proxies"},
+	{Key: SettingParallelism, Kind: KindInt, Default: "4", Min: 1,
+		Description: "number of installations scan verifies at once"},
+	{Key: SettingKeepPatches, Kind: KindInt, Default: "0", Min: 0,
+		Description: "after install, keep only this many patch releases per vendor and major version (0 keeps all)"},
+	{Key: SettingDedupe, Kind: KindBool, Default: "false",
+		Description: "run 'jswitch dedupe' after install, hardlinking files identical to other installations"},
+	{Key: SettingTrustPassword, Kind: KindString, Default: "changeit",
+		Description: "password of the JDK truststores 'jswitch certs' writes to"},
+	{Key: SettingSyncMavenToolchains, Kind: KindBool, Default: "false",
+		Description: "re-run 'jswitch export maven-toolchains' after install and scan"},
+	{Key: SettingDetectBuildFiles, Kind: KindBool, Default: "true",
+		Description: "without a .java-version, infer the project's version from pom.xml, build.gradle(.kts), Dockerfile or GitHub workflows"},
+}
+
+// Settings returns the registry of known settings, in display order.
+func Settings() []Setting {
+	return append([]Setting(nil), settings...)
+}
+
+// LookupSetting returns the registered setting for key.
+func LookupSetting(key string) (Setting, error) {
+	for _, s := range settings {
+		if s.Key == key {
+			return s, nil
+		}
+	}
+	keys := make([]string, len(settings))
+	for i, s := range settings {
+		keys[i] = s.Key
+	}
+	return Setting{}, fmt.Errorf("unknown setting %q (known: %s)", key, strings.Join(keys, ", "))
+}
+
+// EnvVar returns the environment variable that overrides the setting, e.g. JSWITCH_DEFAULT_VENDOR.
+func (s Setting) EnvVar() string {
+	return "JSWITCH_" + strings.ToUpper(strings.ReplaceAll(s.Key, "-", "_"))
+}
+
+// Normalize validates value and returns it in canonical form.
+func (s Setting) Normalize(value string) (string, error) {
+	value = strings.TrimSpace(value)
+	switch s.Kind {
+	case KindBool:
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return "", fmt.Errorf("%s must be true or false, got %q", s.Key, value)
+		}
+		return strconv.FormatBool(b), nil
+	case KindInt:
+		n, err := strconv.Atoi(value)
+		if err != nil || n < s.Min {
+			return "", fmt.Errorf("%s must be an integer of at least %d, got %q", s.Key, s.Min, value)
+		}
+		return strconv.Itoa(n), nil
+	case KindChoice:
+		lower := strings.ToLower(value)
+		for _, c := range s.Choices {
+			if c == lower {
+				return c, nil
+			}
+		}
+		return "", fmt.Errorf("%s must be one of %s, got %q", s.Key, strings.Join(s.Choices, ", "), value)
+	case KindPath:
+		if value == "" {
+			return "", fmt.Errorf("%s must not be empty", s.Key)
+		}
+		abs, err := filepath.Abs(value)
+		if err != nil {
+			return "", fmt.Errorf("%s: %w", s.Key, err)
+		}
+		return abs, nil
+	case KindURL:
+		u, err := url.Parse(value)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return "", fmt.Errorf("%s must be an http(s) URL, got %q", s.Key, value)
+		}
+		return strings.TrimSuffix(value, "/"), nil
+	default:
+		return value, nil
+	}
+}
+
+// SettingValue is the effective value of a setting.
+type SettingValue struct {
+	Setting
+	Value  string
+	Source string
+}
+
+// GetSetting returns the effective value of key: the environment override if set, then the
+// config, then the default. An invalid environment value is an error rather than ignored.
+func (c *Config) GetSetting(key string) (SettingValue, error) {
+	s, err := LookupSetting(key)
+	if err != nil {
+		return SettingValue{}, err
+	}
+	if env := os.Getenv(s.EnvVar()); env != "" {
+		value, err := s.Normalize(env)
+		if err != nil {
+			return SettingValue{}, fmt.Errorf("invalid %s: %w", s.EnvVar(), err)
+		}
+		return SettingValue{Setting: s, Value: value, Source: SourceEnv}, nil
+	}
+	if value, ok := c.Settings[key]; ok {
+		return SettingValue{Setting: s, Value: value, Source: SourceConfig}, nil
+	}
+	if c.system != nil {
+		if value, ok := c.system.Settings[key]; ok {
+			return SettingValue{Setting: s, Value: value, Source: SourceSystem}, nil
+		}
+	}
+	return SettingValue{Setting: s, Value: s.Default, Source: SourceDefault}, nil
+}
+
+// SetSetting validates value and stores it in the config.
+func (c *Config) SetSetting(key, value string) (string, error) {
+	s, err := LookupSetting(key)
+	if err != nil {
+		return "", err
+	}
+	value, err = s.Normalize(value)
+	if err != nil {
+		return "", err
+	}
+	if c.Settings == nil {
+		c.Settings = make(map[string]string)
+	}
+	c.Settings[key] = value
+	return value, nil
+}
+
+// UnsetSetting removes key from the config, restoring the default.
+// Returns false if it was not set.
+func (c *Config) UnsetSetting(key string) (bool, error) {
+	if _, err := LookupSetting(key); err != nil {
+		return false, err
+	}
+	if _, ok := c.Settings[key]; !ok {
+		return false, nil
+	}
+	delete(c.Settings, key)
+	return true, nil
+}
+
+// SettingKeys returns the keys stored in the config, sorted.
+func (c *Config) SettingKeys() []string {
+	keys := make([]string, 0, len(c.Settings))
+	for k := range c.Settings {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// SettingString returns the effective value of a setting, falling back to its default when
+// the environment override is invalid. Use GetSetting to report such errors.
+func (c *Config) SettingString(key string) string {
+	v, err := c.GetSetting(key)
+	if err != nil {
+		s, _ := LookupSetting(key)
+		return s.Default
+	}
+	return v.Value
+}
+
+// SettingBool returns the effective value of a KindBool setting.
+func (c *Config) SettingBool(key string) bool {
+	b, _ := strconv.ParseBool(c.SettingString(key))
+	return b
+}
+
+// SettingInt returns the effective value of a KindInt setting.
+func (c *Config) SettingInt(key string) int {
+	n, _ := strconv.Atoi(c.SettingString(key))
+	return n
+}
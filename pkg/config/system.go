@@ -0,0 +1,73 @@
+package config
+
+import "github.com/user/jswitch/pkg/models"
+
+// layer puts the system config under c. System installations whose path and version the
+// user config does not have are added, and system aliases the user has not defined apply.
+// Settings fall back to the system's in GetSetting.
+func (c *Config) layer(system *Config) {
+	c.system = system
+	for _, inst := range system.Installations {
+		if c.CurrentVersionPath(inst.Version) != "" || c.hasPath(inst.Path) {
+			continue
+		}
+		c.Installations = append(c.Installations, inst)
+	}
+	for name, alias := range system.Aliases {
+		if _, ok := c.Aliases[name]; ok {
+			continue
+		}
+		if c.Aliases == nil {
+			c.Aliases = make(map[string]Alias)
+		}
+		c.Aliases[name] = alias
+	}
+}
+
+// userOnly returns c without the entries layer added.
+func (c *Config) userOnly() *Config {
+	if c.system == nil {
+		return c
+	}
+	out := *c
+	out.system = nil
+	out.Installations = []models.JavaInstallation{}
+	for _, inst := range c.Installations {
+		if !c.IsSystem(inst) {
+			out.Installations = append(out.Installations, inst)
+		}
+	}
+	out.Aliases = nil
+	for name, alias := range c.Aliases {
+		if system, ok := c.system.Aliases[name]; ok && system == alias {
+			continue
+		}
+		if out.Aliases == nil {
+			out.Aliases = make(map[string]Alias)
+		}
+		out.Aliases[name] = alias
+	}
+	return &out
+}
+
+// IsSystem reports whether inst is an installation of the system config.
+func (c *Config) IsSystem(inst models.JavaInstallation) bool {
+	if c.system == nil {
+		return false
+	}
+	for _, s := range c.system.Installations {
+		if s.Path == inst.Path && s.Version == inst.Version {
+			return true
+		}
+	}
+	return false
+}
+
+func (c *Config) hasPath(path string) bool {
+	for _, inst := range c.Installations {
+		if inst.Path == path {
+			return true
+		}
+	}
+	return false
+}
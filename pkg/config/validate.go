@@ -0,0 +1,298 @@
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+
+	"github.com/user/jswitch/pkg/models"
+)
+
+// Problem is an inconsistency found by Validate.
+type Problem struct {
+	Message string
+	// Fix describes what Repair does about it.
+	Fix   string
+	apply func(c *Config)
+}
+
+// Validate checks the config for entries that cannot work: installations with missing
+// fields or directories, duplicate versions or paths, a current version that is not
+// installed, aliases pointing at nothing, and unknown or invalid settings.
+func (c *Config) Validate() []Problem {
+	var problems []Problem
+
+	// Builds of one version from different vendors or of different image types may coexist:
+	// a jswitch.lock selects among them with FindBuild.
+	seenBuild := make(map[string]bool)
+	seenPath := make(map[string]bool)
+	for _, inst := range c.Installations {
+		build := inst.Version + "\x00" + strings.ToLower(inst.Vendor) + "\x00" + ImageType(inst)
+		inst := inst
+		drop := func(c *Config) { c.RemoveInstallation(inst) }
+		switch {
+		case inst.Version == "" || inst.Path == "":
+			problems = append(problems, Problem{
+				Message: fmt.Sprintf("Installation %q at %q has no version or path.", inst.Version, inst.Path),
+				Fix:     "Remove the entry.",
+				apply:   drop,
+			})
+		case seenPath[inst.Path]:
+			problems = append(problems, Problem{
+				Message: fmt.Sprintf("%s is listed more than once.", inst.Path),
+				Fix:     "Keep the first entry.",
+				apply:   drop,
+			})
+		case seenBuild[build]:
+			problems = append(problems, Problem{
+				Message: fmt.Sprintf("%s %s (%s) is installed more than once; only the first can be selected.", inst.Vendor, inst.Version, ImageType(inst)),
+				Fix:     fmt.Sprintf("Remove the entry for %s.", inst.Path),
+				apply:   drop,
+			})
+		case !hasJava(inst.Path):
+			problems = append(problems, Problem{
+				Message: fmt.Sprintf("Java %s is configured at %s, but no java executable was found there.", inst.Version, inst.Path),
+				Fix:     "Remove the entry.",
+				apply:   drop,
+			})
+		}
+		seenBuild[build] = true
+		seenPath[inst.Path] = true
+	}
+
+	if c.CurrentVersion != "" && c.CurrentVersionPath(c.CurrentVersion) == "" {
+		p := Problem{
+			Message: fmt.Sprintf("Current version %s is not a known installation.", c.CurrentVersion),
+			Fix:     "Clear the selection.",
+			apply:   func(c *Config) { c.CurrentVersion = "" },
+		}
+		if inst, err := c.Resolve(DefaultAlias); err == nil {
+			p.Fix = fmt.Sprintf("Select the default alias (Java %s).", inst.Version)
+			p.apply = func(c *Config) { c.CurrentVersion = inst.Version }
+		}
+		problems = append(problems, p)
+	}
+
+	for _, name := range c.AliasNames() {
+		alias := c.Aliases[name]
+		if _, ok := c.findVersion(alias.Target); ok {
+			continue
+		}
+		name := name
+		problems = append(problems, Problem{
+			Message: fmt.Sprintf("Alias %s points at %s, which is not installed.", name, alias.Target),
+			Fix:     "Re-point it at a matching installation, or remove it.",
+			apply: func(c *Config) {
+				c.ReconcileAliases()
+				if _, ok := c.findVersion(c.Aliases[name].Target); !ok {
+					c.RemoveAlias(name)
+				}
+			},
+		})
+	}
+	for _, spec := range c.EnvSpecs() {
+		if c.envApplies(spec) {
+			continue
+		}
+		spec := spec
+		problems = append(problems, Problem{
+			Message: fmt.Sprintf("Environment variables are attached to %s, which matches no installation or alias.", spec),
+			Fix:     "Remove them.",
+			apply:   func(c *Config) { delete(c.Env, spec) },
+		})
+	}
+	for _, key := range c.SettingKeys() {
+		key := key
+		s, err := LookupSetting(key)
+		if err == nil {
+			_, err = s.Normalize(c.Settings[key])
+		}
+		if err != nil {
+			problems = append(problems, Problem{
+				Message: fmt.Sprintf("Setting %s: %v.", key, err),
+				Fix:     "Remove it.",
+				apply:   func(c *Config) { delete(c.Settings, key) },
+			})
+		}
+	}
+	return problems
+}
+
+// RemoveInstallation drops the first entry equal to inst.
+func (c *Config) RemoveInstallation(inst models.JavaInstallation) {
+	for i, existing := range c.Installations {
+		if existing == inst {
+			c.Installations = append(c.Installations[:i], c.Installations[i+1:]...)
+			return
+		}
+	}
+}
+
+func hasJava(dir string) bool {
+	exe := "java"
+	if runtime.GOOS == "windows" {
+		exe = "java.exe"
+	}
+	info, err := os.Stat(filepath.Join(dir, "bin", exe))
+	return err == nil && !info.IsDir()
+}
+
+// RepairReport describes what Repair recovered and changed.
+type RepairReport struct {
+	Path string
+	// Recovered explains how a damaged file was salvaged; empty if it decoded cleanly.
+	Recovered []string
+	// Fixed lists the problems that were repaired.
+	Fixed []Problem
+	// Config is the repaired config.
+	Config *Config
+}
+
+// Changed reports whether Repair had anything to do.
+func (r *RepairReport) Changed() bool {
+	return len(r.Recovered) > 0 || len(r.Fixed) > 0
+}
+
+// Repair loads the config, salvaging what it can from a damaged file (falling back to the
+// last backup), fixes every problem Validate reports and, unless dryRun is set, saves the
+// result. The damaged original is kept as <config>.corrupt by SaveConfig.
+func Repair(dryRun bool) (*RepairReport, error) {
+	path, err := getConfigPath()
+	if err != nil {
+		return nil, err
+	}
+	report := &RepairReport{Path: path}
+
+	lock, err := lockConfig()
+	if err != nil {
+		return nil, err
+	}
+	defer lock.Unlock()
+
+	cfg, err := recoverConfig(path, report)
+	if err != nil {
+		return nil, err
+	}
+	// Validate against what LoadConfig would see, so aliases and the current version may point
+	// at system installations; SaveConfig leaves the system entries out again.
+	cfg.layer(systemOrEmpty())
+
+	// Fixes can expose new problems (removing an installation orphans an alias), so repeat.
+	for pass := 0; pass < 5; pass++ {
+		problems := cfg.Validate()
+		if len(problems) == 0 {
+			break
+		}
+		for _, p := range problems {
+			p.apply(cfg)
+		}
+		report.Fixed = append(report.Fixed, problems...)
+	}
+
+	report.Config = cfg
+	if dryRun || !report.Changed() {
+		return report, nil
+	}
+	return report, SaveConfig(cfg)
+}
+
+// recoverConfig reads the config at path, trying progressively harder to make sense of it.
+func recoverConfig(path string, report *RepairReport) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return &Config{Installations: []models.JavaInstallation{}}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config file at %s: %w", path, err)
+	}
+
+	cfg, err := decode(data)
+	if err == nil {
+		return cfg, nil
+	}
+	if errors.Is(err, ErrNewerSchema) {
+		// Not damage: repairing would throw away whatever the newer version added.
+		return nil, fmt.Errorf("config file %s: %w", path, err)
+	}
+	report.Recovered = append(report.Recovered, fmt.Sprintf("%s could not be read: %v", path, err))
+
+	if backupData, berr := os.ReadFile(path + backupSuffix); berr == nil {
+		if cfg, berr := decode(backupData); berr == nil {
+			report.Recovered = append(report.Recovered, fmt.Sprintf("Restored the previous version from %s.", path+backupSuffix))
+			return cfg, nil
+		}
+	}
+
+	var doc map[string]any
+	if json.Unmarshal(data, &doc) == nil && doc != nil {
+		cfg, notes := salvage(doc)
+		report.Recovered = append(report.Recovered, notes...)
+		return cfg, nil
+	}
+
+	report.Recovered = append(report.Recovered, "Nothing could be salvaged; starting from an empty config. Run 'jswitch scan' to find installations again.")
+	return &Config{Installations: []models.JavaInstallation{}}, nil
+}
+
+// salvage decodes each field of a structurally damaged document on its own,
+// keeping whatever is well-formed.
+func salvage(doc map[string]any) (*Config, []string) {
+	cfg := &Config{Installations: []models.JavaInstallation{}}
+	var notes []string
+
+	field := func(key string, dst any) {
+		v, ok := doc[key]
+		if !ok {
+			return
+		}
+		raw, _ := json.Marshal(v)
+		if err := json.Unmarshal(raw, dst); err != nil {
+			notes = append(notes, fmt.Sprintf("Dropped %s: %v", key, err))
+		}
+	}
+	field("current_version", &cfg.CurrentVersion)
+	field("shell_setup_offered", &cfg.ShellSetupOffered)
+	field("settings", &cfg.Settings)
+	field("env", &cfg.Env)
+	field("projects", &cfg.Projects)
+	field("pinned", &cfg.Pinned)
+
+	if items, ok := doc["installations"].([]any); ok {
+		for i, item := range items {
+			raw, _ := json.Marshal(item)
+			var inst models.JavaInstallation
+			if err := json.Unmarshal(raw, &inst); err != nil {
+				notes = append(notes, fmt.Sprintf("Dropped installation #%d: %v", i+1, err))
+				continue
+			}
+			if inst.MajorVersion == 0 {
+				inst.MajorVersion = models.ParseMajorVersion(inst.Version)
+			}
+			cfg.Installations = append(cfg.Installations, inst)
+		}
+	} else if _, ok := doc["installations"]; ok {
+		notes = append(notes, "Dropped installations: not a list")
+	}
+
+	if aliases, ok := doc["aliases"].(map[string]any); ok {
+		for name, v := range aliases {
+			raw, _ := json.Marshal(v)
+			var alias Alias
+			if err := json.Unmarshal(raw, &alias); err != nil {
+				notes = append(notes, fmt.Sprintf("Dropped alias %s: %v", name, err))
+				continue
+			}
+			if cfg.Aliases == nil {
+				cfg.Aliases = make(map[string]Alias)
+			}
+			cfg.Aliases[name] = alias
+		}
+	}
+
+	notes = append(notes, fmt.Sprintf("Salvaged %d installation(s) and %d alias(es).", len(cfg.Installations), len(cfg.Aliases)))
+	return cfg, notes
+}
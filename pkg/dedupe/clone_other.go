@@ -0,0 +1,9 @@
+//go:build !linux
+
+package dedupe
+
+import "fmt"
+
+func clone(src, dst string) error {
+	return fmt.Errorf("reflinks are only supported on Linux")
+}
@@ -0,0 +1,196 @@
+// Package dedupe shares the storage of identical files across JDK installations, which have
+// most of their files in common between builds, and measures how much disk they use.
+package dedupe
+
+import (
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+// ErrUnsupported is returned where files cannot be linked safely.
+var ErrUnsupported = errors.New("dedupe is not supported on this platform")
+
+// Options control Dedupe.
+type Options struct {
+	// DryRun finds the duplicates and reports the savings without changing anything.
+	DryRun bool
+	// Reflink replaces duplicates with copy-on-write clones instead of hardlinks, so that the
+	// files stay independent. It needs a file system that supports them (Btrfs, XFS).
+	Reflink bool
+}
+
+// Report is the outcome of Dedupe.
+type Report struct {
+	// Files is the number of regular files examined.
+	Files int
+	// Replaced is the number of files replaced by a link, or that would be in a dry run.
+	Replaced int
+	// Saved is the number of bytes freed, or that would be in a dry run.
+	Saved int64
+}
+
+// file is a regular file found under the root.
+type file struct {
+	path  string
+	size  int64
+	mode  fs.FileMode
+	id    fileID
+	links uint64
+}
+
+// inode is a file's storage with the paths under the root that link to it.
+type inode struct {
+	file
+	paths []string
+}
+
+// group is the key under which files can be linked to each other.
+type group struct {
+	dev  uint64
+	size int64
+	mode fs.FileMode
+}
+
+// Dedupe replaces identical files under root by links to a single copy. Files are only
+// linked when their size, permissions and SHA-256 content hash match. Each file is replaced
+// by renaming a link over it, so it is never missing, even if Dedupe is interrupted.
+func Dedupe(root string, opts Options) (Report, error) {
+	var report Report
+	if !identitySupported {
+		return report, ErrUnsupported
+	}
+	files, err := walk(root)
+	if err != nil {
+		return report, err
+	}
+	report.Files = len(files)
+
+	groups := make(map[group]map[fileID]*inode)
+	for _, f := range files {
+		if f.size == 0 {
+			continue
+		}
+		key := group{f.id.dev, f.size, f.mode}
+		if groups[key] == nil {
+			groups[key] = make(map[fileID]*inode)
+		}
+		if in, ok := groups[key][f.id]; ok {
+			in.paths = append(in.paths, f.path)
+		} else {
+			groups[key][f.id] = &inode{file: f, paths: []string{f.path}}
+		}
+	}
+
+	keys := make([]group, 0, len(groups))
+	for key, inodes := range groups {
+		if len(inodes) > 1 {
+			keys = append(keys, key)
+		}
+	}
+	sort.Slice(keys, func(i, j int) bool { return keys[i].size > keys[j].size })
+
+	for _, key := range keys {
+		byHash := make(map[[sha256.Size]byte][]*inode)
+		for _, in := range groups[key] {
+			sum, err := hashFile(in.path)
+			if err != nil {
+				return report, err
+			}
+			byHash[sum] = append(byHash[sum], in)
+		}
+		for _, same := range byHash {
+			if len(same) < 2 {
+				continue
+			}
+			// Link to the copy that is already shared the most, so that fewer files change.
+			sort.Slice(same, func(i, j int) bool {
+				if len(same[i].paths) != len(same[j].paths) {
+					return len(same[i].paths) > len(same[j].paths)
+				}
+				return same[i].path < same[j].path
+			})
+			keep := same[0]
+			for _, dup := range same[1:] {
+				for _, path := range dup.paths {
+					if !opts.DryRun {
+						if err := replace(keep.path, path, opts.Reflink); err != nil {
+							return report, err
+						}
+					}
+					report.Replaced++
+				}
+				// The storage is only freed when no link to it is left outside the root.
+				if opts.Reflink || uint64(len(dup.paths)) >= dup.links {
+					report.Saved += dup.size
+				}
+			}
+		}
+	}
+	return report, nil
+}
+
+// walk returns the regular files under root. Symlinks are not followed.
+func walk(root string) ([]file, error) {
+	var files []file
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		id, links := identity(info)
+		files = append(files, file{path: path, size: info.Size(), mode: info.Mode().Perm(), id: id, links: links})
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("reading %s: %w", root, err)
+	}
+	return files, nil
+}
+
+func hashFile(path string) ([sha256.Size]byte, error) {
+	var sum [sha256.Size]byte
+	f, err := os.Open(path)
+	if err != nil {
+		return sum, err
+	}
+	defer f.Close()
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return sum, fmt.Errorf("reading %s: %w", path, err)
+	}
+	copy(sum[:], h.Sum(nil))
+	return sum, nil
+}
+
+// replace makes path a link to (or a clone of) keep, by creating it next to path and renaming
+// it over path.
+func replace(keep, path string, reflink bool) error {
+	tmp := path + ".jswitch-dedupe"
+	os.Remove(tmp)
+	var err error
+	if reflink {
+		err = clone(keep, tmp)
+	} else {
+		err = os.Link(keep, tmp)
+	}
+	if err == nil {
+		err = os.Rename(tmp, path)
+	}
+	if err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("linking %s to %s: %w", path, keep, err)
+	}
+	return nil
+}
@@ -0,0 +1,188 @@
+package dedupe
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// writeTree creates files under root from relative paths to contents, with mode 0644 unless
+// the path is listed in exec.
+func writeTree(t *testing.T, root string, files map[string]string, exec ...string) {
+	t.Helper()
+	for rel, content := range files {
+		path := filepath.Join(root, filepath.FromSlash(rel))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, rel := range exec {
+		if err := os.Chmod(filepath.Join(root, filepath.FromSlash(rel)), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// jdks creates three installations under a temporary root: the modules file is the same in
+// all of them, bin/java has the same content everywhere but other permissions in jdk-c, and
+// release has the same size but other content in each.
+func jdks(t *testing.T) (root string, modulesSize int64) {
+	t.Helper()
+	if !identitySupported {
+		t.Skip(ErrUnsupported)
+	}
+	root = t.TempDir()
+	modules := string(bytes.Repeat([]byte("module data "), 1000))
+	for _, name := range []string{"jdk-a", "jdk-b", "jdk-c"} {
+		files := map[string]string{
+			"lib/modules": modules,
+			"bin/java":    "#!/bin/sh\n",
+			"release":     "JAVA_VERSION=\"" + name + "\"",
+			"empty":       "",
+		}
+		if name == "jdk-c" {
+			writeTree(t, filepath.Join(root, name), files)
+		} else {
+			writeTree(t, filepath.Join(root, name), files, "bin/java")
+		}
+	}
+	return root, int64(len(modules))
+}
+
+func stat(t *testing.T, root, rel string) os.FileInfo {
+	t.Helper()
+	info, err := os.Stat(filepath.Join(root, filepath.FromSlash(rel)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return info
+}
+
+func TestDedupe(t *testing.T) {
+	root, modulesSize := jdks(t)
+
+	report, err := Dedupe(root, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Two of the three modules files and one of the two executable bin/java files.
+	if report.Files != 12 || report.Replaced != 3 {
+		t.Errorf("report = %+v, want 12 files and 3 replaced", report)
+	}
+	if want := 2*modulesSize + int64(len("#!/bin/sh\n")); report.Saved != want {
+		t.Errorf("saved %d bytes, want %d", report.Saved, want)
+	}
+
+	same := func(a, b string) bool { return os.SameFile(stat(t, root, a), stat(t, root, b)) }
+	if !same("jdk-a/lib/modules", "jdk-b/lib/modules") || !same("jdk-a/lib/modules", "jdk-c/lib/modules") {
+		t.Error("identical modules files do not share an inode")
+	}
+	if !same("jdk-a/bin/java", "jdk-b/bin/java") {
+		t.Error("identical executables do not share an inode")
+	}
+	if same("jdk-a/bin/java", "jdk-c/bin/java") {
+		t.Error("files with different permissions were linked")
+	}
+	if same("jdk-a/release", "jdk-b/release") {
+		t.Error("files with different content were linked")
+	}
+	if got := stat(t, root, "jdk-c/bin/java").Mode().Perm(); got != 0644 {
+		t.Errorf("jdk-c/bin/java has mode %v after dedupe", got)
+	}
+	if leftovers, _ := filepath.Glob(filepath.Join(root, "*", "*", "*.jswitch-dedupe")); len(leftovers) > 0 {
+		t.Errorf("temporary links left behind: %q", leftovers)
+	}
+
+	again, err := Dedupe(root, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if again.Replaced != 0 || again.Saved != 0 {
+		t.Errorf("second run = %+v, want nothing to do", again)
+	}
+}
+
+func TestDedupeDryRun(t *testing.T) {
+	root, _ := jdks(t)
+	// Back-date the files so that a rewrite would show in the modification times.
+	old := time.Now().Add(-time.Hour).Truncate(time.Second)
+	before := make(map[string]os.FileInfo)
+	files, err := walk(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, f := range files {
+		if err := os.Chtimes(f.path, old, old); err != nil {
+			t.Fatal(err)
+		}
+		info, err := os.Stat(f.path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		before[f.path] = info
+	}
+
+	report, err := Dedupe(root, Options{DryRun: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Replaced != 3 || report.Saved == 0 {
+		t.Errorf("dry run report = %+v, want the savings of a real run", report)
+	}
+	for path, was := range before {
+		info, err := os.Stat(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !os.SameFile(was, info) || !info.ModTime().Equal(was.ModTime()) {
+			t.Errorf("dry run changed %s", path)
+		}
+	}
+}
+
+func TestDiskUsage(t *testing.T) {
+	root, modulesSize := jdks(t)
+	dirs := []string{filepath.Join(root, "jdk-a"), filepath.Join(root, "jdk-b"), filepath.Join(root, "jdk-c"), filepath.Join(root, "missing")}
+
+	usage, total, err := DiskUsage(dirs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	size := stat(t, root, "jdk-a/lib/modules").Size() + stat(t, root, "jdk-a/bin/java").Size() + stat(t, root, "jdk-a/release").Size()
+	for _, u := range usage[:3] {
+		if u.Size != size || u.Shared != 0 || u.Unique() != size {
+			t.Errorf("before dedupe %s = %+v, want %d bytes, none shared", u.Path, u, size)
+		}
+	}
+	if total != 3*size {
+		t.Errorf("total before dedupe = %d, want %d", total, 3*size)
+	}
+
+	if _, err := Dedupe(root, Options{}); err != nil {
+		t.Fatal(err)
+	}
+	usage, total, err = DiskUsage(dirs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	java := int64(len("#!/bin/sh\n"))
+	want := []Usage{
+		{Path: dirs[0], Size: size, Shared: modulesSize + java},
+		{Path: dirs[1], Size: size, Shared: modulesSize + java},
+		{Path: dirs[2], Size: size, Shared: modulesSize},
+		{Path: dirs[3]},
+	}
+	for i := range want {
+		if usage[i] != want[i] {
+			t.Errorf("usage[%d] = %+v, want %+v (unique %d)", i, usage[i], want[i], want[i].Unique())
+		}
+	}
+	if want := 3*size - 2*modulesSize - java; total != want {
+		t.Errorf("total after dedupe = %d, want %d", total, want)
+	}
+}
@@ -0,0 +1,23 @@
+//go:build !windows
+
+package dedupe
+
+import (
+	"io/fs"
+	"syscall"
+)
+
+const identitySupported = true
+
+// fileID identifies a file's storage: paths with the same fileID are hardlinks.
+type fileID struct {
+	dev, ino uint64
+}
+
+func identity(info fs.FileInfo) (fileID, uint64) {
+	st, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return fileID{}, 1
+	}
+	return fileID{dev: uint64(st.Dev), ino: uint64(st.Ino)}, uint64(st.Nlink)
+}
@@ -0,0 +1,80 @@
+package dedupe
+
+import (
+	"fmt"
+	"os"
+)
+
+// Usage is the disk usage of one installation.
+type Usage struct {
+	Path string
+	// Size is the apparent size of the installation's files, counting a file linked more than
+	// once within it once.
+	Size int64
+	// Shared is the part of Size stored in files linked into other installations as well.
+	Shared int64
+}
+
+// Unique is the part of Size only this installation uses: what deleting it frees.
+func (u Usage) Unique() int64 {
+	return u.Size - u.Shared
+}
+
+// DiskUsage measures the installations in dirs. It also returns the size of all of them
+// together, counting every shared file once. Directories that do not exist are reported
+// with a zero size.
+func DiskUsage(dirs []string) ([]Usage, int64, error) {
+	// owners counts the installations each file belongs to.
+	owners := make(map[fileID]map[int]bool)
+	perDir := make([]map[fileID]int64, len(dirs))
+	var total int64
+	for i, dir := range dirs {
+		perDir[i] = make(map[fileID]int64)
+		if _, err := os.Stat(dir); os.IsNotExist(err) {
+			continue
+		}
+		files, err := walk(dir)
+		if err != nil {
+			return nil, 0, err
+		}
+		for n, f := range files {
+			id := f.id
+			if !identitySupported {
+				// Without file identities, every path is its own file.
+				id = fileID{dev: uint64(i), ino: uint64(n)}
+			}
+			if _, ok := owners[id]; !ok {
+				owners[id] = make(map[int]bool)
+				total += f.size
+			}
+			owners[id][i] = true
+			perDir[i][id] = f.size
+		}
+	}
+
+	usage := make([]Usage, len(dirs))
+	for i, dir := range dirs {
+		usage[i].Path = dir
+		for id, size := range perDir[i] {
+			usage[i].Size += size
+			if len(owners[id]) > 1 {
+				usage[i].Shared += size
+			}
+		}
+	}
+	return usage, total, nil
+}
+
+// FormatBytes formats a byte count for people, e.g. 312.4 MiB.
+func FormatBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
@@ -0,0 +1,606 @@
+package doctor
+
+import (
+	"bufio"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+
+	"github.com/user/jswitch/pkg/config"
+	"github.com/user/jswitch/pkg/models"
+	"github.com/user/jswitch/pkg/paths"
+	"github.com/user/jswitch/pkg/shell"
+	"github.com/user/jswitch/pkg/switcher"
+)
+
+// Severity describes how serious a finding is.
+type Severity int
+
+const (
+	SeverityOK Severity = iota
+	SeverityInfo
+	SeverityWarning
+	SeverityError
+)
+
+func (s Severity) String() string {
+	switch s {
+	case SeverityOK:
+		return "OK"
+	case SeverityInfo:
+		return "INFO"
+	case SeverityWarning:
+		return "WARN"
+	case SeverityError:
+		return "ERROR"
+	default:
+		return "UNKNOWN"
+	}
+}
+
+// Finding is the result of a single diagnostic check.
+type Finding struct {
+	// Check is a short identifier for the check that produced the finding (e.g. "path").
+	Check    string
+	Severity Severity
+	Message  string
+	// Fix is a human-readable remediation. Empty when nothing needs doing.
+	Fix string
+	// apply performs the remediation automatically. Nil when the fix is manual.
+	apply func() error
+}
+
+// CanFix reports whether the finding has a safe automatic fix.
+func (f Finding) CanFix() bool {
+	return f.apply != nil
+}
+
+// ApplyFix runs the automatic fix for the finding.
+func (f Finding) ApplyFix() error {
+	if f.apply == nil {
+		return fmt.Errorf("no automatic fix available for %s", f.Check)
+	}
+	return f.apply()
+}
+
+// Report is the collected output of a doctor run.
+type Report struct {
+	Findings []Finding
+}
+
+// HasErrors reports whether any finding is of error severity.
+func (r Report) HasErrors() bool {
+	for _, f := range r.Findings {
+		if f.Severity == SeverityError {
+			return true
+		}
+	}
+	return false
+}
+
+// Run executes every check against the current environment.
+func Run() Report {
+	var r Report
+
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		r.Findings = append(r.Findings, Finding{
+			Check:    "config",
+			Severity: SeverityError,
+			Message:  fmt.Sprintf("Config could not be loaded: %v", err),
+			Fix:      "Run 'jswitch config repair'.",
+			apply: func() error {
+				_, err := config.Repair(false)
+				return err
+			},
+		})
+		cfg = &config.Config{}
+	} else {
+		r.Findings = append(r.Findings, checkInstallations(cfg)...)
+		r.Findings = append(r.Findings, checkCurrentVersion(cfg))
+		r.Findings = append(r.Findings, checkAliases(cfg)...)
+	}
+
+	home, err := os.UserHomeDir()
+	if err != nil {
+		r.Findings = append(r.Findings, Finding{
+			Check:    "home",
+			Severity: SeverityError,
+			Message:  fmt.Sprintf("Could not determine home directory: %v", err),
+		})
+		return r
+	}
+	linkPath, err := paths.CurrentLink()
+	if err != nil {
+		r.Findings = append(r.Findings, Finding{
+			Check:    "home",
+			Severity: SeverityError,
+			Message:  fmt.Sprintf("Could not determine the jswitch data directory: %v", err),
+		})
+		return r
+	}
+	currentPath := cfg.CurrentVersionPath(cfg.CurrentVersion)
+
+	if runtime.GOOS != "windows" {
+		r.Findings = append(r.Findings, checkSymlink(linkPath, currentPath))
+	}
+	r.Findings = append(r.Findings, checkPath(home, linkPath, currentPath, cfg.SettingBool(config.SettingAutoSwitch))...)
+	r.Findings = append(r.Findings, checkJavaHome(linkPath, currentPath))
+	if runtime.GOOS != "windows" {
+		r.Findings = append(r.Findings, checkProfiles(home, linkPath)...)
+	}
+	if runtime.GOOS == "linux" {
+		r.Findings = append(r.Findings, checkAlternatives(linkPath)...)
+	}
+
+	return r
+}
+
+// checkInstallations reports config entries whose directory no longer exists.
+func checkInstallations(cfg *config.Config) []Finding {
+	var findings []Finding
+	var missing []models.JavaInstallation
+
+	for _, inst := range cfg.Installations {
+		if _, err := os.Stat(filepath.Join(inst.Path, "bin", javaExe())); err != nil {
+			missing = append(missing, inst)
+		}
+	}
+
+	if len(missing) == 0 {
+		return []Finding{{
+			Check:    "installations",
+			Severity: SeverityOK,
+			Message:  fmt.Sprintf("All %d configured installations exist.", len(cfg.Installations)),
+		}}
+	}
+
+	for _, inst := range missing {
+		inst := inst
+		findings = append(findings, Finding{
+			Check:    "installations",
+			Severity: SeverityError,
+			Message:  fmt.Sprintf("Java %s is configured at %s, but no java executable was found there.", inst.Version, inst.Path),
+			Fix:      "Remove the stale entry from the config (or run 'jswitch scan').",
+			apply: func() error {
+				return removeInstallation(inst.Path)
+			},
+		})
+	}
+	return findings
+}
+
+func removeInstallation(path string) error {
+	return config.Update(func(cfg *config.Config) error {
+		kept := cfg.Installations[:0]
+		for _, inst := range cfg.Installations {
+			if inst.Path != path {
+				kept = append(kept, inst)
+			}
+		}
+		cfg.Installations = kept
+		cfg.ReconcileAliases()
+		if cfg.CurrentVersionPath(cfg.CurrentVersion) == "" {
+			cfg.CurrentVersion = ""
+		}
+		return nil
+	})
+}
+
+// checkCurrentVersion verifies that the selected version is a known installation.
+func checkCurrentVersion(cfg *config.Config) Finding {
+	if cfg.CurrentVersion == "" {
+		f := Finding{
+			Check:    "current",
+			Severity: SeverityWarning,
+			Message:  "No Java version is selected.",
+			Fix:      "Run 'jswitch use <version>'.",
+		}
+		if inst, err := cfg.Resolve(config.DefaultAlias); err == nil {
+			f.Fix = fmt.Sprintf("Select the default alias (Java %s).", inst.Version)
+			f.apply = func() error {
+				return config.Update(func(cfg *config.Config) error {
+					if err := switcher.Switch(inst.Path); err != nil {
+						return err
+					}
+					cfg.CurrentVersion = inst.Version
+					return nil
+				})
+			}
+		}
+		return f
+	}
+	if cfg.CurrentVersionPath(cfg.CurrentVersion) == "" {
+		return Finding{
+			Check:    "current",
+			Severity: SeverityError,
+			Message:  fmt.Sprintf("Selected version %s is not a known installation.", cfg.CurrentVersion),
+			Fix:      "Clear the selection, then run 'jswitch use <version>'.",
+			apply: func() error {
+				return config.Update(func(cfg *config.Config) error {
+					cfg.CurrentVersion = ""
+					return nil
+				})
+			},
+		}
+	}
+	return Finding{
+		Check:    "current",
+		Severity: SeverityOK,
+		Message:  fmt.Sprintf("Java %s is selected.", cfg.CurrentVersion),
+	}
+}
+
+// checkAliases reports aliases whose target is no longer installed. Those with a newer build
+// of the same vendor and major version can be re-pointed at it; the others are left to the
+// user, since removing an alias is not a fix anyone asked for.
+func checkAliases(cfg *config.Config) []Finding {
+	probe := *cfg
+	probe.Aliases = maps.Clone(cfg.Aliases)
+	reconcile := func() error {
+		return config.Update(func(cfg *config.Config) error {
+			cfg.ReconcileAliases()
+			return nil
+		})
+	}
+
+	var findings []Finding
+	for _, name := range probe.ReconcileAliases() {
+		findings = append(findings, Finding{
+			Check:    "aliases",
+			Severity: SeverityWarning,
+			Message:  fmt.Sprintf("Alias %s points at Java %s, which is no longer installed.", name, cfg.Aliases[name].Target),
+			Fix:      fmt.Sprintf("Re-point it at Java %s, the newest build of the same vendor and major version.", probe.Aliases[name].Target),
+			apply:    reconcile,
+		})
+	}
+	for _, name := range probe.AliasNames() {
+		if _, err := probe.Resolve(name); err != nil {
+			findings = append(findings, Finding{
+				Check:    "aliases",
+				Severity: SeverityWarning,
+				Message:  err.Error() + ".",
+				Fix:      fmt.Sprintf("Remove it with 'jswitch alias rm %s' or re-point it with 'jswitch alias set %s <version>'.", name, name),
+			})
+		}
+	}
+	return findings
+}
+
+// checkSymlink verifies the current link points at the selected installation.
+func checkSymlink(linkPath, currentPath string) Finding {
+	var relink func() error
+	if currentPath != "" {
+		relink = func() error { return switcher.Switch(currentPath) }
+	}
+
+	target, err := os.Readlink(linkPath)
+	if err != nil {
+		if currentPath == "" {
+			return Finding{
+				Check:    "symlink",
+				Severity: SeverityInfo,
+				Message:  fmt.Sprintf("%s does not exist yet.", linkPath),
+			}
+		}
+		return Finding{
+			Check:    "symlink",
+			Severity: SeverityError,
+			Message:  fmt.Sprintf("%s is missing or is not a symlink.", linkPath),
+			Fix:      fmt.Sprintf("Recreate the symlink pointing at %s.", currentPath),
+			apply:    relink,
+		}
+	}
+
+	if _, err := os.Stat(linkPath); err != nil {
+		return Finding{
+			Check:    "symlink",
+			Severity: SeverityError,
+			Message:  fmt.Sprintf("%s points at %s, which does not exist.", linkPath, target),
+			Fix:      "Run 'jswitch use <version>' to point it at a valid installation.",
+			apply:    relink,
+		}
+	}
+
+	if currentPath != "" && !samePath(target, currentPath) {
+		return Finding{
+			Check:    "symlink",
+			Severity: SeverityWarning,
+			Message:  fmt.Sprintf("%s points at %s, but the selected version lives at %s.", linkPath, target, currentPath),
+			Fix:      fmt.Sprintf("Repoint the symlink at %s.", currentPath),
+			apply:    relink,
+		}
+	}
+
+	return Finding{
+		Check:    "symlink",
+		Severity: SeverityOK,
+		Message:  fmt.Sprintf("%s -> %s", linkPath, target),
+	}
+}
+
+// checkPath verifies the jswitch bin directory is on PATH and that no other java shadows it.
+func checkPath(home, linkPath, currentPath string, autoSwitch bool) []Finding {
+	binDir := filepath.Join(linkPath, "bin")
+	if runtime.GOOS == "windows" {
+		binDir = filepath.Join(currentPath, "bin")
+	}
+
+	dirs := filepath.SplitList(os.Getenv("PATH"))
+	binIndex := -1
+	for i, dir := range dirs {
+		if samePath(dir, binDir) {
+			binIndex = i
+			break
+		}
+	}
+
+	var findings []Finding
+	if binIndex < 0 && runtime.GOOS == "windows" {
+		findings = append(findings, Finding{
+			Check:    "path",
+			Severity: SeverityError,
+			Message:  fmt.Sprintf("%s is not on PATH.", binDir),
+			Fix:      "Run 'jswitch use <version>' and restart your terminal.",
+		})
+	} else if binIndex < 0 && profileConfigured(home) {
+		findings = append(findings, Finding{
+			Check:    "path",
+			Severity: SeverityWarning,
+			Message:  fmt.Sprintf("%s is not on PATH in this shell, but your profile is configured.", binDir),
+			Fix:      "Open a new shell.",
+		})
+	} else if binIndex < 0 {
+		findings = append(findings, Finding{
+			Check:    "path",
+			Severity: SeverityError,
+			Message:  fmt.Sprintf("%s is not on PATH.", binDir),
+			Fix:      "Run 'jswitch setup' to add it to your shell profile, then open a new shell.",
+			apply: func() error {
+				for _, sh := range shell.Detect(home) {
+					sh.AutoSwitch = autoSwitch
+					if _, err := sh.Install(linkPath); err != nil {
+						return err
+					}
+				}
+				return nil
+			},
+		})
+	} else {
+		findings = append(findings, Finding{
+			Check:    "path",
+			Severity: SeverityOK,
+			Message:  fmt.Sprintf("%s is on PATH.", binDir),
+		})
+	}
+
+	// Look for a java executable that would be picked up before ours.
+	limit := len(dirs)
+	if binIndex >= 0 {
+		limit = binIndex
+	}
+	for _, dir := range dirs[:limit] {
+		candidate := filepath.Join(dir, javaExe())
+		if info, err := os.Stat(candidate); err == nil && !info.IsDir() {
+			findings = append(findings, Finding{
+				Check:    "path",
+				Severity: SeverityError,
+				Message:  fmt.Sprintf("%s appears on PATH before %s and will shadow the selected JDK.", candidate, binDir),
+				Fix:      fmt.Sprintf("Move %s ahead of %s in PATH, or remove %s from PATH.", binDir, dir, dir),
+			})
+			break
+		}
+	}
+
+	return findings
+}
+
+// checkJavaHome verifies the JAVA_HOME exported in this shell matches the selection.
+func checkJavaHome(linkPath, currentPath string) Finding {
+	javaHome := os.Getenv("JAVA_HOME")
+	expected := linkPath
+	if runtime.GOOS == "windows" {
+		expected = currentPath
+	}
+
+	if javaHome == "" {
+		return Finding{
+			Check:    "java_home",
+			Severity: SeverityWarning,
+			Message:  "JAVA_HOME is not set in this shell.",
+			Fix:      fmt.Sprintf("Export JAVA_HOME=%s in your shell profile.", expected),
+		}
+	}
+	if expected != "" && !samePath(javaHome, expected) {
+		return Finding{
+			Check:    "java_home",
+			Severity: SeverityWarning,
+			Message:  fmt.Sprintf("JAVA_HOME is %s, expected %s.", javaHome, expected),
+			Fix:      "Remove the stale JAVA_HOME export and open a new shell.",
+		}
+	}
+	return Finding{
+		Check:    "java_home",
+		Severity: SeverityOK,
+		Message:  fmt.Sprintf("JAVA_HOME is %s.", javaHome),
+	}
+}
+
+// loginProfiles are read by POSIX login shells besides the profiles 'jswitch setup' manages.
+var loginProfiles = []string{".profile", ".bash_profile", ".zprofile", ".zshenv"}
+
+// profilePaths returns the profiles of the shells shell.Detect finds, then the login profiles.
+func profilePaths(home string) []string {
+	var paths []string
+	seen := make(map[string]bool)
+	add := func(path string) {
+		if !seen[path] {
+			seen[path] = true
+			paths = append(paths, path)
+		}
+	}
+	for _, sh := range shell.Detect(home) {
+		add(sh.Profile)
+	}
+	for _, name := range loginProfiles {
+		add(filepath.Join(home, name))
+	}
+	return paths
+}
+
+// checkProfiles looks for JAVA_HOME exports in shell profiles that point elsewhere,
+// outside the block 'jswitch setup' manages.
+func checkProfiles(home, linkPath string) []Finding {
+	var findings []Finding
+	for _, path := range profilePaths(home) {
+		f, err := os.Open(path)
+		if err != nil {
+			continue
+		}
+
+		sc := bufio.NewScanner(f)
+		lineNo := 0
+		managed := false
+		for sc.Scan() {
+			lineNo++
+			switch strings.TrimSpace(sc.Text()) {
+			case shell.BlockStart:
+				managed = true
+				continue
+			case shell.BlockEnd:
+				managed = false
+				continue
+			}
+			if managed {
+				continue
+			}
+			value, ok := parseJavaHomeExport(sc.Text())
+			if !ok {
+				continue
+			}
+			if samePath(expandHome(value, home), linkPath) {
+				continue
+			}
+			findings = append(findings, Finding{
+				Check:    "profile",
+				Severity: SeverityWarning,
+				Message:  fmt.Sprintf("%s:%d sets JAVA_HOME=%s, overriding jswitch.", path, lineNo, value),
+				Fix:      fmt.Sprintf("Remove that line or change it to export JAVA_HOME=%s.", linkPath),
+			})
+		}
+		f.Close()
+	}
+	return findings
+}
+
+// expandHome resolves a leading ~, $HOME or ${HOME} in a profile value.
+func expandHome(value, home string) string {
+	for _, prefix := range []string{"~", "$HOME", "${HOME}"} {
+		if value == prefix || strings.HasPrefix(value, prefix+"/") {
+			return home + strings.TrimPrefix(value, prefix)
+		}
+	}
+	return value
+}
+
+// parseJavaHomeExport extracts the value from lines setting JAVA_HOME: `export JAVA_HOME=/path`
+// in POSIX shells, `set -gx JAVA_HOME /path` in fish and `$env:JAVA_HOME = "/path"` in PowerShell.
+func parseJavaHomeExport(line string) (string, bool) {
+	line = strings.TrimSpace(line)
+	if strings.HasPrefix(line, "#") {
+		return "", false
+	}
+	var value string
+	if fields := strings.Fields(line); len(fields) > 0 && fields[0] == "set" {
+		i := 1
+		for i < len(fields) && strings.HasPrefix(fields[i], "-") {
+			i++
+		}
+		if i+1 >= len(fields) || fields[i] != "JAVA_HOME" {
+			return "", false
+		}
+		value = strings.Join(fields[i+1:], " ")
+	} else if rest, ok := cutPrefixFold(line, "$env:JAVA_HOME"); ok {
+		rest = strings.TrimSpace(rest)
+		if !strings.HasPrefix(rest, "=") {
+			return "", false
+		}
+		value = strings.TrimSpace(strings.TrimPrefix(rest, "="))
+	} else {
+		line = strings.TrimPrefix(line, "export ")
+		if !strings.HasPrefix(line, "JAVA_HOME=") {
+			return "", false
+		}
+		value = strings.TrimPrefix(line, "JAVA_HOME=")
+	}
+	value = strings.Trim(value, `"'`)
+	return value, true
+}
+
+// cutPrefixFold is strings.CutPrefix ignoring case, as PowerShell variable names do.
+func cutPrefixFold(s, prefix string) (string, bool) {
+	if len(s) < len(prefix) || !strings.EqualFold(s[:len(prefix)], prefix) {
+		return s, false
+	}
+	return s[len(prefix):], true
+}
+
+// checkAlternatives reports when /etc/alternatives/java serves a JDK outside jswitch
+// and /usr/bin would win over the jswitch bin directory.
+func checkAlternatives(linkPath string) []Finding {
+	const altPath = "/etc/alternatives/java"
+	target, err := filepath.EvalSymlinks(altPath)
+	if err != nil {
+		return nil
+	}
+
+	binDir := filepath.Join(linkPath, "bin")
+	dirs := filepath.SplitList(os.Getenv("PATH"))
+	for _, dir := range dirs {
+		if samePath(dir, binDir) {
+			return []Finding{{
+				Check:    "alternatives",
+				Severity: SeverityInfo,
+				Message:  fmt.Sprintf("%s -> %s is shadowed by jswitch on PATH.", altPath, target),
+			}}
+		}
+		if resolved, err := filepath.EvalSymlinks(filepath.Join(dir, "java")); err == nil && resolved == target {
+			return []Finding{{
+				Check:    "alternatives",
+				Severity: SeverityWarning,
+				Message:  fmt.Sprintf("%s/java resolves through %s to %s and takes precedence over jswitch.", dir, altPath, target),
+				Fix:      fmt.Sprintf("Put %s before %s in PATH.", binDir, dir),
+			}}
+		}
+	}
+	return nil
+}
+
+// profileConfigured reports whether any detected shell profile has the jswitch block.
+func profileConfigured(home string) bool {
+	for _, sh := range shell.Detect(home) {
+		if sh.IsConfigured() {
+			return true
+		}
+	}
+	return false
+}
+
+func javaExe() string {
+	if runtime.GOOS == "windows" {
+		return "java.exe"
+	}
+	return "java"
+}
+
+func samePath(a, b string) bool {
+	a = filepath.Clean(a)
+	b = filepath.Clean(b)
+	if runtime.GOOS == "windows" {
+		return strings.EqualFold(a, b)
+	}
+	return a == b
+}
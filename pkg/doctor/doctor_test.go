@@ -0,0 +1,148 @@
+package doctor
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/user/jswitch/pkg/config"
+	"github.com/user/jswitch/pkg/models"
+	"github.com/user/jswitch/pkg/paths"
+	"github.com/user/jswitch/pkg/shell"
+)
+
+func TestCheckProfilesSkipsManagedBlock(t *testing.T) {
+	home := t.TempDir()
+	link := filepath.Join(home, ".local", "share", "jswitch", "current")
+	profile := strings.Join([]string{
+		"export PATH=$HOME/bin:$PATH",
+		"export JAVA_HOME=/opt/jdk-11",
+		shell.BlockStart,
+		`export JAVA_HOME="${XDG_DATA_HOME:-$HOME/.local/share}/jswitch/current"`,
+		shell.BlockEnd,
+		"export JAVA_HOME=~/.local/share/jswitch/current",
+	}, "\n") + "\n"
+	if err := os.WriteFile(filepath.Join(home, ".bashrc"), []byte(profile), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	findings := checkProfiles(home, link)
+	if len(findings) != 1 {
+		t.Fatalf("got %d findings, want only the /opt/jdk-11 export: %+v", len(findings), findings)
+	}
+	if want := ".bashrc:2 sets JAVA_HOME=/opt/jdk-11"; !strings.Contains(findings[0].Message, want) {
+		t.Errorf("finding %q does not mention %q", findings[0].Message, want)
+	}
+}
+
+func TestCheckProfilesReadsEveryShell(t *testing.T) {
+	t.Setenv("SHELL", "")
+	home := t.TempDir()
+	link := filepath.Join(home, ".local", "share", "jswitch", "current")
+	profiles := map[string]string{
+		"fish": "set -gx PATH $HOME/bin $PATH\nset -gx JAVA_HOME /opt/jdk-11\nset -gx JAVA_HOME $HOME/.local/share/jswitch/current\n",
+		"pwsh": "$Env:JAVA_HOME = \"/opt/jdk-17\"\n# $env:JAVA_HOME = '/opt/jdk-8'\n",
+	}
+	for name, content := range profiles {
+		sh, err := shell.ForName(home, name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := os.MkdirAll(filepath.Dir(sh.Profile), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(sh.Profile, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var messages []string
+	for _, f := range checkProfiles(home, link) {
+		messages = append(messages, f.Message)
+	}
+	got := strings.Join(messages, "\n")
+	for _, want := range []string{"config.fish:2 sets JAVA_HOME=/opt/jdk-11", "Microsoft.PowerShell_profile.ps1:1 sets JAVA_HOME=/opt/jdk-17"} {
+		if !strings.Contains(got, want) {
+			t.Errorf("findings do not mention %q:\n%s", want, got)
+		}
+	}
+	if len(messages) != 2 {
+		t.Errorf("got %d findings, want 2:\n%s", len(messages), got)
+	}
+}
+
+func TestParseJavaHomeExport(t *testing.T) {
+	tests := []struct {
+		line string
+		want string
+		ok   bool
+	}{
+		{"export JAVA_HOME=/opt/jdk", "/opt/jdk", true},
+		{`JAVA_HOME="/opt/my jdk"`, "/opt/my jdk", true},
+		{"set -gx JAVA_HOME /opt/jdk", "/opt/jdk", true},
+		{"set --export --global JAVA_HOME '/opt/jdk'", "/opt/jdk", true},
+		{`$env:JAVA_HOME = "C:\\jdk"`, `C:\\jdk`, true},
+		{"$ENV:JAVA_HOME='/opt/jdk'", "/opt/jdk", true},
+		{"# export JAVA_HOME=/opt/jdk", "", false},
+		{"set -gx JAVA_OPTS -Xmx1g", "", false},
+		{"$env:JAVA_HOMEDIR = 'x'", "", false},
+		{"export PATH=$JAVA_HOME/bin:$PATH", "", false},
+	}
+	for _, tt := range tests {
+		got, ok := parseJavaHomeExport(tt.line)
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("parseJavaHomeExport(%q) = %q, %v, want %q, %v", tt.line, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestCheckAliasesNeverRemoves(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv(paths.EnvHome, filepath.Join(dir, "home"))
+	t.Setenv(paths.EnvSystemHome, filepath.Join(dir, "system"))
+	config.SetPath(filepath.Join(dir, "home", "config.json"))
+	t.Cleanup(func() { config.SetPath("") })
+	err := config.SaveConfig(&config.Config{
+		Installations: []models.JavaInstallation{{Version: "21.0.2", MajorVersion: 21, Vendor: "Temurin", Path: "/jdks/21.0.2"}},
+		Aliases: map[string]config.Alias{
+			"work":   {Target: "21.0.1", Vendor: "Temurin", MajorVersion: 21},
+			"legacy": {Target: "11.0.1", Vendor: "Temurin", MajorVersion: 11},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	findings := checkAliases(cfg)
+	if len(findings) != 2 {
+		t.Fatalf("got %d findings, want 2: %+v", len(findings), findings)
+	}
+	if !strings.Contains(findings[0].Message, "Alias work") || !findings[0].CanFix() {
+		t.Errorf("the re-pointable alias is not fixable: %+v", findings[0])
+	}
+	if !strings.Contains(findings[1].Message, "legacy") || findings[1].CanFix() {
+		t.Errorf("the dangling alias has an automatic fix: %+v", findings[1])
+	}
+	if cfg.Aliases["work"].Target != "21.0.1" {
+		t.Error("checkAliases changed the loaded config")
+	}
+
+	if err := findings[0].ApplyFix(); err != nil {
+		t.Fatal(err)
+	}
+	cfg, err = config.LoadConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := cfg.Aliases["work"].Target; got != "21.0.2" {
+		t.Errorf("work points at %s after the fix, want 21.0.2", got)
+	}
+	if _, ok := cfg.Aliases["legacy"]; !ok {
+		t.Error("the fix removed the dangling alias")
+	}
+}
@@ -0,0 +1,74 @@
+// Package export registers jswitch installations with build tools and IDEs.
+//
+// Every exporter edits an existing file in place: entries written by jswitch are
+// recognisable (see each exporter), are replaced wholesale on every export, and
+// can be removed again; everything else in the file is left untouched.
+package export
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/user/jswitch/pkg/fileutil"
+	"github.com/user/jswitch/pkg/models"
+)
+
+// backupSuffix matches the shell profile backups written by 'jswitch setup'.
+const backupSuffix = ".jswitch.bak"
+
+// Result describes an export to a single file.
+type Result struct {
+	Path    string
+	Entries int
+	Changed bool
+}
+
+// sorted returns installations ordered by major version, then version.
+func sorted(installations []models.JavaInstallation) []models.JavaInstallation {
+	out := append([]models.JavaInstallation(nil), installations...)
+	sort.SliceStable(out, func(i, j int) bool {
+		if out[i].MajorVersion != out[j].MajorVersion {
+			return out[i].MajorVersion < out[j].MajorVersion
+		}
+		return models.CompareVersions(out[i].Version, out[j].Version) < 0
+	})
+	return out
+}
+
+// rewrite applies merge to the file at path and writes the result if it changed,
+// keeping the previous contents as <path>.jswitch.bak. A missing file is treated as empty.
+func rewrite(path string, dryRun bool, merge func(data []byte) ([]byte, error)) (bool, []byte, error) {
+	original, err := os.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return false, nil, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+
+	updated, err := merge(original)
+	if err != nil {
+		return false, nil, fmt.Errorf("%s: %w", path, err)
+	}
+	if bytes.Equal(original, updated) {
+		return false, updated, nil
+	}
+	if dryRun {
+		return true, updated, nil
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return false, nil, fmt.Errorf("failed to create directory for %s: %w", path, err)
+	}
+	mode := os.FileMode(0644)
+	if info, err := os.Stat(path); err == nil {
+		mode = info.Mode().Perm()
+		if err := os.WriteFile(path+backupSuffix, original, mode); err != nil {
+			return false, nil, fmt.Errorf("failed to back up %s: %w", path, err)
+		}
+	}
+	if err := fileutil.WriteFile(path, updated, mode); err != nil {
+		return false, nil, fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	return true, updated, nil
+}
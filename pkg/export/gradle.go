@@ -0,0 +1,210 @@
+package export
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/user/jswitch/pkg/models"
+)
+
+const (
+	// GradlePathsProperty lists extra JDK locations for Gradle's toolchain detection.
+	GradlePathsProperty = "org.gradle.java.installations.paths"
+	// gradleMarker starts the comment above the property that records which of its
+	// paths jswitch added, so that paths added by hand survive an export.
+	gradleMarker = "# jswitch-managed:"
+)
+
+// GradleUserHome returns $GRADLE_USER_HOME, or ~/.gradle.
+func GradleUserHome(home string) string {
+	if dir := os.Getenv("GRADLE_USER_HOME"); dir != "" {
+		return dir
+	}
+	return filepath.Join(home, ".gradle")
+}
+
+// GradleProperties rewrites the jswitch paths of org.gradle.java.installations.paths in the
+// gradle.properties file at path. Passing no installations removes them again.
+// With dryRun set, nothing is written and the resulting document is returned.
+func GradleProperties(path string, installations []models.JavaInstallation, dryRun bool) (Result, []byte, error) {
+	result := Result{Path: path}
+	changed, doc, err := rewrite(path, dryRun, func(data []byte) ([]byte, error) {
+		merged, n := MergeGradleProperties(data, installations)
+		result.Entries = n
+		return merged, nil
+	})
+	result.Changed = changed
+	return result, doc, err
+}
+
+// MergeGradleProperties sets the jswitch paths of org.gradle.java.installations.paths in a
+// gradle.properties document to the installation paths. Paths added by hand and all other
+// lines are kept. Returns the new document and the number of paths jswitch contributed.
+func MergeGradleProperties(data []byte, installations []models.JavaInstallation) ([]byte, int) {
+	text := string(data)
+	eol := "\n"
+	if strings.Contains(text, "\r\n") {
+		eol = "\r\n"
+	}
+	lines := strings.Split(strings.ReplaceAll(text, "\r\n", "\n"), "\n")
+	if len(lines) > 0 && lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+
+	var managed, existing []string
+	var kept []string
+	at := -1
+	for i := 0; i < len(lines); i++ {
+		line := lines[i]
+		trimmed := strings.TrimLeft(line, " \t\f")
+		if strings.HasPrefix(trimmed, gradleMarker) {
+			managed = append(managed, splitPaths(strings.TrimPrefix(trimmed, gradleMarker))...)
+			continue
+		}
+		if strings.HasPrefix(trimmed, "#") || strings.HasPrefix(trimmed, "!") {
+			kept = append(kept, line)
+			continue
+		}
+		// A logical line continues while a physical line ends in an odd number of backslashes.
+		start, logical := i, trimmed
+		for continues(lines[i]) && i+1 < len(lines) {
+			logical = logical[:len(logical)-1] + strings.TrimLeft(lines[i+1], " \t\f")
+			i++
+		}
+		key, value, ok := cutProperty(logical)
+		if !ok || key != GradlePathsProperty {
+			kept = append(kept, lines[start:i+1]...)
+			continue
+		}
+		if at < 0 {
+			at = len(kept)
+		}
+		existing = append(existing, splitPaths(unescapeProperty(value))...)
+	}
+
+	// Paths jswitch added before are dropped; anything else in the property was added by hand.
+	wasManaged := make(map[string]bool)
+	for _, p := range managed {
+		wasManaged[filepath.Clean(p)] = true
+	}
+	var paths []string
+	seen := make(map[string]bool)
+	for _, p := range existing {
+		if !wasManaged[filepath.Clean(p)] && !seen[filepath.Clean(p)] {
+			paths = append(paths, p)
+			seen[filepath.Clean(p)] = true
+		}
+	}
+	var ours []string
+	for _, inst := range sorted(installations) {
+		if !seen[filepath.Clean(inst.Path)] {
+			ours = append(ours, inst.Path)
+			seen[filepath.Clean(inst.Path)] = true
+		}
+	}
+	paths = append(paths, ours...)
+
+	var block []string
+	if len(ours) > 0 {
+		block = append(block, gradleMarker+" "+strings.Join(ours, ","))
+	}
+	if len(paths) > 0 {
+		block = append(block, GradlePathsProperty+"="+escapeProperty(strings.Join(paths, ",")))
+	}
+	if at < 0 {
+		at = len(kept)
+	}
+	out := append(append(append([]string(nil), kept[:at]...), block...), kept[at:]...)
+	if len(out) == 0 {
+		return nil, len(ours)
+	}
+	return []byte(strings.Join(out, eol) + eol), len(ours)
+}
+
+// continues reports whether a physical properties line is continued on the next one.
+func continues(line string) bool {
+	n := 0
+	for i := len(line) - 1; i >= 0 && line[i] == '\\'; i-- {
+		n++
+	}
+	return n%2 == 1
+}
+
+// cutProperty splits a logical properties line into key and value.
+func cutProperty(line string) (key, value string, ok bool) {
+	if strings.TrimSpace(line) == "" {
+		return "", "", false
+	}
+	i := 0
+	for i < len(line) {
+		c := line[i]
+		if c == '\\' {
+			i += 2
+			continue
+		}
+		if c == '=' || c == ':' || c == ' ' || c == '\t' || c == '\f' {
+			break
+		}
+		i++
+	}
+	i = min(i, len(line))
+	key, rest := line[:i], strings.TrimLeft(line[i:], " \t\f")
+	if rest != "" && (rest[0] == '=' || rest[0] == ':') {
+		rest = strings.TrimLeft(rest[1:], " \t\f")
+	}
+	return unescapeProperty(key), rest, true
+}
+
+func unescapeProperty(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] != '\\' || i+1 == len(s) {
+			b.WriteByte(s[i])
+			continue
+		}
+		i++
+		switch s[i] {
+		case 't':
+			b.WriteByte('\t')
+		case 'n':
+			b.WriteByte('\n')
+		case 'r':
+			b.WriteByte('\r')
+		case 'f':
+			b.WriteByte('\f')
+		case 'u':
+			if i+4 < len(s) {
+				if r, err := strconv.ParseUint(s[i+1:i+5], 16, 16); err == nil {
+					b.WriteRune(rune(r))
+					i += 4
+					continue
+				}
+			}
+			b.WriteByte('u')
+		default:
+			b.WriteByte(s[i])
+		}
+	}
+	return b.String()
+}
+
+// escapeProperty escapes a value for a properties file; backslashes in Windows paths must be doubled.
+func escapeProperty(s string) string {
+	s = strings.ReplaceAll(s, `\`, `\\`)
+	if strings.HasPrefix(s, " ") {
+		s = `\` + s
+	}
+	return s
+}
+
+func splitPaths(s string) []string {
+	var out []string
+	for _, p := range strings.Split(s, ",") {
+		if p = strings.TrimSpace(p); p != "" {
+			out = append(out, p)
+		}
+	}
+	return out
+}
@@ -0,0 +1,331 @@
+package export
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+
+	"github.com/user/jswitch/pkg/models"
+)
+
+// IntelliJJDKTables returns the jdk.table.xml of every IntelliJ IDEA (Ultimate or Community)
+// configuration directory found under the JetBrains config root, newest release last.
+// The files need not exist yet.
+func IntelliJJDKTables(home string) ([]string, error) {
+	root, err := jetBrainsRoot(home)
+	if err != nil {
+		return nil, err
+	}
+	entries, err := os.ReadDir(root)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	var files []string
+	for _, e := range entries {
+		if e.IsDir() && (strings.HasPrefix(e.Name(), "IntelliJIdea") || strings.HasPrefix(e.Name(), "IdeaIC")) {
+			files = append(files, filepath.Join(root, e.Name(), "options", "jdk.table.xml"))
+		}
+	}
+	sort.Strings(files)
+	return files, nil
+}
+
+func jetBrainsRoot(home string) (string, error) {
+	switch runtime.GOOS {
+	case "windows":
+		appData := os.Getenv("APPDATA")
+		if appData == "" {
+			return "", fmt.Errorf("APPDATA is not set")
+		}
+		return filepath.Join(appData, "JetBrains"), nil
+	case "darwin":
+		return filepath.Join(home, "Library", "Application Support", "JetBrains"), nil
+	default:
+		if dir := os.Getenv("XDG_CONFIG_HOME"); dir != "" && filepath.IsAbs(dir) {
+			return filepath.Join(dir, "JetBrains"), nil
+		}
+		return filepath.Join(home, ".config", "JetBrains"), nil
+	}
+}
+
+// IntelliJJDKTable registers installations as JDKs in the jdk.table.xml at path. A JDK whose
+// home is already registered is left as it is. With remove set, the JDKs whose home is one of
+// the installations are unregistered instead. With dryRun set, nothing is written and the
+// resulting document is returned.
+func IntelliJJDKTable(path string, installations []models.JavaInstallation, remove, dryRun bool) (Result, []byte, error) {
+	home, _ := os.UserHomeDir()
+	result := Result{Path: path}
+	changed, doc, err := rewrite(path, dryRun, func(data []byte) ([]byte, error) {
+		merged, n, err := MergeJDKTable(data, installations, home, remove)
+		result.Entries = n
+		return merged, err
+	})
+	result.Changed = changed
+	return result, doc, err
+}
+
+// jdkTableEntry is the subset of a <jdk> element jswitch needs to recognise.
+type jdkTableEntry struct {
+	Name struct {
+		Value string `xml:"value,attr"`
+	} `xml:"name"`
+	HomePath struct {
+		Value string `xml:"value,attr"`
+	} `xml:"homePath"`
+}
+
+// MergeJDKTable adds a <jdk> for every installation whose home is not yet registered in a
+// jdk.table.xml document, or with remove set, drops those whose home is an installation.
+// Everything else is kept byte for byte. userHome expands IntelliJ's $USER_HOME$ macro.
+// Returns the new document and the number of JDKs added or removed.
+func MergeJDKTable(data []byte, installations []models.JavaInstallation, userHome string, remove bool) ([]byte, int, error) {
+	if len(bytes.TrimSpace(data)) == 0 {
+		if remove {
+			return data, 0, nil
+		}
+		data = []byte("<application>\n  <component name=\"ProjectJdkTable\">\n  </component>\n</application>\n")
+	}
+
+	type span struct{ start, end int }
+	var drop []span
+	registered := make(map[string]bool)
+	names := make(map[string]bool)
+	tableEnd, rootEnd := -1, -1
+	// selfClosing is set when the table is written as <component name="ProjectJdkTable" />.
+	selfClosing := false
+	tableOpened := -1
+	wanted := make(map[string]bool)
+	for _, inst := range installations {
+		wanted[ideaPath(inst.Path)] = true
+	}
+
+	dec := xml.NewDecoder(bytes.NewReader(data))
+	var stack []string
+	inTable := false
+	for {
+		offset := int(dec.InputOffset())
+		tok, err := dec.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, 0, fmt.Errorf("invalid jdk.table.xml: %w", err)
+		}
+		switch t := tok.(type) {
+		case xml.StartElement:
+			if inTable && len(stack) == 2 && t.Name.Local == "jdk" {
+				var jdk jdkTableEntry
+				if err := dec.DecodeElement(&jdk, &t); err != nil {
+					return nil, 0, fmt.Errorf("invalid jdk.table.xml: %w", err)
+				}
+				home := ideaPath(strings.ReplaceAll(jdk.HomePath.Value, "$USER_HOME$", userHome))
+				if remove && wanted[home] {
+					drop = append(drop, span{offset, int(dec.InputOffset())})
+					continue
+				}
+				registered[home] = true
+				names[jdk.Name.Value] = true
+				continue
+			}
+			if len(stack) == 1 && t.Name.Local == "component" && attr(t, "name") == "ProjectJdkTable" {
+				inTable = true
+				tableOpened = int(dec.InputOffset())
+			}
+			stack = append(stack, t.Name.Local)
+		case xml.EndElement:
+			stack = stack[:len(stack)-1]
+			switch {
+			case len(stack) == 1 && inTable:
+				inTable = false
+				if tableEnd < 0 {
+					tableEnd = offset
+					selfClosing = offset == tableOpened && bytes.HasSuffix(data[:offset], []byte("/>"))
+				}
+			case len(stack) == 0:
+				rootEnd = offset
+			}
+		}
+	}
+	if rootEnd < 0 {
+		return nil, 0, fmt.Errorf("invalid jdk.table.xml: no root element")
+	}
+
+	var entries bytes.Buffer
+	n := len(drop)
+	if !remove {
+		for _, inst := range sorted(installations) {
+			home := ideaPath(inst.Path)
+			if registered[home] {
+				continue
+			}
+			registered[home] = true
+			writeJDKTableEntry(&entries, inst, uniqueName(names, jdkName(inst)))
+			n++
+		}
+	}
+
+	// The block replaces data[insertAt:resume].
+	insertAt, resume, block := -1, -1, entries.String()
+	if block != "" {
+		switch {
+		case selfClosing:
+			// Open the element up: <component ... /> becomes <component ...> entries </component>.
+			insertAt = len(bytes.TrimRight(data[:tableEnd-2], " "))
+			resume = tableEnd
+			block = ">\n" + block + "  </component>"
+		case tableEnd >= 0:
+			insertAt = lineStart(data, tableEnd)
+		default:
+			insertAt = lineStart(data, rootEnd)
+			block = "  <component name=\"ProjectJdkTable\">\n" + block + "  </component>\n"
+		}
+		resume = max(resume, insertAt)
+	}
+
+	var out bytes.Buffer
+	pos := 0
+	for _, s := range drop {
+		start, end := max(lineStart(data, s.start), pos), lineEnd(data, s.end)
+		out.Write(data[pos:start])
+		pos = end
+	}
+	if insertAt >= 0 {
+		out.Write(data[pos:insertAt])
+		pos = resume
+		if b := out.Bytes(); !selfClosing && len(b) > 0 && b[len(b)-1] != '\n' {
+			out.WriteByte('\n')
+		}
+		out.WriteString(block)
+	}
+	out.Write(data[pos:])
+	return out.Bytes(), n, nil
+}
+
+func attr(e xml.StartElement, name string) string {
+	for _, a := range e.Attr {
+		if a.Name.Local == name {
+			return a.Value
+		}
+	}
+	return ""
+}
+
+// ideaPath normalises a JDK home the way IntelliJ writes it: cleaned, with forward slashes.
+func ideaPath(path string) string {
+	return filepath.ToSlash(filepath.Clean(path))
+}
+
+// jdkName names an installation the way IntelliJ's own JDK download does, e.g. openjdk-17.0.2.
+func jdkName(inst models.JavaInstallation) string {
+	vendor := strings.ToLower(strings.Join(strings.Fields(inst.Vendor), "-"))
+	if vendor == "" || vendor == "unknown" {
+		return inst.Version
+	}
+	return vendor + "-" + inst.Version
+}
+
+// uniqueName returns name, or name (2), name (3)... if it is taken, and reserves it.
+func uniqueName(taken map[string]bool, name string) string {
+	candidate := name
+	for i := 2; taken[candidate]; i++ {
+		candidate = fmt.Sprintf("%s (%d)", name, i)
+	}
+	taken[candidate] = true
+	return candidate
+}
+
+func writeJDKTableEntry(b *bytes.Buffer, inst models.JavaInstallation, name string) {
+	esc := func(s string) string {
+		var e bytes.Buffer
+		xml.EscapeText(&e, []byte(s))
+		return e.String()
+	}
+	home := ideaPath(inst.Path)
+	classes, sources := jdkRoots(inst.Path, home)
+	roots := func(tag string, urls []string) {
+		if len(urls) == 0 {
+			fmt.Fprintf(b, "        <%s>\n          <root type=\"composite\" />\n        </%s>\n", tag, tag)
+			return
+		}
+		fmt.Fprintf(b, "        <%s>\n          <root type=\"composite\">\n", tag)
+		for _, u := range urls {
+			fmt.Fprintf(b, "            <root url=\"%s\" type=\"simple\" />\n", esc(u))
+		}
+		fmt.Fprintf(b, "          </root>\n        </%s>\n", tag)
+	}
+
+	fmt.Fprintf(b, "    <jdk version=\"2\">\n")
+	fmt.Fprintf(b, "      <name value=\"%s\" />\n", esc(name))
+	fmt.Fprintf(b, "      <type value=\"JavaSDK\" />\n")
+	fmt.Fprintf(b, "      <version value=\"%s\" />\n", esc(fmt.Sprintf("java version \"%s\"", inst.Version)))
+	fmt.Fprintf(b, "      <homePath value=\"%s\" />\n", esc(home))
+	fmt.Fprintf(b, "      <roots>\n")
+	roots("annotationsPath", []string{"jar://$APPLICATION_HOME_DIR$/plugins/java/lib/resources/jdkAnnotations.jar!/"})
+	roots("classPath", classes)
+	roots("javadocPath", nil)
+	roots("sourcePath", sources)
+	fmt.Fprintf(b, "      </roots>\n")
+	fmt.Fprintf(b, "      <additional />\n")
+	fmt.Fprintf(b, "    </jdk>\n")
+}
+
+// jdkRoots lists the class and source roots IntelliJ expects for the JDK at dir: one jrt://
+// root per module for Java 9+ (from the release file), or the jars of jre/lib for Java 8.
+func jdkRoots(dir, home string) (classes, sources []string) {
+	if modules := releaseModules(dir); len(modules) > 0 {
+		hasSrc := exists(filepath.Join(dir, "lib", "src.zip"))
+		for _, m := range modules {
+			classes = append(classes, "jrt://"+home+"!/"+m)
+			if hasSrc {
+				sources = append(sources, "jar://"+home+"/lib/src.zip!/"+m)
+			}
+		}
+		return classes, sources
+	}
+
+	for _, sub := range []string{"jre/lib", "jre/lib/ext", "lib"} {
+		jars, _ := filepath.Glob(filepath.Join(dir, filepath.FromSlash(sub), "*.jar"))
+		sort.Strings(jars)
+		for _, jar := range jars {
+			classes = append(classes, "jar://"+filepath.ToSlash(jar)+"!/")
+		}
+		if len(classes) > 0 {
+			break
+		}
+	}
+	if exists(filepath.Join(dir, "src.zip")) {
+		sources = append(sources, "jar://"+home+"/src.zip!/")
+	}
+	return classes, sources
+}
+
+// releaseModules reads the MODULES line of a JDK's release file.
+func releaseModules(dir string) []string {
+	f, err := os.Open(filepath.Join(dir, "release"))
+	if err != nil {
+		return nil
+	}
+	defer f.Close()
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		if value, ok := strings.CutPrefix(sc.Text(), "MODULES="); ok {
+			return strings.Fields(strings.Trim(value, `"`))
+		}
+	}
+	return nil
+}
+
+func exists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
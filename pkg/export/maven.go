@@ -0,0 +1,192 @@
+package export
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+
+	"github.com/user/jswitch/pkg/models"
+)
+
+// mavenIDPrefix marks the toolchains jswitch writes: their <provides><id> starts with it.
+// Entries without it belong to the user and are never touched.
+const mavenIDPrefix = "jswitch-"
+
+// MavenToolchainsFile returns the user toolchains file, ~/.m2/toolchains.xml.
+func MavenToolchainsFile(home string) string {
+	return filepath.Join(home, ".m2", "toolchains.xml")
+}
+
+// MavenToolchains rewrites the jswitch entries of the toolchains file at path to
+// match installations. Passing no installations removes every jswitch entry.
+// With dryRun set, nothing is written and the resulting document is returned.
+func MavenToolchains(path string, installations []models.JavaInstallation, dryRun bool) (Result, []byte, error) {
+	result := Result{Path: path}
+	changed, doc, err := rewrite(path, dryRun, func(data []byte) ([]byte, error) {
+		merged, n, err := MergeMavenToolchains(data, installations)
+		result.Entries = n
+		return merged, err
+	})
+	result.Changed = changed
+	return result, doc, err
+}
+
+// mavenToolchain is the subset of a <toolchain> element jswitch needs to recognise.
+type mavenToolchain struct {
+	Type     string `xml:"type"`
+	Provides struct {
+		ID string `xml:"id"`
+	} `xml:"provides"`
+	Configuration struct {
+		JDKHome string `xml:"jdkHome"`
+	} `xml:"configuration"`
+}
+
+// MergeMavenToolchains replaces the jswitch toolchains in a toolchains.xml document with one
+// JDK toolchain per installation. Everything else (other toolchains, comments, formatting)
+// is kept byte for byte. Installations already listed by a user entry with the same jdkHome
+// are skipped. Returns the new document and the number of toolchains jswitch wrote.
+func MergeMavenToolchains(data []byte, installations []models.JavaInstallation) ([]byte, int, error) {
+	if len(bytes.TrimSpace(data)) == 0 {
+		data = []byte(xml.Header + "<toolchains>\n</toolchains>\n")
+	}
+
+	type span struct{ start, end int }
+	var drop []span
+	userHomes := make(map[string]bool)
+	rootEnd := -1
+
+	dec := xml.NewDecoder(bytes.NewReader(data))
+	depth := 0
+	for {
+		offset := int(dec.InputOffset())
+		tok, err := dec.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, 0, fmt.Errorf("invalid toolchains file: %w", err)
+		}
+		switch t := tok.(type) {
+		case xml.StartElement:
+			if depth == 0 && t.Name.Local != "toolchains" {
+				return nil, 0, fmt.Errorf("invalid toolchains file: root element is <%s>, expected <toolchains>", t.Name.Local)
+			}
+			if depth == 1 && t.Name.Local == "toolchain" {
+				var tc mavenToolchain
+				if err := dec.DecodeElement(&tc, &t); err != nil {
+					return nil, 0, fmt.Errorf("invalid toolchains file: %w", err)
+				}
+				if strings.HasPrefix(strings.TrimSpace(tc.Provides.ID), mavenIDPrefix) {
+					drop = append(drop, span{offset, int(dec.InputOffset())})
+				} else if home := strings.TrimSpace(tc.Configuration.JDKHome); home != "" {
+					userHomes[filepath.Clean(home)] = true
+				}
+				continue
+			}
+			depth++
+		case xml.EndElement:
+			depth--
+			if depth == 0 {
+				rootEnd = offset
+			}
+		}
+	}
+	if rootEnd < 0 {
+		return nil, 0, fmt.Errorf("invalid toolchains file: no <toolchains> element")
+	}
+
+	var entries bytes.Buffer
+	n := 0
+	for _, inst := range sorted(installations) {
+		if userHomes[filepath.Clean(inst.Path)] {
+			continue
+		}
+		writeMavenToolchain(&entries, inst)
+		n++
+	}
+
+	// Splice: keep everything outside the dropped entries, insert ours before </toolchains>.
+	insertAt := lineStart(data, rootEnd)
+	var out bytes.Buffer
+	pos := 0
+	for _, s := range drop {
+		start, end := max(lineStart(data, s.start), pos), min(lineEnd(data, s.end), insertAt)
+		out.Write(data[pos:start])
+		pos = end
+	}
+	if pos < insertAt {
+		out.Write(data[pos:insertAt])
+		pos = insertAt
+	}
+	if entries.Len() > 0 {
+		if b := out.Bytes(); len(b) > 0 && b[len(b)-1] != '\n' {
+			out.WriteByte('\n')
+		}
+		out.Write(entries.Bytes())
+	}
+	out.Write(data[pos:])
+	return out.Bytes(), n, nil
+}
+
+// mavenToolchainID identifies an installation's toolchain, e.g. jswitch-eclipse-adoptium-17.0.2.
+func mavenToolchainID(inst models.JavaInstallation) string {
+	vendor := strings.ToLower(strings.Join(strings.FieldsFunc(inst.Vendor, func(r rune) bool {
+		return !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9')
+	}), "-"))
+	if vendor == "" {
+		return mavenIDPrefix + inst.Version
+	}
+	return mavenIDPrefix + vendor + "-" + inst.Version
+}
+
+func writeMavenToolchain(b *bytes.Buffer, inst models.JavaInstallation) {
+	esc := func(s string) string {
+		var e bytes.Buffer
+		xml.EscapeText(&e, []byte(s))
+		return e.String()
+	}
+	fmt.Fprintf(b, "  <toolchain>\n")
+	fmt.Fprintf(b, "    <type>jdk</type>\n")
+	fmt.Fprintf(b, "    <provides>\n")
+	fmt.Fprintf(b, "      <id>%s</id>\n", esc(mavenToolchainID(inst)))
+	fmt.Fprintf(b, "      <version>%s</version>\n", esc(inst.Version))
+	if inst.Vendor != "" {
+		fmt.Fprintf(b, "      <vendor>%s</vendor>\n", esc(inst.Vendor))
+	}
+	fmt.Fprintf(b, "    </provides>\n")
+	fmt.Fprintf(b, "    <configuration>\n")
+	fmt.Fprintf(b, "      <jdkHome>%s</jdkHome>\n", esc(inst.Path))
+	fmt.Fprintf(b, "    </configuration>\n")
+	fmt.Fprintf(b, "  </toolchain>\n")
+}
+
+// lineStart moves i back over indentation to the start of its line, if only blanks precede it.
+func lineStart(data []byte, i int) int {
+	j := i
+	for j > 0 && (data[j-1] == ' ' || data[j-1] == '\t') {
+		j--
+	}
+	if j == 0 || data[j-1] == '\n' {
+		return j
+	}
+	return i
+}
+
+// lineEnd moves i forward over trailing blanks and one line break, if nothing else follows on the line.
+func lineEnd(data []byte, i int) int {
+	j := i
+	for j < len(data) && (data[j] == ' ' || data[j] == '\t' || data[j] == '\r') {
+		j++
+	}
+	if j == len(data) {
+		return j
+	}
+	if data[j] == '\n' {
+		return j + 1
+	}
+	return i
+}
@@ -0,0 +1,456 @@
+package export
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+
+	"github.com/user/jswitch/pkg/models"
+)
+
+// VSCodeRuntimesSetting is the Java extension's list of JDKs for project execution environments.
+const VSCodeRuntimesSetting = "java.configuration.runtimes"
+
+// VSCodeSettingsFiles returns the user settings.json of every installed VS Code flavour
+// (stable, Insiders, VSCodium), or of stable VS Code if none is installed yet.
+func VSCodeSettingsFiles(home string) ([]string, error) {
+	var root string
+	switch runtime.GOOS {
+	case "windows":
+		root = os.Getenv("APPDATA")
+		if root == "" {
+			return nil, fmt.Errorf("APPDATA is not set")
+		}
+	case "darwin":
+		root = filepath.Join(home, "Library", "Application Support")
+	default:
+		root = filepath.Join(home, ".config")
+		if dir := os.Getenv("XDG_CONFIG_HOME"); dir != "" && filepath.IsAbs(dir) {
+			root = dir
+		}
+	}
+
+	var files []string
+	for _, flavour := range []string{"Code", "Code - Insiders", "VSCodium"} {
+		if dir := filepath.Join(root, flavour, "User"); exists(dir) {
+			files = append(files, filepath.Join(dir, "settings.json"))
+		}
+	}
+	if len(files) == 0 {
+		files = append(files, filepath.Join(root, "Code", "User", "settings.json"))
+	}
+	return files, nil
+}
+
+// VSCodeSettings adds installations to java.configuration.runtimes in the settings.json at
+// path, one per major version. Runtimes whose path is already listed are left as they are.
+// With remove set, the runtimes whose path is one of the installations are dropped instead.
+// With dryRun set, nothing is written and the resulting document is returned.
+func VSCodeSettings(path string, installations []models.JavaInstallation, remove, dryRun bool) (Result, []byte, error) {
+	result := Result{Path: path}
+	changed, doc, err := rewrite(path, dryRun, func(data []byte) ([]byte, error) {
+		merged, n, err := MergeVSCodeSettings(data, installations, remove)
+		result.Entries = n
+		return merged, err
+	})
+	result.Changed = changed
+	return result, doc, err
+}
+
+// vscodeRuntime is an entry of java.configuration.runtimes.
+type vscodeRuntime struct {
+	Name    string `json:"name"`
+	Path    string `json:"path"`
+	Default bool   `json:"default,omitempty"`
+}
+
+// MergeVSCodeSettings updates java.configuration.runtimes in a settings.json document (JSON
+// with comments). Only the value of that setting is rewritten, and the setting is deleted
+// again when nothing is left in it; the rest of the file, comments included, is kept byte for
+// byte. Returns the new document and the number of runtimes added or removed.
+func MergeVSCodeSettings(data []byte, installations []models.JavaInstallation, remove bool) ([]byte, int, error) {
+	if len(bytes.TrimSpace(data)) == 0 {
+		if remove {
+			return data, 0, nil
+		}
+		data = []byte("{\n}\n")
+	}
+	obj, err := scanObject(data)
+	if err != nil {
+		return nil, 0, fmt.Errorf("invalid settings.json: %w", err)
+	}
+	member := obj.member(VSCodeRuntimesSetting)
+
+	// Existing entries are kept, compacted and without any comments inside the list.
+	var existing []json.RawMessage
+	if member != nil {
+		clean := stripJSONC(data[member.valueStart:member.valueEnd])
+		if err := json.Unmarshal(clean, &existing); err != nil {
+			return nil, 0, fmt.Errorf("invalid settings.json: %s is not a list: %w", VSCodeRuntimesSetting, err)
+		}
+	}
+
+	ours := make(map[string]bool)
+	for _, inst := range installations {
+		ours[filepath.Clean(inst.Path)] = true
+	}
+	var entries []json.RawMessage
+	listed := make(map[string]bool)
+	names := make(map[string]bool)
+	n := 0
+	for _, raw := range existing {
+		var rt vscodeRuntime
+		json.Unmarshal(raw, &rt)
+		if remove && ours[filepath.Clean(rt.Path)] {
+			n++
+			continue
+		}
+		entries = append(entries, raw)
+		listed[filepath.Clean(rt.Path)] = true
+		names[rt.Name] = true
+	}
+	if !remove {
+		// The extension accepts one runtime per execution environment: newest patch wins.
+		list := sorted(installations)
+		for i := len(list) - 1; i >= 0; i-- {
+			inst := list[i]
+			name := executionEnvironment(inst.MajorVersion)
+			if name == "" || names[name] || listed[filepath.Clean(inst.Path)] {
+				continue
+			}
+			raw, _ := json.Marshal(vscodeRuntime{Name: name, Path: inst.Path})
+			entries = append(entries, raw)
+			names[name] = true
+			n++
+		}
+	}
+	if n == 0 {
+		return data, 0, nil
+	}
+
+	indent := obj.indent(data)
+	if len(entries) == 0 {
+		return obj.deleteMember(data, member), n, nil
+	}
+	value := formatRuntimes(entries, indent)
+	if member != nil {
+		return splice(data, member.valueStart, member.valueEnd, value), n, nil
+	}
+	return obj.appendMember(data, indent, VSCodeRuntimesSetting, value), n, nil
+}
+
+// executionEnvironment names a major version the way the Java extension does, e.g. JavaSE-17.
+func executionEnvironment(major int) string {
+	switch {
+	case major <= 0:
+		return ""
+	case major <= 5:
+		return fmt.Sprintf("J2SE-1.%d", major)
+	case major <= 8:
+		return fmt.Sprintf("JavaSE-1.%d", major)
+	default:
+		return fmt.Sprintf("JavaSE-%d", major)
+	}
+}
+
+func formatRuntimes(entries []json.RawMessage, indent string) []byte {
+	var b bytes.Buffer
+	b.WriteString("[\n")
+	for i, raw := range entries {
+		var compact bytes.Buffer
+		if json.Compact(&compact, raw) != nil {
+			compact.Write(raw)
+		}
+		b.WriteString(indent + indent)
+		b.Write(compact.Bytes())
+		if i < len(entries)-1 {
+			b.WriteByte(',')
+		}
+		b.WriteByte('\n')
+	}
+	b.WriteString(indent + "]")
+	return b.Bytes()
+}
+
+func splice(data []byte, start, end int, insert []byte) []byte {
+	out := make([]byte, 0, len(data)+len(insert))
+	out = append(out, data[:start]...)
+	out = append(out, insert...)
+	return append(out, data[end:]...)
+}
+
+// jsoncObject locates the members of the top-level object of a JSON-with-comments document.
+type jsoncObject struct {
+	open, close int
+	members     []jsoncMember
+}
+
+type jsoncMember struct {
+	key                  string
+	keyStart             int
+	valueStart, valueEnd int
+	// comma is the offset of the comma following the value, or -1.
+	comma int
+}
+
+func (o *jsoncObject) member(key string) *jsoncMember {
+	for i := range o.members {
+		if o.members[i].key == key {
+			return &o.members[i]
+		}
+	}
+	return nil
+}
+
+// indent returns the indentation of the first member, or four spaces like VS Code.
+func (o *jsoncObject) indent(data []byte) string {
+	if len(o.members) > 0 {
+		start := lineStart(data, o.members[0].keyStart)
+		if start != o.members[0].keyStart {
+			return string(data[start:o.members[0].keyStart])
+		}
+	}
+	return "    "
+}
+
+func (o *jsoncObject) appendMember(data []byte, indent, key string, value []byte) []byte {
+	k, _ := json.Marshal(key)
+	member := append([]byte(indent), k...)
+	member = append(append(member, ": "...), value...)
+	if len(o.members) == 0 {
+		at := lineStart(data, o.close)
+		insert := append(member, '\n')
+		if at == o.close && (at == 0 || data[at-1] != '\n') {
+			insert = append([]byte("\n"), insert...)
+		}
+		return splice(data, at, at, insert)
+	}
+	last := o.members[len(o.members)-1]
+	if last.comma >= 0 {
+		// settings.json allows a trailing comma: the new member goes after it.
+		at := restOfLine(data, last.comma+1)
+		return splice(data, at, at, append([]byte("\n"), member...))
+	}
+	at := restOfLine(data, last.valueEnd)
+	out := splice(data, at, at, append([]byte("\n"), member...))
+	return splice(out, last.valueEnd, last.valueEnd, []byte(","))
+}
+
+// restOfLine skips a comment following i on the same line, so that it stays with the value before it.
+func restOfLine(data []byte, i int) int {
+	nl := bytes.IndexByte(data[i:], '\n')
+	if nl < 0 {
+		return i
+	}
+	nl += i
+	s := &jsoncScanner{data: data[:nl], pos: i}
+	s.skip()
+	if s.pos == nl {
+		if nl > i && data[nl-1] == '\r' {
+			return nl - 1
+		}
+		return nl
+	}
+	return i
+}
+
+func (o *jsoncObject) deleteMember(data []byte, m *jsoncMember) []byte {
+	if m == nil {
+		return data
+	}
+	if m.comma >= 0 {
+		return splice(data, lineStart(data, m.keyStart), lineEnd(data, m.comma+1), nil)
+	}
+	for i := range o.members {
+		if &o.members[i] == m && i > 0 {
+			if prev := o.members[i-1]; prev.comma >= 0 {
+				// The member was last: its lines go, then the comma before it.
+				out := splice(data, lineStart(data, m.keyStart), lineEnd(data, m.valueEnd), nil)
+				return splice(out, prev.comma, prev.comma+1, nil)
+			}
+		}
+	}
+	return splice(data, lineStart(data, m.keyStart), lineEnd(data, m.valueEnd), nil)
+}
+
+// scanObject parses the top level of a settings document.
+func scanObject(data []byte) (*jsoncObject, error) {
+	s := &jsoncScanner{data: data}
+	s.skip()
+	if !s.consume('{') {
+		return nil, fmt.Errorf("expected an object")
+	}
+	obj := &jsoncObject{open: s.pos - 1, close: -1}
+	for {
+		s.skip()
+		if s.consume('}') {
+			obj.close = s.pos - 1
+			return obj, nil
+		}
+		m := jsoncMember{keyStart: s.pos, comma: -1}
+		key, err := s.str()
+		if err != nil {
+			return nil, err
+		}
+		m.key = key
+		s.skip()
+		if !s.consume(':') {
+			return nil, fmt.Errorf("expected ':' after %q at offset %d", key, s.pos)
+		}
+		s.skip()
+		m.valueStart = s.pos
+		if err := s.value(); err != nil {
+			return nil, err
+		}
+		m.valueEnd = s.pos
+		s.skip()
+		if s.consume(',') {
+			m.comma = s.pos - 1
+		} else if s.peek() != '}' {
+			return nil, fmt.Errorf("expected ',' or '}' at offset %d", s.pos)
+		}
+		obj.members = append(obj.members, m)
+	}
+}
+
+type jsoncScanner struct {
+	data []byte
+	pos  int
+}
+
+func (s *jsoncScanner) peek() byte {
+	if s.pos < len(s.data) {
+		return s.data[s.pos]
+	}
+	return 0
+}
+
+func (s *jsoncScanner) consume(c byte) bool {
+	if s.peek() == c {
+		s.pos++
+		return true
+	}
+	return false
+}
+
+// skip moves past whitespace and comments.
+func (s *jsoncScanner) skip() {
+	for s.pos < len(s.data) {
+		rest := s.data[s.pos:]
+		switch {
+		case rest[0] == ' ' || rest[0] == '\t' || rest[0] == '\r' || rest[0] == '\n':
+			s.pos++
+		case bytes.HasPrefix(rest, []byte("//")):
+			if i := bytes.IndexByte(rest, '\n'); i >= 0 {
+				s.pos += i + 1
+			} else {
+				s.pos = len(s.data)
+			}
+		case bytes.HasPrefix(rest, []byte("/*")):
+			if i := bytes.Index(rest[2:], []byte("*/")); i >= 0 {
+				s.pos += i + 4
+			} else {
+				s.pos = len(s.data)
+			}
+		default:
+			return
+		}
+	}
+}
+
+func (s *jsoncScanner) str() (string, error) {
+	start := s.pos
+	if !s.consume('"') {
+		return "", fmt.Errorf("expected a string at offset %d", s.pos)
+	}
+	for s.pos < len(s.data) {
+		switch s.data[s.pos] {
+		case '\\':
+			s.pos += 2
+		case '"':
+			s.pos++
+			var v string
+			if err := json.Unmarshal(s.data[start:s.pos], &v); err != nil {
+				return "", err
+			}
+			return v, nil
+		default:
+			s.pos++
+		}
+	}
+	return "", fmt.Errorf("unterminated string at offset %d", start)
+}
+
+// value moves past one value of any kind.
+func (s *jsoncScanner) value() error {
+	switch s.peek() {
+	case '"':
+		_, err := s.str()
+		return err
+	case '{', '[':
+		depth := 0
+		for s.pos < len(s.data) {
+			s.skip()
+			switch s.peek() {
+			case '"':
+				if _, err := s.str(); err != nil {
+					return err
+				}
+				continue
+			case '{', '[':
+				depth++
+			case '}', ']':
+				depth--
+			}
+			s.pos++
+			if depth == 0 {
+				return nil
+			}
+		}
+		return fmt.Errorf("unterminated value")
+	default:
+		start := s.pos
+		for s.pos < len(s.data) && !strings.ContainsRune(",}] \t\r\n/", rune(s.data[s.pos])) {
+			s.pos++
+		}
+		if s.pos == start {
+			return fmt.Errorf("expected a value at offset %d", s.pos)
+		}
+		return nil
+	}
+}
+
+// stripJSONC turns a JSON-with-comments value into plain JSON: comments and trailing commas go.
+func stripJSONC(data []byte) []byte {
+	var out []byte
+	s := &jsoncScanner{data: data}
+	for s.pos < len(data) {
+		start := s.pos
+		s.skip()
+		if s.pos > start {
+			out = append(out, ' ')
+			continue
+		}
+		switch c := s.peek(); c {
+		case '"':
+			s.str()
+			out = append(out, data[start:s.pos]...)
+		case '}', ']':
+			// Drop a trailing comma before the closing bracket.
+			trimmed := bytes.TrimRight(out, " ")
+			if len(trimmed) > 0 && trimmed[len(trimmed)-1] == ',' {
+				out = trimmed[:len(trimmed)-1]
+			}
+			out = append(out, c)
+			s.pos++
+		default:
+			out = append(out, c)
+			s.pos++
+		}
+	}
+	return out
+}
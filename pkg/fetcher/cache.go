@@ -0,0 +1,59 @@
+package fetcher
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/user/jswitch/pkg/fileutil"
+	"github.com/user/jswitch/pkg/paths"
+)
+
+const (
+	availableReleasesFile = "available_releases.json"
+)
+
+// getCachePath returns the path of a file in the cache directory.
+func getCachePath(name string) (string, error) {
+	dir, err := paths.CacheDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, name), nil
+}
+
+// CachedAvailableReleases returns the listing saved by the last successful ListAvailableReleases
+// call, without touching the network. Used where latency matters, such as shell completion.
+func CachedAvailableReleases() (*AvailableReleases, error) {
+	path, err := getCachePath(availableReleasesFile)
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var available AvailableReleases
+	if err := json.Unmarshal(data, &available); err != nil {
+		return nil, fmt.Errorf("failed to parse cached releases: %w", err)
+	}
+	return &available, nil
+}
+
+// saveAvailableReleases caches a listing for CachedAvailableReleases. Failures are ignored;
+// the cache is only an optimisation.
+func saveAvailableReleases(available *AvailableReleases) {
+	path, err := getCachePath(availableReleasesFile)
+	if err != nil {
+		return
+	}
+	data, err := json.Marshal(available)
+	if err != nil {
+		return
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return
+	}
+	fileutil.WriteFile(path, data, 0644)
+}
@@ -5,10 +5,45 @@ import (
 	"fmt"
 	"net/http"
 	"runtime"
+	"strings"
 	"time"
+
+	"github.com/user/jswitch/pkg/models"
+)
+
+const (
+	// DefaultAPIBase is the public Adoptium API.
+	DefaultAPIBase = "https://api.adoptium.net"
+
+	featureReleasesPath   = "/v3/assets/feature_releases/%d/ga"
+	availableReleasesPath = "/v3/info/available_releases"
 )
 
-const baseURL = "https://api.adoptium.net/v3/assets/feature_releases/%d/ga"
+// Options select where and what to download.
+type Options struct {
+	// APIBase is the Adoptium API root, or a mirror of it.
+	APIBase string
+	// ImageType is "jdk" or "jre".
+	ImageType string
+	// Vendor is the API vendor parameter; see VendorParam.
+	Vendor string
+}
+
+// DefaultOptions downloads Temurin JDKs from the public API.
+func DefaultOptions() Options {
+	return Options{APIBase: DefaultAPIBase, ImageType: "jdk", Vendor: "eclipse"}
+}
+
+// VendorParam maps a vendor name to the Adoptium API's vendor parameter.
+// The API only serves Eclipse Temurin, so other vendors are an error.
+func VendorParam(name string) (string, error) {
+	switch strings.ToLower(name) {
+	case "", "temurin", "adoptium", "eclipse":
+		return "eclipse", nil
+	default:
+		return "", fmt.Errorf("downloads are only available for temurin, not %s", name)
+	}
+}
 
 type Release struct {
 	Binaries    []Binary    `json:"binaries"`
@@ -33,14 +68,14 @@ type Package struct {
 }
 
 // GetLatestVersion returns the download URL and the semantic version string for the requested major Java version.
-func GetLatestVersion(version int) (string, string, error) {
+func GetLatestVersion(version int, opts Options) (string, string, error) {
 	client := &http.Client{Timeout: 10 * time.Second}
 
 	osParam := getOSParam()
 	archParam := getArchParam()
 
 	// Construct URL with query parameters
-	url := fmt.Sprintf(baseURL, version)
+	url := opts.APIBase + fmt.Sprintf(featureReleasesPath, version)
 	req, err := http.NewRequest("GET", url, nil)
 	if err != nil {
 		return "", "", fmt.Errorf("failed to create request: %w", err)
@@ -49,9 +84,9 @@ func GetLatestVersion(version int) (string, string, error) {
 	q := req.URL.Query()
 	q.Add("os", osParam)
 	q.Add("architecture", archParam)
-	q.Add("image_type", "jdk")
+	q.Add("image_type", opts.ImageType)
 	q.Add("jvm_impl", "hotspot")
-	q.Add("vendor", "eclipse")
+	q.Add("vendor", opts.Vendor)
 	q.Add("page_size", "1") // We only need the latest one
 	q.Add("sort_order", "DESC")
 
@@ -88,6 +123,103 @@ func GetLatestVersion(version int) (string, string, error) {
 	return release.Binaries[0].Package.Link, release.VersionData.Semver, nil
 }
 
+// GetLatestRelease returns the newest GA release of a feature version with the binaries for
+// every platform, for recording in a lockfile.
+func GetLatestRelease(version int, opts Options) (*Release, error) {
+	client := &http.Client{Timeout: 10 * time.Second}
+
+	req, err := http.NewRequest("GET", opts.APIBase+fmt.Sprintf(featureReleasesPath, version), nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	q := req.URL.Query()
+	q.Add("image_type", opts.ImageType)
+	q.Add("jvm_impl", "hotspot")
+	q.Add("vendor", opts.Vendor)
+	q.Add("page_size", "1")
+	q.Add("sort_order", "DESC")
+	req.URL.RawQuery = q.Encode()
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch releases: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("API request failed with status: %d", resp.StatusCode)
+	}
+
+	var releases []Release
+	if err := json.NewDecoder(resp.Body).Decode(&releases); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+	if len(releases) == 0 || len(releases[0].Binaries) == 0 {
+		return nil, fmt.Errorf("no releases found for Java %d", version)
+	}
+	return &releases[0], nil
+}
+
+// Platform names the running OS and architecture the way the API does, e.g. "linux-x64".
+func Platform() string {
+	return getOSParam() + "-" + getArchParam()
+}
+
+// Platform names the binary's OS and architecture, e.g. "mac-aarch64".
+func (b Binary) Platform() string {
+	return b.Os + "-" + b.Architecture
+}
+
+// AvailableReleases lists the feature releases published by Adoptium.
+type AvailableReleases struct {
+	Releases                 []int `json:"available_releases"`
+	LTSReleases              []int `json:"available_lts_releases"`
+	MostRecentLTS            int   `json:"most_recent_lts"`
+	MostRecentFeatureRelease int   `json:"most_recent_feature_release"`
+}
+
+// IsLTS reports whether the feature release is a long-term support release.
+func (a AvailableReleases) IsLTS(version int) bool {
+	for _, v := range a.LTSReleases {
+		if v == version {
+			return true
+		}
+	}
+	return false
+}
+
+// ListAvailableReleases returns the feature releases that can be installed.
+func ListAvailableReleases(opts Options) (*AvailableReleases, error) {
+	client := &http.Client{Timeout: 10 * time.Second}
+
+	resp, err := client.Get(opts.APIBase + availableReleasesPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch available releases: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("API request failed with status: %d", resp.StatusCode)
+	}
+
+	var available AvailableReleases
+	if err := json.NewDecoder(resp.Body).Decode(&available); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+	saveAvailableReleases(&available)
+	return &available, nil
+}
+
+// NewInstallation describes a JDK extracted from an Adoptium release.
+func NewInstallation(semver, path string) models.JavaInstallation {
+	return models.JavaInstallation{
+		Vendor:       "Eclipse Adoptium",
+		Version:      semver,
+		MajorVersion: models.ParseMajorVersion(semver),
+		Path:         path,
+	}
+}
+
 func getOSParam() string {
 	switch runtime.GOOS {
 	case "darwin":
@@ -4,6 +4,8 @@ import (
 	"archive/tar"
 	"archive/zip"
 	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
 	"io"
 	"net/http"
@@ -33,6 +35,12 @@ func (pw *ProgressWriter) Write(p []byte) (int, error) {
 // DownloadAndExtract downloads the file from url and extracts it to destFolder.
 // Sends progress (0.0 - 1.0) to progressChan.
 func DownloadAndExtract(url string, destFolder string, progressChan chan float64) (string, error) {
+	return DownloadVerifyAndExtract(url, "", destFolder, progressChan)
+}
+
+// DownloadVerifyAndExtract is DownloadAndExtract checking the archive against a SHA-256
+// checksum (hex) before extracting it. An empty checksum skips the check.
+func DownloadVerifyAndExtract(url, checksum, destFolder string, progressChan chan float64) (string, error) {
 	// 1. Download
 	resp, err := http.Get(url)
 	if err != nil {
@@ -64,11 +72,15 @@ func DownloadAndExtract(url string, destFolder string, progressChan chan float64
 		},
 	}
 
-	// Copy from response to temp file, tracking progress
-	_, err = io.Copy(tempFile, io.TeeReader(resp.Body, pw))
+	// Copy from response to temp file, tracking progress and hashing
+	hash := sha256.New()
+	_, err = io.Copy(io.MultiWriter(tempFile, hash), io.TeeReader(resp.Body, pw))
 	if err != nil {
 		return "", fmt.Errorf("failed to write file: %w", err)
 	}
+	if sum := hex.EncodeToString(hash.Sum(nil)); checksum != "" && !strings.EqualFold(sum, checksum) {
+		return "", fmt.Errorf("checksum mismatch for %s: expected SHA-256 %s, got %s", url, checksum, sum)
+	}
 
 	// Ensure 100% is sent
 	select {
@@ -84,10 +96,25 @@ func DownloadAndExtract(url string, destFolder string, progressChan chan float64
 	if err != nil {
 		return "", fmt.Errorf("extraction failed: %w", err)
 	}
+	os.Remove(filepath.Join(extractedPath, StagingMarker))
 
 	return extractedPath, nil
 }
 
+// StagingMarker is created in the root directory of an archive while it is extracted and removed
+// once extraction succeeds, so that 'jswitch prune' can tell an interrupted extraction from a JDK.
+const StagingMarker = ".jswitch-staging"
+
+// markStaging creates the StagingMarker in root, the top directory of an archive.
+func markStaging(root string) {
+	if err := os.MkdirAll(root, 0755); err != nil {
+		return
+	}
+	if f, err := os.Create(filepath.Join(root, StagingMarker)); err == nil {
+		f.Close()
+	}
+}
+
 func extract(src string, dest string) (string, error) {
 	// Detect file type by signature or just look at content (since we don't have extension easily from temp file unless we preserved it)
 	// Actually, API usually gives a .zip or .tar.gz.
@@ -130,6 +157,9 @@ func unzip(src string, dest string) (string, error) {
 			if len(parts) > 0 {
 				rootDir = filepath.Join(dest, parts[0])
 			}
+			if len(parts) > 1 {
+				markStaging(rootDir)
+			}
 		}
 
 		fpath := filepath.Join(dest, f.Name)
@@ -148,7 +178,7 @@ func unzip(src string, dest string) (string, error) {
 			return "", err
 		}
 
-		outFile, err := os.OpenFile(fpath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, f.Mode())
+		outFile, err := createFile(fpath, f.Mode())
 		if err != nil {
 			return "", err
 		}
@@ -203,6 +233,9 @@ func untar(src string, dest string) (string, error) {
 			if len(parts) > 0 {
 				rootDir = filepath.Join(dest, parts[0])
 			}
+			if len(parts) > 1 {
+				markStaging(rootDir)
+			}
 		}
 
 		target := filepath.Join(dest, header.Name)
@@ -221,7 +254,7 @@ func untar(src string, dest string) (string, error) {
 			if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
 				return "", err
 			}
-			f, err := os.OpenFile(target, os.O_CREATE|os.O_RDWR, os.FileMode(header.Mode))
+			f, err := createFile(target, os.FileMode(header.Mode))
 			if err != nil {
 				return "", err
 			}
@@ -234,3 +267,12 @@ func untar(src string, dest string) (string, error) {
 	}
 	return rootDir, nil
 }
+
+// createFile creates an extracted file, replacing rather than overwriting an existing one:
+// after 'jswitch dedupe' it may be a hardlink shared with other installations.
+func createFile(path string, mode os.FileMode) (*os.File, error) {
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	return os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, mode)
+}
@@ -0,0 +1,78 @@
+package fileutil
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// LockTimeout is how long Lock waits for another process to release a lock.
+const LockTimeout = 30 * time.Second
+
+// ErrLocked is returned by LockWithin with a zero timeout when another process holds the lock.
+var ErrLocked = errors.New("locked")
+
+// FileLock is an exclusive advisory lock held on a lock file.
+// Locks are per open file, so a process must not lock the same path twice.
+type FileLock struct {
+	f *os.File
+}
+
+// Lock takes an exclusive lock on path (conventionally "<file>.lock"), creating it if
+// needed. It waits up to LockTimeout for other processes to release it.
+// The lock is released by Unlock, or by the operating system when the process exits.
+func Lock(path string) (*FileLock, error) {
+	return LockWithin(path, LockTimeout)
+}
+
+// LockWithin is Lock waiting up to timeout. With a zero timeout it does not wait, and
+// returns ErrLocked if another process holds the lock.
+func LockWithin(path string, timeout time.Duration) (*FileLock, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, err
+	}
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0644)
+	if os.IsPermission(err) {
+		// A lock file created by another user in a shared directory; reading is enough to lock it.
+		f, err = os.Open(path)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	deadline := time.Now().Add(timeout)
+	delay := 5 * time.Millisecond
+	for {
+		err := tryLock(f)
+		if err == nil {
+			return &FileLock{f: f}, nil
+		}
+		if !errors.Is(err, ErrLocked) {
+			f.Close()
+			return nil, fmt.Errorf("failed to lock %s: %w", path, err)
+		}
+		if timeout == 0 {
+			f.Close()
+			return nil, ErrLocked
+		}
+		if time.Now().After(deadline) {
+			f.Close()
+			return nil, fmt.Errorf("timed out after %s waiting for %s; is another jswitch still running?", timeout, path)
+		}
+		time.Sleep(delay)
+		if delay < 100*time.Millisecond {
+			delay *= 2
+		}
+	}
+}
+
+// Unlock releases the lock.
+func (l *FileLock) Unlock() error {
+	if err := unlock(l.f); err != nil {
+		l.f.Close()
+		return err
+	}
+	return l.f.Close()
+}
@@ -0,0 +1,52 @@
+package fileutil
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// Permissions used under the system installation root, so that every member of the
+// root's group can install, update and remove JDKs. Directories are setgid so that new
+// files inherit the root's group.
+const (
+	SharedDirMode  = 0775 | os.ModeSetgid
+	SharedFileMode = 0664
+)
+
+// MkdirShared creates dir and its missing parents, and gives dir SharedDirMode regardless
+// of the umask. A dir owned by someone else keeps its mode; it is assumed to be set up.
+func MkdirShared(dir string) error {
+	if err := os.MkdirAll(dir, 0775); err != nil {
+		return err
+	}
+	if err := os.Chmod(dir, SharedDirMode); err != nil && !os.IsPermission(err) {
+		return err
+	}
+	return nil
+}
+
+// ShareTree makes everything under root that the current user owns group-readable and
+// group-writable, and directories setgid, keeping the other permission bits.
+func ShareTree(root string) error {
+	return filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.Type()&fs.ModeSymlink != 0 {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		mode := info.Mode().Perm() | 0060
+		if d.IsDir() {
+			mode |= 0070 | os.ModeSetgid
+		}
+		if err := os.Chmod(path, mode); err != nil && !os.IsPermission(err) {
+			return err
+		}
+		return nil
+	})
+}
@@ -0,0 +1,61 @@
+// Package fileutil provides crash-safe file writes and advisory file locks.
+package fileutil
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+)
+
+// WriteFile replaces path with data so that readers, and the file after a crash, hold
+// either the old or the new contents, never a truncated mix. Data goes to a temporary
+// file in the same directory, which is fsynced and then renamed over path.
+// If path is a symlink, its target is replaced and the link is left in place.
+func WriteFile(path string, data []byte, perm os.FileMode) error {
+	if resolved, err := filepath.EvalSymlinks(path); err == nil {
+		path = resolved
+	}
+	dir := filepath.Dir(path)
+
+	tmp, err := os.CreateTemp(dir, "."+filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return err
+	}
+	tmpPath := tmp.Name()
+	committed := false
+	defer func() {
+		if !committed {
+			tmp.Close()
+			os.Remove(tmpPath)
+		}
+	}()
+
+	if _, err := tmp.Write(data); err != nil {
+		return err
+	}
+	if err := tmp.Chmod(perm); err != nil && runtime.GOOS != "windows" {
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(tmpPath, path); err != nil {
+		return err
+	}
+	committed = true
+
+	// Persist the rename itself. Directories cannot be opened for syncing on Windows.
+	if runtime.GOOS != "windows" {
+		if d, err := os.Open(dir); err == nil {
+			defer d.Close()
+			if err := d.Sync(); err != nil {
+				return fmt.Errorf("failed to sync %s: %w", dir, err)
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,118 @@
+package history
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/user/jswitch/pkg/fileutil"
+	"github.com/user/jswitch/pkg/paths"
+)
+
+const (
+	// MaxEntries bounds the history log; older entries are dropped first.
+	MaxEntries = 100
+)
+
+// Scopes recorded with each switch.
+const (
+	ScopeGlobal = "global"
+	// ScopeSystem is a switch of the system-wide alternatives ('jswitch use --system').
+	ScopeSystem = "system"
+)
+
+// Entry records a single version switch.
+type Entry struct {
+	Time  time.Time `json:"time"`
+	From  string    `json:"from,omitempty"`
+	To    string    `json:"to"`
+	Scope string    `json:"scope"`
+}
+
+// getHistoryPath returns the full path to the history file, in the state directory.
+func getHistoryPath() (string, error) {
+	return paths.HistoryFile()
+}
+
+// Load returns the recorded switches, oldest first.
+// Returns an empty slice if no history has been recorded yet.
+func Load() ([]Entry, error) {
+	path, err := getHistoryPath()
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return []Entry{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read history file at %s: %w", path, err)
+	}
+
+	var entries []Entry
+	if err := json.Unmarshal(data, &entries); err != nil {
+		return nil, fmt.Errorf("failed to parse history file: %w", err)
+	}
+	return entries, nil
+}
+
+// Record appends an entry to the history, trimming it to MaxEntries.
+func Record(e Entry) error {
+	path, err := getHistoryPath()
+	if err != nil {
+		return err
+	}
+	lock, err := fileutil.Lock(path + ".lock")
+	if err != nil {
+		return fmt.Errorf("failed to lock history: %w", err)
+	}
+	defer lock.Unlock()
+
+	entries, err := Load()
+	if err != nil {
+		// A corrupt history is not worth failing a switch over; start again.
+		entries = nil
+	}
+
+	if e.Time.IsZero() {
+		e.Time = time.Now()
+	}
+	if e.Scope == "" {
+		e.Scope = ScopeGlobal
+	}
+	entries = append(entries, e)
+	if len(entries) > MaxEntries {
+		entries = entries[len(entries)-MaxEntries:]
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create history directory: %w", err)
+	}
+
+	data, err := json.MarshalIndent(entries, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal history: %w", err)
+	}
+	if err := fileutil.WriteFile(path, data, 0644); err != nil {
+		return fmt.Errorf("failed to write history file to %s: %w", path, err)
+	}
+	return nil
+}
+
+// Previous returns the version that was active before the most recent switch in scope.
+// Entries recorded before scopes existed count as global.
+func Previous(scope string) (string, bool) {
+	entries, err := Load()
+	if err != nil {
+		return "", false
+	}
+	for i := len(entries) - 1; i >= 0; i-- {
+		if s := entries[i].Scope; s == scope || (s == "" && scope == ScopeGlobal) {
+			return entries[i].From, entries[i].From != ""
+		}
+	}
+	return "", false
+}
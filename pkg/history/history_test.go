@@ -0,0 +1,133 @@
+package history
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/user/jswitch/pkg/paths"
+)
+
+// useTempHome points the jswitch home, and so the history file, at a temporary directory.
+func useTempHome(t *testing.T) string {
+	t.Helper()
+	t.Setenv(paths.EnvHome, t.TempDir())
+	path, err := paths.HistoryFile()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestRecordTrims(t *testing.T) {
+	useTempHome(t)
+	for i := 0; i < MaxEntries+5; i++ {
+		if err := Record(Entry{From: fmt.Sprint(i), To: fmt.Sprint(i + 1)}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	entries, err := Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != MaxEntries {
+		t.Fatalf("got %d entries, want %d", len(entries), MaxEntries)
+	}
+	if entries[0].From != "5" || entries[len(entries)-1].To != fmt.Sprint(MaxEntries+5) {
+		t.Errorf("kept %s..%s, want the newest entries", entries[0].From, entries[len(entries)-1].To)
+	}
+	if entries[0].Scope != ScopeGlobal || entries[0].Time.IsZero() {
+		t.Errorf("Record did not fill in the defaults: %+v", entries[0])
+	}
+}
+
+func TestPrevious(t *testing.T) {
+	tests := []struct {
+		name    string
+		entries []Entry
+		scope   string
+		want    string
+		wantOK  bool
+	}{
+		{name: "empty history", scope: ScopeGlobal},
+		{
+			name:    "global",
+			entries: []Entry{{From: "11", To: "17"}, {From: "17", To: "21"}},
+			scope:   ScopeGlobal,
+			want:    "17",
+			wantOK:  true,
+		},
+		{
+			name:    "system switches are separate",
+			entries: []Entry{{From: "11", To: "17"}, {From: "8", To: "21", Scope: ScopeSystem}},
+			scope:   ScopeGlobal,
+			want:    "11",
+			wantOK:  true,
+		},
+		{
+			name:    "system",
+			entries: []Entry{{From: "8", To: "21", Scope: ScopeSystem}, {From: "11", To: "17"}},
+			scope:   ScopeSystem,
+			want:    "8",
+			wantOK:  true,
+		},
+		{
+			name:    "no switch in scope",
+			entries: []Entry{{From: "11", To: "17"}},
+			scope:   ScopeSystem,
+		},
+		{
+			name:    "first switch",
+			entries: []Entry{{To: "17"}},
+			scope:   ScopeGlobal,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			useTempHome(t)
+			for _, e := range tt.entries {
+				if err := Record(e); err != nil {
+					t.Fatal(err)
+				}
+			}
+			got, ok := Previous(tt.scope)
+			if got != tt.want || ok != tt.wantOK {
+				t.Errorf("Previous(%s) = %q, %v, want %q, %v", tt.scope, got, ok, tt.want, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestPreviousBeforeScopes(t *testing.T) {
+	path := useTempHome(t)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	legacy := `[{"time": "2024-01-01T00:00:00Z", "from": "11", "to": "17"}]`
+	if err := os.WriteFile(path, []byte(legacy), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if got, ok := Previous(ScopeGlobal); got != "11" || !ok {
+		t.Errorf("Previous(global) = %q, %v, want the unscoped entry", got, ok)
+	}
+	if _, ok := Previous(ScopeSystem); ok {
+		t.Error("an unscoped entry counts as a system switch")
+	}
+}
+
+func TestRecordReplacesCorruptHistory(t *testing.T) {
+	path := useTempHome(t)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte("{not json"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := Record(Entry{From: "11", To: "17"}); err != nil {
+		t.Fatal(err)
+	}
+	if got, ok := Previous(ScopeGlobal); got != "11" || !ok {
+		t.Errorf("Previous after recording over a corrupt file = %q, %v", got, ok)
+	}
+}
@@ -0,0 +1,283 @@
+// Package importer reads the JDKs, defaults and project files of other Java version
+// managers (SDKMAN!, jenv, asdf and jabba) so that 'jswitch import' can take them over.
+package importer
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// State is what a version manager knows about its JDKs.
+type State struct {
+	// Root is the tool's data directory, e.g. ~/.sdkman.
+	Root string
+	// Homes maps the tool's name for each JDK (e.g. "17.0.9-tem") to its home directory.
+	Homes map[string]string
+	// Default names the JDK the tool selects when nothing else does; empty if none.
+	Default string
+	// Aliases maps names the tool lets users define to JDK names.
+	Aliases map[string]string
+}
+
+// Names returns the JDK names in order.
+func (s *State) Names() []string {
+	names := make([]string, 0, len(s.Homes))
+	for name := range s.Homes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Tool is a supported version manager.
+type Tool struct {
+	Name string
+	// Load reads the tool's state from its data directory, relative to the user's home.
+	Load func(home string) (*State, error)
+	// ProjectFile is the file that pins a JDK for a directory.
+	ProjectFile string
+	// ReadProject returns the JDK name a project file selects.
+	ReadProject func(data []byte) (string, bool)
+}
+
+// Tools lists the supported version managers.
+var Tools = []Tool{
+	{Name: "sdkman", Load: loadSDKMAN, ProjectFile: ".sdkmanrc", ReadProject: readSDKMANRC},
+	{Name: "jenv", Load: loadJenv, ProjectFile: ".java-version", ReadProject: readFirstLine},
+	{Name: "asdf", Load: loadAsdf, ProjectFile: ".tool-versions", ReadProject: readToolVersions},
+	{Name: "jabba", Load: loadJabba, ProjectFile: ".jabbarc", ReadProject: readFirstLine},
+}
+
+// Lookup returns the tool called name.
+func Lookup(name string) (Tool, bool) {
+	for _, t := range Tools {
+		if t.Name == strings.ToLower(name) {
+			return t, true
+		}
+	}
+	return Tool{}, false
+}
+
+// ToolNames returns the names of the supported tools.
+func ToolNames() []string {
+	names := make([]string, len(Tools))
+	for i, t := range Tools {
+		names[i] = t.Name
+	}
+	return names
+}
+
+// root returns $env, or dir under home.
+func root(env, home, dir string) string {
+	if v := os.Getenv(env); v != "" {
+		return v
+	}
+	return filepath.Join(home, dir)
+}
+
+// readJDKDirs maps the name of every JDK directory in dir to its home. Entries may be
+// symlinks; they are resolved. Entries that do not contain a JDK are skipped.
+func readJDKDirs(dir string, skip ...string) (map[string]string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	homes := make(map[string]string)
+	for _, e := range entries {
+		name := e.Name()
+		if strings.HasPrefix(name, ".") || contains(skip, name) {
+			continue
+		}
+		path, err := filepath.EvalSymlinks(filepath.Join(dir, name))
+		if err != nil {
+			continue
+		}
+		if home, ok := jdkHome(path); ok {
+			homes[name] = home
+		}
+	}
+	return homes, nil
+}
+
+// jdkHome returns the JDK home in dir: dir itself, or Contents/Home for a macOS bundle.
+func jdkHome(dir string) (string, bool) {
+	for _, home := range []string{dir, filepath.Join(dir, "Contents", "Home")} {
+		for _, java := range []string{"java", "java.exe"} {
+			if info, err := os.Stat(filepath.Join(home, "bin", java)); err == nil && !info.IsDir() {
+				return home, true
+			}
+		}
+	}
+	return "", false
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+// notFound reports a tool whose data directory does not exist.
+func notFound(tool, dir string, err error) error {
+	if os.IsNotExist(err) {
+		return fmt.Errorf("no %s installation found at %s", tool, dir)
+	}
+	return fmt.Errorf("failed to read %s: %w", dir, err)
+}
+
+// SDKMAN!: candidates/java/<id>, with the current symlink pointing at the default.
+func loadSDKMAN(home string) (*State, error) {
+	dir := root("SDKMAN_DIR", home, ".sdkman")
+	java := filepath.Join(dir, "candidates", "java")
+	homes, err := readJDKDirs(java, "current")
+	if err != nil {
+		return nil, notFound("SDKMAN", java, err)
+	}
+	s := &State{Root: dir, Homes: homes}
+	if target, err := os.Readlink(filepath.Join(java, "current")); err == nil {
+		s.Default = filepath.Base(target)
+	}
+	return s, nil
+}
+
+// readSDKMANRC reads java=<id> from a .sdkmanrc.
+func readSDKMANRC(data []byte) (string, bool) {
+	sc := bufio.NewScanner(bytes.NewReader(data))
+	for sc.Scan() {
+		line := strings.TrimSpace(sc.Text())
+		if key, value, ok := strings.Cut(line, "="); ok && strings.TrimSpace(key) == "java" {
+			return strings.TrimSpace(value), strings.TrimSpace(value) != ""
+		}
+	}
+	return "", false
+}
+
+// jenv: versions/<name> symlinks to JDK homes, and a version file with the global choice.
+func loadJenv(home string) (*State, error) {
+	dir := root("JENV_ROOT", home, ".jenv")
+	versions := filepath.Join(dir, "versions")
+	homes, err := readJDKDirs(versions)
+	if err != nil {
+		return nil, notFound("jenv", versions, err)
+	}
+	s := &State{Root: dir, Homes: homes}
+	if data, err := os.ReadFile(filepath.Join(dir, "version")); err == nil {
+		if name, ok := readFirstLine(data); ok && name != "system" {
+			s.Default = name
+		}
+	}
+	return s, nil
+}
+
+// asdf: installs/java/<name>, and the global ~/.tool-versions.
+func loadAsdf(home string) (*State, error) {
+	dir := root("ASDF_DATA_DIR", home, ".asdf")
+	installs := filepath.Join(dir, "installs", "java")
+	homes, err := readJDKDirs(installs)
+	if err != nil {
+		return nil, notFound("asdf", installs, err)
+	}
+	s := &State{Root: dir, Homes: homes}
+	if data, err := os.ReadFile(filepath.Join(home, ".tool-versions")); err == nil {
+		if name, ok := readToolVersions(data); ok {
+			s.Default = name
+		}
+	}
+	return s, nil
+}
+
+// readToolVersions reads the first java version of a .tool-versions file.
+func readToolVersions(data []byte) (string, bool) {
+	sc := bufio.NewScanner(bytes.NewReader(data))
+	for sc.Scan() {
+		line, _, _ := strings.Cut(sc.Text(), "#")
+		if fields := strings.Fields(line); len(fields) >= 2 && fields[0] == "java" {
+			return fields[1], true
+		}
+	}
+	return "", false
+}
+
+// jabba: jdk/<name>, and <alias>.alias files naming a JDK; "default" is the default.
+func loadJabba(home string) (*State, error) {
+	dir := root("JABBA_HOME", home, ".jabba")
+	jdks := filepath.Join(dir, "jdk")
+	homes, err := readJDKDirs(jdks)
+	if err != nil {
+		return nil, notFound("jabba", jdks, err)
+	}
+	s := &State{Root: dir, Homes: homes, Aliases: make(map[string]string)}
+	files, _ := filepath.Glob(filepath.Join(dir, "*.alias"))
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			continue
+		}
+		name, ok := readFirstLine(data)
+		if !ok {
+			continue
+		}
+		if alias := strings.TrimSuffix(filepath.Base(file), ".alias"); alias == "default" {
+			s.Default = name
+		} else {
+			s.Aliases[alias] = name
+		}
+	}
+	return s, nil
+}
+
+// readFirstLine returns the first non-empty, non-comment line.
+func readFirstLine(data []byte) (string, bool) {
+	sc := bufio.NewScanner(bytes.NewReader(data))
+	for sc.Scan() {
+		line := strings.TrimSpace(sc.Text())
+		if line != "" && !strings.HasPrefix(line, "#") {
+			return line, true
+		}
+	}
+	return "", false
+}
+
+// vendorHints maps the vendor part of a tool's JDK name to a vendor jswitch recognises.
+var vendorHints = map[string]string{
+	"tem":          "Eclipse Adoptium",
+	"temurin":      "Eclipse Adoptium",
+	"adopt":        "AdoptOpenJDK",
+	"adoptopenjdk": "AdoptOpenJDK",
+	"zulu":         "Azul Zulu",
+	"amzn":         "Amazon Corretto",
+	"corretto":     "Amazon Corretto",
+	"graal":        "GraalVM",
+	"graalce":      "GraalVM",
+	"graalvm":      "GraalVM",
+	"librca":       "BellSoft Liberica",
+	"liberica":     "BellSoft Liberica",
+	"ms":           "Microsoft",
+	"microsoft":    "Microsoft",
+	"sapmchn":      "SAP Machine",
+	"sapmachine":   "SAP Machine",
+	"sem":          "IBM Semeru",
+	"semeru":       "IBM Semeru",
+	"oracle":       "Oracle",
+}
+
+// Vendor guesses the vendor from a tool's JDK name, e.g. "17.0.9-tem", "zulu@1.17.0" or
+// "corretto-17.0.9.8.1". Returns "" when the name does not say.
+func Vendor(name string) string {
+	for _, part := range strings.FieldsFunc(strings.ToLower(name), func(r rune) bool {
+		return r == '-' || r == '@' || r == '_' || r == '+'
+	}) {
+		if vendor, ok := vendorHints[part]; ok {
+			return vendor
+		}
+	}
+	return ""
+}
@@ -0,0 +1,112 @@
+// Package lockfile reads and writes jswitch.lock, which pins a project to one exact JDK
+// build: the vendor, the full version and, for every platform, the archive to download
+// and its SHA-256 checksum. It is meant to be committed so that everyone working on the
+// project, and CI, run the same JDK.
+package lockfile
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/user/jswitch/pkg/fileutil"
+)
+
+const (
+	// FileName is looked up in the working directory and its parents.
+	FileName = "jswitch.lock"
+	// SchemaVersion is the current layout of the file.
+	SchemaVersion = 1
+)
+
+// Lock is the contents of a jswitch.lock file.
+type Lock struct {
+	SchemaVersion int `json:"schema_version"`
+	// Feature is the Java feature release the lock follows; 'jswitch lock --update' moves
+	// to the newest build of it.
+	Feature   int    `json:"feature"`
+	Vendor    string `json:"vendor"`
+	ImageType string `json:"image_type"`
+	// Version is the exact version, as 'jswitch install' records it.
+	Version   string              `json:"version"`
+	Platforms map[string]Artifact `json:"platforms"`
+}
+
+// Artifact is the archive of the locked build for one platform.
+type Artifact struct {
+	URL    string `json:"url"`
+	Name   string `json:"name,omitempty"`
+	SHA256 string `json:"sha256"`
+}
+
+// Artifact returns the archive for platform (e.g. "linux-x64").
+func (l *Lock) Artifact(platform string) (Artifact, error) {
+	a, ok := l.Platforms[platform]
+	if !ok {
+		return Artifact{}, fmt.Errorf("%s has no build for %s (it has %v); run 'jswitch lock --update' on this platform", FileName, platform, l.PlatformNames())
+	}
+	return a, nil
+}
+
+// PlatformNames lists the locked platforms in order.
+func (l *Lock) PlatformNames() []string {
+	names := make([]string, 0, len(l.Platforms))
+	for name := range l.Platforms {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Read parses the lockfile at path.
+func Read(path string) (*Lock, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var l Lock
+	if err := json.Unmarshal(data, &l); err != nil {
+		return nil, fmt.Errorf("invalid %s: %w", path, err)
+	}
+	if l.SchemaVersion > SchemaVersion {
+		return nil, fmt.Errorf("%s has schema version %d; this jswitch understands up to %d, please upgrade", path, l.SchemaVersion, SchemaVersion)
+	}
+	if l.Version == "" {
+		return nil, fmt.Errorf("invalid %s: no version", path)
+	}
+	return &l, nil
+}
+
+// Write stores l at path.
+func Write(path string, l *Lock) error {
+	l.SchemaVersion = SchemaVersion
+	data, err := json.MarshalIndent(l, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode %s: %w", FileName, err)
+	}
+	if err := fileutil.WriteFile(path, append(data, '\n'), 0644); err != nil {
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	return nil
+}
+
+// Find walks up from dir to the nearest jswitch.lock and returns its path.
+func Find(dir string) (string, bool) {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return "", false
+	}
+	for {
+		path := filepath.Join(dir, FileName)
+		if info, err := os.Stat(path); err == nil && !info.IsDir() {
+			return path, true
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return "", false
+		}
+		dir = parent
+	}
+}
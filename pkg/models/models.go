@@ -16,5 +16,5 @@ type JavaInstallation struct {
 }
 
 func (j JavaInstallation) String() string {
-	return fmt.Sprintf("[%s] %s (%s) @ %s", j.Vendor, j.Version, j.MajorVersion, j.Path)
+	return fmt.Sprintf("[%s] %s (%d) @ %s", j.Vendor, j.Version, j.MajorVersion, j.Path)
 }
@@ -0,0 +1,59 @@
+package models
+
+import (
+	"strconv"
+	"strings"
+)
+
+// ParseMajorVersion extracts the feature release number from a Java version string.
+// Handles both the legacy "1.8.0_202" scheme and the modern "17.0.2+8" scheme.
+func ParseMajorVersion(version string) int {
+	parts := versionNumbers(version)
+	if len(parts) == 0 {
+		return 0
+	}
+	// Old style: 1.8.0 -> 8
+	if parts[0] == 1 && len(parts) > 1 {
+		return parts[1]
+	}
+	// New style: 17.0.2 -> 17
+	return parts[0]
+}
+
+// CompareVersions compares two Java version strings numerically, component by component.
+// Returns -1 if a < b, 0 if equal and 1 if a > b.
+func CompareVersions(a, b string) int {
+	pa, pb := versionNumbers(a), versionNumbers(b)
+	for i := 0; i < len(pa) || i < len(pb); i++ {
+		var x, y int
+		if i < len(pa) {
+			x = pa[i]
+		}
+		if i < len(pb) {
+			y = pb[i]
+		}
+		if x < y {
+			return -1
+		}
+		if x > y {
+			return 1
+		}
+	}
+	return 0
+}
+
+// versionNumbers splits a version string on any non-digit separator ('.', '_', '+', '-').
+func versionNumbers(version string) []int {
+	fields := strings.FieldsFunc(version, func(r rune) bool {
+		return r < '0' || r > '9'
+	})
+	nums := make([]int, 0, len(fields))
+	for _, f := range fields {
+		n, err := strconv.Atoi(f)
+		if err != nil {
+			break
+		}
+		nums = append(nums, n)
+	}
+	return nums
+}
@@ -0,0 +1,158 @@
+package output
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+	"text/template"
+
+	"gopkg.in/yaml.v3"
+)
+
+// SchemaVersion is bumped whenever a field is removed or changes meaning.
+// Adding fields is not a breaking change. See docs/output.md.
+const SchemaVersion = 1
+
+// Format selects how command results are rendered.
+type Format string
+
+const (
+	FormatTable    Format = "table"
+	FormatJSON     Format = "json"
+	FormatYAML     Format = "yaml"
+	FormatTemplate Format = "template"
+)
+
+// Options holds the parsed --output / --format flags.
+type Options struct {
+	Format   Format
+	Template string
+}
+
+// Structured reports whether output should be machine-readable rather than prose.
+func (o Options) Structured() bool {
+	return o.Format != FormatTable
+}
+
+// Envelope wraps every JSON/YAML document so consumers can check the schema version.
+type Envelope struct {
+	SchemaVersion int    `json:"schema_version"`
+	Kind          string `json:"kind"`
+	Data          any    `json:"data"`
+}
+
+// NewOptions validates the values of the --output and --format flags.
+// A non-empty template implies the template format.
+func NewOptions(format, tmpl string) (Options, error) {
+	opts := Options{Format: Format(format), Template: tmpl}
+	if opts.Format == "" {
+		opts.Format = FormatTable
+	}
+	if tmpl != "" {
+		opts.Format = FormatTemplate
+	}
+
+	switch opts.Format {
+	case FormatTable, FormatJSON, FormatYAML, FormatTemplate:
+	default:
+		return opts, fmt.Errorf("unknown output format %q (use json, yaml, table or template)", format)
+	}
+
+	if opts.Format == FormatTemplate && opts.Template == "" {
+		return opts, fmt.Errorf("--output template requires --format '<go template>'")
+	}
+	return opts, nil
+}
+
+// Write renders data in the selected structured format.
+// For templates, a slice is rendered one element per line so '{{.Path}}' works on lists.
+func (o Options) Write(w io.Writer, kind string, data any) error {
+	switch o.Format {
+	case FormatJSON:
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(Envelope{SchemaVersion: SchemaVersion, Kind: kind, Data: data})
+
+	case FormatYAML:
+		return writeYAML(w, Envelope{SchemaVersion: SchemaVersion, Kind: kind, Data: data})
+
+	case FormatTemplate:
+		tmpl, err := template.New("format").Funcs(template.FuncMap{
+			"json": func(v any) (string, error) {
+				b, err := json.Marshal(v)
+				return string(b), err
+			},
+			"join": strings.Join,
+		}).Parse(o.Template)
+		if err != nil {
+			return fmt.Errorf("invalid template: %w", err)
+		}
+
+		v := reflect.ValueOf(data)
+		if v.Kind() != reflect.Slice {
+			return executeLine(w, tmpl, data)
+		}
+		for i := 0; i < v.Len(); i++ {
+			if err := executeLine(w, tmpl, v.Index(i).Interface()); err != nil {
+				return err
+			}
+		}
+		return nil
+
+	default:
+		return fmt.Errorf("format %q is not a structured format", o.Format)
+	}
+}
+
+func executeLine(w io.Writer, tmpl *template.Template, data any) error {
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return fmt.Errorf("template failed: %w", err)
+	}
+	// Items filtered out by the template ({{if ...}}) produce no blank line.
+	if buf.Len() == 0 {
+		return nil
+	}
+	if !bytes.HasSuffix(buf.Bytes(), []byte("\n")) {
+		buf.WriteByte('\n')
+	}
+	_, err := w.Write(buf.Bytes())
+	return err
+}
+
+// writeYAML renders via JSON so YAML keys and field order match the JSON schema exactly.
+func writeYAML(w io.Writer, v any) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+
+	var node yaml.Node
+	if err := yaml.Unmarshal(data, &node); err != nil {
+		return err
+	}
+	blockStyle(&node)
+
+	enc := yaml.NewEncoder(w)
+	enc.SetIndent(2)
+	if err := enc.Encode(&node); err != nil {
+		return err
+	}
+	return enc.Close()
+}
+
+// blockStyle clears the flow style the JSON input was parsed with.
+func blockStyle(n *yaml.Node) {
+	if n.Kind == yaml.MappingNode || n.Kind == yaml.SequenceNode {
+		n.Style = 0
+	}
+	if n.Kind == yaml.ScalarNode && n.Style == yaml.DoubleQuotedStyle && n.Tag == "!!str" {
+		n.Style = 0
+	}
+	for _, c := range n.Content {
+		blockStyle(c)
+	}
+}
@@ -0,0 +1,84 @@
+package output
+
+import "github.com/user/jswitch/pkg/models"
+
+// Document kinds, reported in Envelope.Kind.
+const (
+	KindInstallationList = "InstallationList"
+	KindInstallation     = "Installation"
+	KindRemoteList       = "RemoteReleaseList"
+	KindDoctorReport     = "DoctorReport"
+	KindSettingList      = "SettingList"
+	KindDiskUsage        = "DiskUsage"
+)
+
+// Installation is models.JavaInstallation annotated with jswitch state.
+type Installation struct {
+	models.JavaInstallation
+	Current bool     `json:"current"`
+	Aliases []string `json:"aliases,omitempty"`
+}
+
+// Resolution is the installation reported by 'jswitch current', with the reason it applies.
+type Resolution struct {
+	Installation
+	// Source is one of "env", "project", "global" or "default".
+	Source string `json:"source"`
+	Spec   string `json:"spec"`
+	Origin string `json:"origin,omitempty"`
+	Alias  string `json:"alias,omitempty"`
+	// Detail names the build file declaration the spec was inferred from.
+	Detail string `json:"detail,omitempty"`
+}
+
+// RemoteRelease is a feature release available for download.
+type RemoteRelease struct {
+	FeatureVersion int  `json:"feature_version"`
+	LTS            bool `json:"lts"`
+}
+
+// Finding is a single doctor result.
+type Finding struct {
+	Check    string `json:"check"`
+	Severity string `json:"severity"`
+	Message  string `json:"message"`
+	Fix      string `json:"fix,omitempty"`
+	Fixable  bool   `json:"fixable"`
+}
+
+// DoctorReport is the result of 'jswitch doctor'.
+type DoctorReport struct {
+	OK       bool      `json:"ok"`
+	Findings []Finding `json:"findings"`
+}
+
+// Setting is the effective value of a user setting.
+type Setting struct {
+	Key   string `json:"key"`
+	Value string `json:"value"`
+	// Source is one of "default", "config", "system" or "env".
+	Source  string `json:"source"`
+	Default string `json:"default"`
+	Type    string `json:"type"`
+	EnvVar  string `json:"env_var"`
+}
+
+// InstallationUsage is the disk usage of one installation.
+type InstallationUsage struct {
+	Version string `json:"version"`
+	Path    string `json:"path"`
+	// Size counts every file of the installation; Shared is the part of it in files hardlinked
+	// into other installations, and Unique the part deleting the installation frees.
+	Size   int64 `json:"size"`
+	Shared int64 `json:"shared"`
+	Unique int64 `json:"unique"`
+}
+
+// DiskUsage is the result of 'jswitch du'.
+type DiskUsage struct {
+	Installations []InstallationUsage `json:"installations"`
+	// Total is the space all installations take, counting shared files once; Saved is what
+	// sharing saves compared to the sum of their sizes.
+	Total int64 `json:"total"`
+	Saved int64 `json:"saved"`
+}
@@ -0,0 +1,86 @@
+package paths
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// Migration describes a completed move from the legacy ~/.jswitch directory.
+type Migration struct {
+	From Layout
+	To   Layout
+}
+
+// MigrateLegacy moves a legacy ~/.jswitch directory into the platform's native layout.
+// It returns nil when there is nothing to do: $JSWITCH_HOME is set, the native layout is
+// ~/.jswitch itself (macOS), no legacy state exists, or the native layout is already in use.
+//
+// Files are renamed, not copied; if any step fails the earlier ones are undone and the
+// legacy directory stays in use. The current symlink is removed rather than moved, and
+// installation paths in config.json are left untouched, so the caller must relink and
+// rewrite paths under From.VersionsDir().
+func MigrateLegacy() (*Migration, error) {
+	if os.Getenv(EnvHome) != "" {
+		return nil, nil
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return nil, fmt.Errorf("could not find user home directory: %w", err)
+	}
+
+	from := flat(filepath.Join(home, legacyDirName))
+	to := nativeLayout(home)
+	if from == to || !legacyInUse(from) || exists(to.ConfigFile()) {
+		return nil, nil
+	}
+
+	for _, dir := range []string{to.Config, to.Data, to.Cache, to.State} {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return nil, fmt.Errorf("failed to create %s: %w", dir, err)
+		}
+	}
+
+	// config.json goes last: once it has moved, Current switches to the new layout.
+	moves := [][2]string{
+		{from.VersionsDir(), to.VersionsDir()},
+		{from.Cache, to.Cache},
+		{from.HistoryFile(), to.HistoryFile()},
+		{from.CertsDir(), to.CertsDir()},
+		{from.ConfigFile(), to.ConfigFile()},
+	}
+	var done [][2]string
+	for _, mv := range moves {
+		if _, err := os.Lstat(mv[0]); os.IsNotExist(err) {
+			continue
+		}
+		if err := moveInto(mv[0], mv[1]); err != nil {
+			for i := len(done) - 1; i >= 0; i-- {
+				os.Rename(done[i][1], done[i][0])
+			}
+			return nil, fmt.Errorf("failed to move %s to %s: %w", mv[0], mv[1], err)
+		}
+		done = append(done, mv)
+	}
+
+	if info, err := os.Lstat(from.CurrentLink()); err == nil && info.Mode()&os.ModeSymlink != 0 {
+		os.Remove(from.CurrentLink())
+	}
+	// Only succeeds if nothing else was left behind.
+	os.Remove(from.Config)
+
+	return &Migration{From: from, To: to}, nil
+}
+
+// moveInto renames src to dst, merging into dst when it is an existing empty directory.
+func moveInto(src, dst string) error {
+	if entries, err := os.ReadDir(dst); err == nil {
+		if len(entries) > 0 {
+			return fmt.Errorf("%s already exists and is not empty", dst)
+		}
+		if err := os.Remove(dst); err != nil {
+			return err
+		}
+	}
+	return os.Rename(src, dst)
+}
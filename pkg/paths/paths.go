@@ -0,0 +1,214 @@
+// Package paths decides where jswitch keeps its files.
+//
+// The location depends on the environment, in order of precedence:
+//   - $JSWITCH_HOME: everything lives directly under that directory.
+//   - Windows: %LOCALAPPDATA%\jswitch.
+//   - macOS: ~/.jswitch.
+//   - Linux and other Unix systems: the XDG base directories, split into
+//     config ($XDG_CONFIG_HOME/jswitch), data ($XDG_DATA_HOME/jswitch),
+//     cache ($XDG_CACHE_HOME/jswitch) and state ($XDG_STATE_HOME/jswitch).
+//
+// An existing legacy ~/.jswitch directory keeps being used until MigrateLegacy moves it.
+package paths
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+)
+
+// EnvHome overrides every jswitch directory with a single root.
+const EnvHome = "JSWITCH_HOME"
+
+// EnvSystemHome overrides the shared, multi-user installation root.
+const EnvSystemHome = "JSWITCH_SYSTEM_HOME"
+
+const (
+	appName       = "jswitch"
+	legacyDirName = ".jswitch"
+
+	configFileName  = "config.json"
+	historyFileName = "history.json"
+	versionsDirName = "versions"
+	currentLinkName = "current"
+	cacheDirName    = "cache"
+	certsDirName    = "certs"
+)
+
+// Layout is the set of directories jswitch uses.
+type Layout struct {
+	// Config holds config.json and the extra trusted certificates (certs/).
+	Config string
+	// Data holds installed JDKs (versions/) and the current symlink.
+	Data string
+	// Cache holds downloaded metadata that can be thrown away at any time.
+	Cache string
+	// State holds history.json.
+	State string
+}
+
+// ConfigFile returns the path of config.json.
+func (l Layout) ConfigFile() string { return filepath.Join(l.Config, configFileName) }
+
+// VersionsDir returns the directory JDKs are installed into.
+func (l Layout) VersionsDir() string { return filepath.Join(l.Data, versionsDirName) }
+
+// CurrentLink returns the path of the symlink pointing at the selected JDK.
+func (l Layout) CurrentLink() string { return filepath.Join(l.Data, currentLinkName) }
+
+// CertsDir returns the directory holding the certificates 'jswitch certs' adds to every JDK.
+func (l Layout) CertsDir() string { return filepath.Join(l.Config, certsDirName) }
+
+// HistoryFile returns the path of history.json.
+func (l Layout) HistoryFile() string { return filepath.Join(l.State, historyFileName) }
+
+// flat keeps everything under a single root, as ~/.jswitch always has.
+func flat(root string) Layout {
+	return Layout{Config: root, Data: root, Cache: filepath.Join(root, cacheDirName), State: root}
+}
+
+// Current returns the layout in effect.
+func Current() (Layout, error) {
+	if root := os.Getenv(EnvHome); root != "" {
+		abs, err := filepath.Abs(root)
+		if err != nil {
+			return Layout{}, fmt.Errorf("invalid %s: %w", EnvHome, err)
+		}
+		return flat(abs), nil
+	}
+
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return Layout{}, fmt.Errorf("could not find user home directory: %w", err)
+	}
+	native := nativeLayout(home)
+
+	// Until it has been migrated, the legacy directory wins over an empty native layout.
+	legacy := flat(filepath.Join(home, legacyDirName))
+	if legacyInUse(legacy) && !exists(native.ConfigFile()) {
+		return legacy, nil
+	}
+	return native, nil
+}
+
+// System returns the layout of the shared installation root used by every user of the
+// machine: $JSWITCH_SYSTEM_HOME, %ProgramData%\jswitch on Windows, or /opt/jswitch.
+// Everything lives directly under the root.
+func System() (Layout, error) {
+	root := os.Getenv(EnvSystemHome)
+	switch {
+	case root != "":
+		abs, err := filepath.Abs(root)
+		if err != nil {
+			return Layout{}, fmt.Errorf("invalid %s: %w", EnvSystemHome, err)
+		}
+		root = abs
+	case runtime.GOOS == "windows":
+		base := os.Getenv("ProgramData")
+		if base == "" {
+			base = `C:\ProgramData`
+		}
+		root = filepath.Join(base, appName)
+	default:
+		root = filepath.Join("/opt", appName)
+	}
+	return flat(root), nil
+}
+
+// nativeLayout returns the platform's preferred layout, ignoring $JSWITCH_HOME and ~/.jswitch.
+func nativeLayout(home string) Layout {
+	switch runtime.GOOS {
+	case "windows":
+		base := os.Getenv("LOCALAPPDATA")
+		if base == "" {
+			base = filepath.Join(home, "AppData", "Local")
+		}
+		return flat(filepath.Join(base, appName))
+	case "darwin":
+		return flat(filepath.Join(home, legacyDirName))
+	default:
+		return Layout{
+			Config: filepath.Join(xdgDir("XDG_CONFIG_HOME", home, ".config"), appName),
+			Data:   filepath.Join(xdgDir("XDG_DATA_HOME", home, ".local", "share"), appName),
+			Cache:  filepath.Join(xdgDir("XDG_CACHE_HOME", home, ".cache"), appName),
+			State:  filepath.Join(xdgDir("XDG_STATE_HOME", home, ".local", "state"), appName),
+		}
+	}
+}
+
+// xdgDir returns $env, or home/fallback when it is unset or relative (as the XDG spec requires).
+func xdgDir(env, home string, fallback ...string) string {
+	if dir := os.Getenv(env); filepath.IsAbs(dir) {
+		return dir
+	}
+	return filepath.Join(append([]string{home}, fallback...)...)
+}
+
+// legacyInUse reports whether a ~/.jswitch directory holds jswitch state.
+func legacyInUse(l Layout) bool {
+	if exists(l.ConfigFile()) || exists(l.VersionsDir()) {
+		return true
+	}
+	_, err := os.Lstat(l.CurrentLink())
+	return err == nil
+}
+
+func exists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
+
+// ConfigFile returns the path of config.json in the current layout.
+func ConfigFile() (string, error) {
+	l, err := Current()
+	if err != nil {
+		return "", err
+	}
+	return l.ConfigFile(), nil
+}
+
+// VersionsDir returns the directory JDKs are installed into.
+func VersionsDir() (string, error) {
+	l, err := Current()
+	if err != nil {
+		return "", err
+	}
+	return l.VersionsDir(), nil
+}
+
+// CurrentLink returns the path of the symlink pointing at the selected JDK.
+func CurrentLink() (string, error) {
+	l, err := Current()
+	if err != nil {
+		return "", err
+	}
+	return l.CurrentLink(), nil
+}
+
+// CacheDir returns the cache directory.
+func CacheDir() (string, error) {
+	l, err := Current()
+	if err != nil {
+		return "", err
+	}
+	return l.Cache, nil
+}
+
+// HistoryFile returns the path of history.json.
+func HistoryFile() (string, error) {
+	l, err := Current()
+	if err != nil {
+		return "", err
+	}
+	return l.HistoryFile(), nil
+}
+
+// CertsDir returns the directory of extra trusted certificates.
+func CertsDir() (string, error) {
+	l, err := Current()
+	if err != nil {
+		return "", err
+	}
+	return l.CertsDir(), nil
+}
@@ -0,0 +1,167 @@
+// Package prune decides which installed JDKs a retention policy removes, and finds what
+// interrupted downloads, installs and dedupe runs left behind.
+package prune
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/user/jswitch/pkg/fetcher"
+	"github.com/user/jswitch/pkg/models"
+)
+
+// Decision is what the policy does with an installation.
+type Decision struct {
+	Installation models.JavaInstallation
+	Remove       bool
+	// Reason says why an installation the policy would remove is kept; empty otherwise.
+	Reason string
+}
+
+// Options is the retention policy Plan applies.
+type Options struct {
+	// Keep is the number of builds kept for every vendor and feature release.
+	Keep int
+	// Protected gives, by path, a reason to keep an installation the policy would remove.
+	Protected map[string]string
+	// System reports whether an installation belongs to the system config. Those are left to
+	// the administrator: they get no decision and do not count towards Keep.
+	System func(models.JavaInstallation) bool
+}
+
+// Plan applies the retention policy to insts: for every vendor and feature release, the
+// newest opts.Keep builds stay and older ones are removed, unless opts.Protected gives a
+// reason to keep them. Decisions are returned for the installations beyond the newest Keep
+// of their group, newest first within a group.
+func Plan(insts []models.JavaInstallation, opts Options) []Decision {
+	type key struct {
+		vendor string
+		major  int
+	}
+	groups := make(map[key][]models.JavaInstallation)
+	var order []key
+	for _, inst := range insts {
+		if opts.System != nil && opts.System(inst) {
+			continue
+		}
+		k := key{inst.Vendor, inst.MajorVersion}
+		if _, ok := groups[k]; !ok {
+			order = append(order, k)
+		}
+		groups[k] = append(groups[k], inst)
+	}
+
+	var decisions []Decision
+	for _, k := range order {
+		group := groups[k]
+		sort.SliceStable(group, func(i, j int) bool {
+			return models.CompareVersions(group[i].Version, group[j].Version) > 0
+		})
+		for _, inst := range group[min(opts.Keep, len(group)):] {
+			reason, ok := opts.Protected[inst.Path]
+			decisions = append(decisions, Decision{Installation: inst, Remove: !ok, Reason: reason})
+		}
+	}
+	return decisions
+}
+
+// Kinds of leftovers.
+const (
+	KindDownload = "partial download"
+	KindStaging  = "staging directory"
+	KindDedupe   = "dedupe leftover"
+)
+
+// Leftover is a file or directory an interrupted operation left behind.
+type Leftover struct {
+	Path string
+	Kind string
+	Size int64
+}
+
+// Leftovers finds, among the entries not modified within minAge:
+//   - downloaded archives in tempDir (jdk-download-*), which are deleted after extraction;
+//   - directories in installDir that still hold the fetcher's staging marker, left by an
+//     extraction that failed or was interrupted, unless registered claims them or they hold a
+//     complete JDK (bin/java and a release file);
+//   - temporary links in installDir left by an interrupted 'jswitch dedupe'.
+//
+// registered reports whether a directory is, or contains, a registered installation.
+func Leftovers(installDir, tempDir string, registered func(dir string) bool, minAge time.Duration) ([]Leftover, error) {
+	cutoff := time.Now().Add(-minAge)
+	var found []Leftover
+
+	downloads, _ := filepath.Glob(filepath.Join(tempDir, "jdk-download-*"))
+	for _, path := range downloads {
+		if info, err := os.Lstat(path); err == nil && info.Mode().IsRegular() && info.ModTime().Before(cutoff) {
+			found = append(found, Leftover{Path: path, Kind: KindDownload, Size: info.Size()})
+		}
+	}
+
+	entries, err := os.ReadDir(installDir)
+	if err != nil && !os.IsNotExist(err) {
+		return found, err
+	}
+	for _, e := range entries {
+		path := filepath.Join(installDir, e.Name())
+		if !e.IsDir() || !exists(filepath.Join(path, fetcher.StagingMarker)) || isJDK(path) || registered(path) {
+			continue
+		}
+		size, newest := measure(path)
+		if newest.Before(cutoff) {
+			found = append(found, Leftover{Path: path, Kind: KindStaging, Size: size})
+		}
+	}
+
+	err = filepath.WalkDir(installDir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() || !strings.HasSuffix(path, ".jswitch-dedupe") {
+			return nil
+		}
+		if info, err := d.Info(); err == nil && info.ModTime().Before(cutoff) {
+			found = append(found, Leftover{Path: path, Kind: KindDedupe, Size: info.Size()})
+		}
+		return nil
+	})
+	return found, err
+}
+
+// measure returns the size of the files under dir and the newest modification time in it.
+func measure(dir string) (int64, time.Time) {
+	var size int64
+	var newest time.Time
+	filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return nil
+		}
+		if info.Mode().IsRegular() {
+			size += info.Size()
+		}
+		if info.ModTime().After(newest) {
+			newest = info.ModTime()
+		}
+		return nil
+	})
+	return size, newest
+}
+
+// isJDK reports whether dir holds a complete JDK or JRE, which prune never removes as a leftover.
+func isJDK(dir string) bool {
+	if home := filepath.Join(dir, "Contents", "Home"); exists(home) {
+		dir = home
+	}
+	hasJava := exists(filepath.Join(dir, "bin", "java")) || exists(filepath.Join(dir, "bin", "java.exe"))
+	return hasJava && exists(filepath.Join(dir, "release"))
+}
+
+func exists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
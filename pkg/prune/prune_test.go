@@ -0,0 +1,198 @@
+package prune
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/user/jswitch/pkg/fetcher"
+	"github.com/user/jswitch/pkg/models"
+)
+
+func inst(vendor, version string) models.JavaInstallation {
+	return models.JavaInstallation{
+		Version:      version,
+		MajorVersion: models.ParseMajorVersion(version),
+		Vendor:       vendor,
+		Path:         "/jdks/" + vendor + "-" + version,
+	}
+}
+
+func TestPlan(t *testing.T) {
+	installed := []models.JavaInstallation{
+		inst("Temurin", "17.0.9"),
+		inst("Temurin", "17.0.10"),
+		inst("Temurin", "17.0.2"),
+		inst("Temurin", "21.0.1"),
+		inst("Corretto", "17.0.8"),
+		inst("Corretto", "17.0.7"),
+	}
+	system := inst("Temurin", "17.0.1")
+	isSystem := func(i models.JavaInstallation) bool { return i == system }
+
+	tests := []struct {
+		name       string
+		insts      []models.JavaInstallation
+		opts       Options
+		wantRemove []string
+		wantKeep   map[string]string
+	}{
+		{
+			name:       "newest per vendor and feature release",
+			insts:      installed,
+			opts:       Options{Keep: 1},
+			wantRemove: []string{"Temurin-17.0.9", "Temurin-17.0.2", "Corretto-17.0.7"},
+		},
+		{
+			name:       "keep two",
+			insts:      installed,
+			opts:       Options{Keep: 2},
+			wantRemove: []string{"Temurin-17.0.2"},
+		},
+		{
+			name:  "protected",
+			insts: installed,
+			opts: Options{Keep: 1, Protected: map[string]string{
+				"/jdks/Temurin-17.0.2": "it is pinned",
+				"/jdks/Temurin-21.0.1": "it is the current version",
+			}},
+			wantRemove: []string{"Temurin-17.0.9", "Corretto-17.0.7"},
+			wantKeep:   map[string]string{"Temurin-17.0.2": "it is pinned"},
+		},
+		{
+			name:       "system installations skipped",
+			insts:      append(slices.Clone(installed), system),
+			opts:       Options{Keep: 1, System: isSystem},
+			wantRemove: []string{"Temurin-17.0.9", "Temurin-17.0.2", "Corretto-17.0.7"},
+		},
+		{
+			name:  "system installations do not count towards keep",
+			insts: []models.JavaInstallation{system, inst("Temurin", "17.0.0")},
+			opts:  Options{Keep: 1, System: isSystem},
+		},
+		{
+			name:  "fewer than keep",
+			insts: installed[3:4],
+			opts:  Options{Keep: 3},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var remove []string
+			keep := make(map[string]string)
+			for _, d := range Plan(tt.insts, tt.opts) {
+				name := d.Installation.Vendor + "-" + d.Installation.Version
+				if isSystem(d.Installation) {
+					t.Errorf("decision for the system installation %s", name)
+				}
+				if _, ok := tt.opts.Protected[d.Installation.Path]; ok && d.Remove {
+					t.Errorf("protected %s is removed", name)
+				}
+				if d.Remove {
+					remove = append(remove, name)
+				} else {
+					keep[name] = d.Reason
+				}
+			}
+			if !slices.Equal(remove, tt.wantRemove) {
+				t.Errorf("removed %q, want %q", remove, tt.wantRemove)
+			}
+			if len(keep) != len(tt.wantKeep) {
+				t.Errorf("kept %v, want %v", keep, tt.wantKeep)
+			}
+			for name, reason := range tt.wantKeep {
+				if keep[name] != reason {
+					t.Errorf("%s kept because %q, want %q", name, keep[name], reason)
+				}
+			}
+		})
+	}
+}
+
+// mkfile creates path with content and sets its modification time.
+func mkfile(t *testing.T, path, content string, mtime time.Time) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chtimes(path, mtime, mtime); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestLeftovers(t *testing.T) {
+	dir := t.TempDir()
+	installDir, tempDir := filepath.Join(dir, "jdks"), filepath.Join(dir, "tmp")
+	old, recent := time.Now().Add(-2*time.Hour), time.Now()
+	marker := fetcher.StagingMarker
+
+	tests := []struct {
+		path  string
+		stale bool
+		kind  string
+	}{
+		// Interrupted extractions still hold the staging marker.
+		{path: "jdks/failed/" + marker, stale: true, kind: KindStaging},
+		{path: "jdks/extracting/" + marker, kind: ""},
+		// A directory without the marker is not jswitch's.
+		{path: "jdks/hand-made/bin/java", stale: true},
+		// A registered installation with a marker left over is kept.
+		{path: "jdks/registered/" + marker, stale: true},
+		// So is a complete JDK.
+		{path: "jdks/complete/" + marker, stale: true},
+		{path: "jdks/complete/bin/java", stale: true},
+		{path: "jdks/complete/release", stale: true},
+		{path: "tmp/jdk-download-123", stale: true, kind: KindDownload},
+		{path: "tmp/jdk-download-456", kind: ""},
+		{path: "tmp/other-download", stale: true},
+		{path: "jdks/jdk-17/lib/modules.jswitch-dedupe", stale: true, kind: KindDedupe},
+	}
+	var want []string
+	for _, tt := range tests {
+		mtime := recent
+		if tt.stale {
+			mtime = old
+		}
+		mkfile(t, filepath.Join(dir, filepath.FromSlash(tt.path)), "data", mtime)
+		if tt.kind != "" {
+			want = append(want, tt.kind+" "+filepath.Dir(tt.path))
+		}
+	}
+
+	// Only the files' times count: back-date the directories created along the way.
+	filepath.WalkDir(installDir, func(path string, d fs.DirEntry, err error) error {
+		if err == nil && d.IsDir() {
+			os.Chtimes(path, old, old)
+		}
+		return nil
+	})
+
+	registered := func(path string) bool { return filepath.Base(path) == "registered" }
+	found, err := Leftovers(installDir, tempDir, registered, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, l := range found {
+		rel, _ := filepath.Rel(dir, l.Path)
+		if l.Kind != KindStaging {
+			rel = filepath.Dir(rel)
+		}
+		got = append(got, l.Kind+" "+filepath.ToSlash(rel))
+		if l.Size != int64(len("data")) {
+			t.Errorf("%s has size %d", l.Path, l.Size)
+		}
+	}
+	slices.Sort(got)
+	slices.Sort(want)
+	if !slices.Equal(got, want) {
+		t.Errorf("leftovers:\n got  %q\n want %q", got, want)
+	}
+}
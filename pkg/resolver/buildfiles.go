@@ -0,0 +1,267 @@
+package resolver
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/user/jswitch/pkg/models"
+	"gopkg.in/yaml.v3"
+)
+
+// Requirement is a Java version a build file asks for.
+type Requirement struct {
+	Major int
+	// File is the build file the requirement was read from.
+	File string
+	// Detail names the declaration, e.g. "<maven.compiler.release>".
+	Detail string
+}
+
+// Spec returns the requirement as a version specifier (the major version).
+func (r Requirement) Spec() string {
+	return strconv.Itoa(r.Major)
+}
+
+func (r Requirement) String() string {
+	return fmt.Sprintf("Java %d from %s (%s)", r.Major, r.File, r.Detail)
+}
+
+// buildFileDetectors are tried in order; the first requirement found in a directory wins.
+var buildFileDetectors = []func(dir string) []Requirement{
+	detectMaven,
+	detectGradle,
+	detectDockerfile,
+	detectWorkflows,
+}
+
+// DetectBuildFiles returns the Java versions required by the build files in dir (not its
+// parents): pom.xml, build.gradle(.kts), Dockerfile and GitHub workflows using setup-java.
+// The first entry is the one jswitch uses; later ones are reported for information.
+func DetectBuildFiles(dir string) []Requirement {
+	var reqs []Requirement
+	for _, detect := range buildFileDetectors {
+		reqs = append(reqs, detect(dir)...)
+	}
+	return reqs
+}
+
+// parseMajor turns a version as written in a build file ("17", "1.8", "21.0.2", 11) into a major version.
+func parseMajor(s string) int {
+	s = strings.Trim(strings.TrimSpace(s), `"'`)
+	if s == "" || s[0] < '0' || s[0] > '9' {
+		return 0
+	}
+	return models.ParseMajorVersion(s)
+}
+
+func detectMaven(dir string) []Requirement {
+	file := filepath.Join(dir, "pom.xml")
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil
+	}
+
+	props := make(map[string]string)
+	plugin := make(map[string]string)
+	dec := xml.NewDecoder(bytes.NewReader(data))
+	dec.Strict = false
+	var path []string
+	var text strings.Builder
+	for {
+		tok, err := dec.Token()
+		if err != nil {
+			break
+		}
+		switch t := tok.(type) {
+		case xml.StartElement:
+			path = append(path, t.Name.Local)
+			text.Reset()
+		case xml.CharData:
+			text.Write(t)
+		case xml.EndElement:
+			value := strings.TrimSpace(text.String())
+			switch n := len(path); {
+			case n == 3 && path[0] == "project" && path[1] == "properties":
+				props[path[2]] = value
+			case n >= 2 && path[n-2] == "configuration":
+				// maven-compiler-plugin <configuration><release>17</release>
+				if _, seen := plugin[path[n-1]]; !seen {
+					plugin[path[n-1]] = value
+				}
+			}
+			path = path[:len(path)-1]
+			text.Reset()
+		}
+	}
+	expand := func(v string) string {
+		for i := 0; i < 5 && strings.HasPrefix(v, "${") && strings.HasSuffix(v, "}"); i++ {
+			v = props[v[2:len(v)-1]]
+		}
+		return v
+	}
+
+	// In order of preference: the release is what the JDK must support.
+	var reqs []Requirement
+	add := func(value, detail string) {
+		if major := parseMajor(expand(value)); major > 0 {
+			reqs = append(reqs, Requirement{Major: major, File: file, Detail: detail})
+		}
+	}
+	add(props["maven.compiler.release"], "<maven.compiler.release>")
+	add(plugin["release"], "maven-compiler-plugin <release>")
+	add(props["java.version"], "<java.version>")
+	add(props["maven.compiler.target"], "<maven.compiler.target>")
+	add(plugin["target"], "maven-compiler-plugin <target>")
+	add(props["maven.compiler.source"], "<maven.compiler.source>")
+	add(plugin["source"], "maven-compiler-plugin <source>")
+	return reqs
+}
+
+var gradlePatterns = []struct {
+	re     *regexp.Regexp
+	detail string
+}{
+	{regexp.MustCompile(`JavaLanguageVersion\.of\(\s*["']?(\d+)["']?\s*\)`), "JavaLanguageVersion.of(%s)"},
+	{regexp.MustCompile(`jvmToolchain\(\s*(\d+)\s*\)`), "jvmToolchain(%s)"},
+	{regexp.MustCompile(`(?:targetCompatibility|sourceCompatibility)\s*=\s*(?:JavaVersion\.VERSION_|["']?)(1[._]\d+|\d+)`), "%s compatibility"},
+}
+
+func detectGradle(dir string) []Requirement {
+	var reqs []Requirement
+	for _, name := range []string{"build.gradle.kts", "build.gradle"} {
+		file := filepath.Join(dir, name)
+		data, err := os.ReadFile(file)
+		if err != nil {
+			continue
+		}
+		src := stripLineComments(string(data))
+		for _, p := range gradlePatterns {
+			if m := p.re.FindStringSubmatch(src); m != nil {
+				if major := parseMajor(strings.ReplaceAll(m[1], "_", ".")); major > 0 {
+					reqs = append(reqs, Requirement{Major: major, File: file, Detail: fmt.Sprintf(p.detail, m[1])})
+				}
+			}
+		}
+	}
+	return reqs
+}
+
+// stripLineComments drops // comments so commented-out toolchains are not picked up.
+func stripLineComments(src string) string {
+	lines := strings.Split(src, "\n")
+	for i, line := range lines {
+		if strings.HasPrefix(strings.TrimSpace(line), "//") {
+			lines[i] = ""
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+var (
+	dockerFrom = regexp.MustCompile(`(?im)^\s*FROM\s+(?:--\S+\s+)*(\S+)`)
+	// JDK images whose tag starts with the Java version, e.g. eclipse-temurin:17-jdk.
+	dockerJDKImages  = regexp.MustCompile(`(?:^|/)(?:eclipse-temurin|openjdk|amazoncorretto|zulu-openjdk[-\w]*|ibm-semeru-runtimes|sapmachine|liberica-openjdk[-\w]*|graalvm-ce|microsoft/openjdk/jdk|jdk|java)$`)
+	dockerTagVersion = regexp.MustCompile(`^(\d+)`)
+	// Build tool images name the JDK inside the tag, e.g. maven:3.9-eclipse-temurin-17.
+	dockerToolImages = regexp.MustCompile(`(?:^|/)(?:maven|gradle)$`)
+	dockerToolTag    = regexp.MustCompile(`(?:jdk|temurin|corretto|openjdk|zulu|sapmachine|graal|liberica|semeru)-?(\d+)`)
+)
+
+func detectDockerfile(dir string) []Requirement {
+	file := filepath.Join(dir, "Dockerfile")
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil
+	}
+	// The first Java stage is usually the one that builds the project.
+	for _, m := range dockerFrom.FindAllStringSubmatch(string(data), -1) {
+		ref := m[1]
+		if i := strings.Index(ref, "@"); i >= 0 {
+			ref = ref[:i]
+		}
+		// A colon before the last slash belongs to a registry port, not a tag.
+		i := strings.LastIndex(ref, ":")
+		if i < 0 || i < strings.LastIndex(ref, "/") {
+			continue
+		}
+		image, tag := ref[:i], ref[i+1:]
+		var v []string
+		switch {
+		case dockerJDKImages.MatchString(image):
+			v = dockerTagVersion.FindStringSubmatch(tag)
+		case dockerToolImages.MatchString(image):
+			v = dockerToolTag.FindStringSubmatch(tag)
+		}
+		if v != nil {
+			if major := models.ParseMajorVersion(v[1]); major > 0 {
+				return []Requirement{{Major: major, File: file, Detail: "FROM " + m[1]}}
+			}
+		}
+	}
+	return nil
+}
+
+// workflow is the part of a GitHub Actions workflow that sets up Java.
+type workflow struct {
+	Jobs map[string]struct {
+		Steps []struct {
+			Uses string `yaml:"uses"`
+			With struct {
+				JavaVersion yaml.Node `yaml:"java-version"`
+			} `yaml:"with"`
+		} `yaml:"steps"`
+	} `yaml:"jobs"`
+}
+
+func detectWorkflows(dir string) []Requirement {
+	var files []string
+	for _, ext := range []string{"*.yml", "*.yaml"} {
+		matches, _ := filepath.Glob(filepath.Join(dir, ".github", "workflows", ext))
+		files = append(files, matches...)
+	}
+	sort.Strings(files)
+
+	var reqs []Requirement
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			continue
+		}
+		var wf workflow
+		if yaml.Unmarshal(data, &wf) != nil {
+			continue
+		}
+		jobs := make([]string, 0, len(wf.Jobs))
+		for name := range wf.Jobs {
+			jobs = append(jobs, name)
+		}
+		sort.Strings(jobs)
+		for _, name := range jobs {
+			for _, step := range wf.Jobs[name].Steps {
+				if !strings.HasPrefix(step.Uses, "actions/setup-java@") {
+					continue
+				}
+				// Several versions may be listed one per line; setup-java makes the last one the default.
+				var value string
+				if step.With.JavaVersion.Decode(&value) != nil || strings.Contains(value, "${{") {
+					continue
+				}
+				fields := strings.Fields(value)
+				if len(fields) == 0 {
+					continue
+				}
+				if major := parseMajor(fields[len(fields)-1]); major > 0 {
+					reqs = append(reqs, Requirement{Major: major, File: file, Detail: fmt.Sprintf("setup-java java-version %s in job %s", fields[len(fields)-1], name)})
+				}
+			}
+		}
+	}
+	return reqs
+}
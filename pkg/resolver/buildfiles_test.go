@@ -0,0 +1,242 @@
+package resolver
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// buildFileTest is a build file, and the major version and declaration detected first.
+type buildFileTest struct {
+	name       string
+	content    string
+	wantMajor  int
+	wantDetail string
+}
+
+func runBuildFileTests(t *testing.T, file string, detect func(dir string) []Requirement, tests []buildFileTest) {
+	t.Helper()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			path := filepath.Join(dir, filepath.FromSlash(file))
+			if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+				t.Fatal(err)
+			}
+			if err := os.WriteFile(path, []byte(tt.content), 0644); err != nil {
+				t.Fatal(err)
+			}
+			reqs := detect(dir)
+			if tt.wantMajor == 0 {
+				if len(reqs) > 0 {
+					t.Errorf("detected %v, want nothing", reqs)
+				}
+				return
+			}
+			if len(reqs) == 0 {
+				t.Fatalf("detected nothing, want Java %d", tt.wantMajor)
+			}
+			if reqs[0].Major != tt.wantMajor || reqs[0].Detail != tt.wantDetail {
+				t.Errorf("detected Java %d (%s), want Java %d (%s)", reqs[0].Major, reqs[0].Detail, tt.wantMajor, tt.wantDetail)
+			}
+			if reqs[0].File != path {
+				t.Errorf("file = %s, want %s", reqs[0].File, path)
+			}
+		})
+	}
+}
+
+func TestDetectMaven(t *testing.T) {
+	runBuildFileTests(t, "pom.xml", detectMaven, []buildFileTest{
+		{
+			name: "java.version property",
+			content: `<project><properties>
+				<java.version>21</java.version>
+				<maven.compiler.release>${java.version}</maven.compiler.release>
+			</properties></project>`,
+			wantMajor:  21,
+			wantDetail: "<maven.compiler.release>",
+		},
+		{
+			name: "nested properties",
+			content: `<project><properties>
+				<jdk>11</jdk>
+				<java.version>${jdk}</java.version>
+			</properties></project>`,
+			wantMajor:  11,
+			wantDetail: "<java.version>",
+		},
+		{
+			name: "release before source",
+			content: `<project><properties>
+				<maven.compiler.source>11</maven.compiler.source>
+				<maven.compiler.release>17</maven.compiler.release>
+			</properties></project>`,
+			wantMajor:  17,
+			wantDetail: "<maven.compiler.release>",
+		},
+		{
+			name: "legacy source",
+			content: `<project><properties>
+				<maven.compiler.source>1.8</maven.compiler.source>
+			</properties></project>`,
+			wantMajor:  8,
+			wantDetail: "<maven.compiler.source>",
+		},
+		{
+			name: "compiler plugin",
+			content: `<project><build><plugins><plugin>
+				<artifactId>maven-compiler-plugin</artifactId>
+				<configuration><release>17</release></configuration>
+			</plugin></plugins></build></project>`,
+			wantMajor:  17,
+			wantDetail: "maven-compiler-plugin <release>",
+		},
+		{
+			name:    "undefined property",
+			content: `<project><properties><maven.compiler.release>${missing}</maven.compiler.release></properties></project>`,
+		},
+	})
+}
+
+func TestDetectGradle(t *testing.T) {
+	groovy := []buildFileTest{
+		{
+			name:       "toolchain",
+			content:    "java {\n    toolchain {\n        languageVersion = JavaLanguageVersion.of(17)\n    }\n}\n",
+			wantMajor:  17,
+			wantDetail: "JavaLanguageVersion.of(17)",
+		},
+		{
+			name:       "commented-out toolchain",
+			content:    "java {\n    // languageVersion = JavaLanguageVersion.of(11)\n    languageVersion = JavaLanguageVersion.of(21)\n}\n",
+			wantMajor:  21,
+			wantDetail: "JavaLanguageVersion.of(21)",
+		},
+		{
+			name:    "only commented out",
+			content: "// sourceCompatibility = '11'\n",
+		},
+		{
+			name:       "JavaVersion constant",
+			content:    "sourceCompatibility = JavaVersion.VERSION_1_8\ntargetCompatibility = JavaVersion.VERSION_1_8\n",
+			wantMajor:  8,
+			wantDetail: "1_8 compatibility",
+		},
+		{
+			name:       "quoted compatibility",
+			content:    "sourceCompatibility = '11'\n",
+			wantMajor:  11,
+			wantDetail: "11 compatibility",
+		},
+	}
+	kotlin := []buildFileTest{
+		{
+			name:       "toolchain",
+			content:    "java {\n    toolchain {\n        languageVersion.set(JavaLanguageVersion.of(\"21\"))\n    }\n}\n",
+			wantMajor:  21,
+			wantDetail: "JavaLanguageVersion.of(21)",
+		},
+		{
+			name:       "jvmToolchain",
+			content:    "kotlin {\n    jvmToolchain(17) // was 11\n}\n",
+			wantMajor:  17,
+			wantDetail: "jvmToolchain(17)",
+		},
+		{
+			name:       "JavaVersion constant",
+			content:    "java {\n    sourceCompatibility = JavaVersion.VERSION_11\n}\n",
+			wantMajor:  11,
+			wantDetail: "11 compatibility",
+		},
+	}
+	t.Run("groovy", func(t *testing.T) { runBuildFileTests(t, "build.gradle", detectGradle, groovy) })
+	t.Run("kotlin", func(t *testing.T) { runBuildFileTests(t, "build.gradle.kts", detectGradle, kotlin) })
+}
+
+func TestDetectDockerfile(t *testing.T) {
+	runBuildFileTests(t, "Dockerfile", detectDockerfile, []buildFileTest{
+		{
+			name:       "tag",
+			content:    "FROM eclipse-temurin:21-jdk\n",
+			wantMajor:  21,
+			wantDetail: "FROM eclipse-temurin:21-jdk",
+		},
+		{
+			name:       "registry port",
+			content:    "FROM registry.example.com:5000/library/openjdk:17-slim\n",
+			wantMajor:  17,
+			wantDetail: "FROM registry.example.com:5000/library/openjdk:17-slim",
+		},
+		{
+			name:    "registry port without tag",
+			content: "FROM localhost:5000/eclipse-temurin\n",
+		},
+		{
+			name:       "digest",
+			content:    "FROM amazoncorretto:11@sha256:0123456789abcdef\n",
+			wantMajor:  11,
+			wantDetail: "FROM amazoncorretto:11@sha256:0123456789abcdef",
+		},
+		{
+			name:    "digest without tag",
+			content: "FROM eclipse-temurin@sha256:0123456789abcdef\n",
+		},
+		{
+			name:       "multi-stage",
+			content:    "FROM node:20 AS web\nFROM --platform=$BUILDPLATFORM gradle:8.5-jdk21 AS build\nFROM eclipse-temurin:17-jre\n",
+			wantMajor:  21,
+			wantDetail: "FROM gradle:8.5-jdk21",
+		},
+		{
+			name:       "maven image",
+			content:    "from maven:3.9-eclipse-temurin-17 as build\n",
+			wantMajor:  17,
+			wantDetail: "FROM maven:3.9-eclipse-temurin-17",
+		},
+		{
+			name:    "no Java",
+			content: "FROM golang:1.24\n",
+		},
+	})
+}
+
+func TestDetectWorkflows(t *testing.T) {
+	workflow := func(with string) string {
+		return "jobs:\n  build:\n    steps:\n      - uses: actions/checkout@v4\n      - uses: actions/setup-java@v4\n        with:\n          distribution: temurin\n" + with
+	}
+	runBuildFileTests(t, ".github/workflows/ci.yml", detectWorkflows, []buildFileTest{
+		{
+			name:       "scalar",
+			content:    workflow("          java-version: 17\n"),
+			wantMajor:  17,
+			wantDetail: "setup-java java-version 17 in job build",
+		},
+		{
+			name:       "quoted",
+			content:    workflow("          java-version: '21'\n"),
+			wantMajor:  21,
+			wantDetail: "setup-java java-version 21 in job build",
+		},
+		{
+			name:       "legacy version",
+			content:    workflow("          java-version: \"1.8\"\n"),
+			wantMajor:  8,
+			wantDetail: "setup-java java-version 1.8 in job build",
+		},
+		{
+			name:       "list",
+			content:    workflow("          java-version: |\n            11\n            21\n"),
+			wantMajor:  21,
+			wantDetail: "setup-java java-version 21 in job build",
+		},
+		{
+			name:    "matrix",
+			content: "jobs:\n  build:\n    strategy:\n      matrix:\n        java: [11, 17]\n    steps:\n      - uses: actions/setup-java@v4\n        with:\n          java-version: ${{ matrix.java }}\n",
+		},
+		{
+			name:    "other action",
+			content: "jobs:\n  build:\n    steps:\n      - uses: actions/setup-node@v4\n        with:\n          java-version: 17\n",
+		},
+	})
+}
@@ -0,0 +1,274 @@
+package resolver
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+
+	"github.com/user/jswitch/pkg/config"
+	"github.com/user/jswitch/pkg/lockfile"
+	"github.com/user/jswitch/pkg/models"
+)
+
+const (
+	// EnvVar overrides every other source when set.
+	EnvVar = "JSWITCH_VERSION"
+	// ProjectFileName is looked up in the working directory and its parents.
+	ProjectFileName = ".java-version"
+)
+
+// Source says where the active version came from.
+type Source string
+
+const (
+	SourceEnv     Source = "env"
+	SourceProject Source = "project"
+	SourceGlobal  Source = "global"
+	SourceDefault Source = "default"
+)
+
+// ErrNoVersion is returned when no source selects a version.
+var ErrNoVersion = errors.New("no Java version selected")
+
+// Resolution is the active installation together with the reason it was chosen.
+type Resolution struct {
+	Installation models.JavaInstallation
+	Source       Source
+	// Spec is the version specifier as written in the source.
+	Spec string
+	// Origin is the file or variable the spec was read from, if any.
+	Origin string
+	// Alias is set when Spec named an alias.
+	Alias string
+	// Detail names the declaration in a build file the spec was inferred from.
+	Detail string
+}
+
+// Reason describes the resolution in a sentence fragment, e.g. "set by /repo/.java-version".
+func (r Resolution) Reason() string {
+	var reason string
+	switch r.Source {
+	case SourceEnv:
+		reason = fmt.Sprintf("set by $%s", r.Origin)
+	case SourceProject:
+		reason = fmt.Sprintf("set by %s", r.Origin)
+		if r.Detail != "" {
+			reason = fmt.Sprintf("required by %s (%s)", r.Origin, r.Detail)
+		}
+	case SourceGlobal:
+		reason = "selected globally with 'jswitch use'"
+	case SourceDefault:
+		reason = "default alias"
+	}
+	if r.Alias != "" && r.Source != SourceDefault {
+		reason += fmt.Sprintf(" via alias %q", r.Alias)
+	}
+	return reason
+}
+
+// Active resolves the version that applies in dir.
+// Precedence: $JSWITCH_VERSION, the nearest jswitch.lock, .java-version or build file (with
+// the detect-build-files setting), the global selection, the default alias.
+func Active(cfg *config.Config, dir string) (Resolution, error) {
+	if spec := strings.TrimSpace(os.Getenv(EnvVar)); spec != "" {
+		return resolve(cfg, spec, SourceEnv, EnvVar)
+	}
+
+	if pv, ok := FindProject(dir, cfg.SettingBool(config.SettingDetectBuildFiles)); ok {
+		if pv.Lock != nil {
+			return resolveLock(cfg, pv)
+		}
+		r, err := resolve(cfg, pv.Spec, SourceProject, pv.File)
+		r.Detail = pv.Detail
+		return r, err
+	}
+
+	if cfg.CurrentVersion != "" {
+		if r, err := resolve(cfg, cfg.CurrentVersion, SourceGlobal, ""); err == nil {
+			return r, nil
+		}
+	}
+
+	if _, ok := cfg.Aliases[config.DefaultAlias]; ok {
+		return resolve(cfg, config.DefaultAlias, SourceDefault, "")
+	}
+
+	return Resolution{}, ErrNoVersion
+}
+
+func resolve(cfg *config.Config, spec string, source Source, origin string) (Resolution, error) {
+	inst, err := cfg.Resolve(spec)
+	if err != nil {
+		if origin != "" {
+			return Resolution{}, fmt.Errorf("%w (from %s)", err, origin)
+		}
+		return Resolution{}, err
+	}
+
+	r := Resolution{Installation: inst, Source: source, Spec: spec, Origin: origin}
+	if _, ok := cfg.Aliases[spec]; ok {
+		r.Alias = spec
+	}
+	return r, nil
+}
+
+// resolveLock resolves the exact build a jswitch.lock pins: its version, vendor and image type.
+func resolveLock(cfg *config.Config, pv ProjectVersion) (Resolution, error) {
+	l := pv.Lock
+	inst, ok := cfg.FindBuild(l.Version, l.Vendor, l.ImageType)
+	if !ok {
+		err := &config.NotFoundError{Spec: l.Version, Reason: fmt.Sprintf("the locked build (%s %s %s) is not installed", l.Vendor, l.ImageType, l.Version)}
+		return Resolution{}, fmt.Errorf("%w (from %s); run 'jswitch sync' to install it", err, pv.File)
+	}
+	return Resolution{Installation: inst, Source: SourceProject, Spec: pv.Spec, Origin: pv.File}, nil
+}
+
+// ProjectVersion is the version selected for a directory tree by a file in it.
+type ProjectVersion struct {
+	Spec string
+	File string
+	// Detail names the declaration for versions inferred from a build file; empty for
+	// .java-version and jswitch.lock.
+	Detail string
+	// Lock is the lockfile for versions read from jswitch.lock, which also pins the vendor
+	// and image type.
+	Lock *lockfile.Lock
+}
+
+// FindProject walks up from dir to the nearest jswitch.lock, .java-version or, with buildFiles
+// set, build file that requires a Java version; in the same directory they win in that order,
+// so that a lockfile pins the exact build even next to a .java-version. Build files
+// are only considered up to the repository root (the first directory containing .git),
+// and the walk for them stops below the home directory, so that a stray Dockerfile in the
+// home directory does not apply to everything below it.
+func FindProject(dir string, buildFiles bool) (ProjectVersion, bool) {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return ProjectVersion{}, false
+	}
+
+	home := homeDir()
+	for {
+		if dir == home {
+			buildFiles = false
+		}
+		lock := filepath.Join(dir, lockfile.FileName)
+		if l, err := lockfile.Read(lock); err == nil {
+			return ProjectVersion{Spec: l.Version, File: lock, Lock: l}, true
+		}
+		path := filepath.Join(dir, ProjectFileName)
+		if spec, err := ReadProjectFile(path); err == nil && spec != "" {
+			return ProjectVersion{Spec: spec, File: path}, true
+		}
+		if buildFiles {
+			if reqs := DetectBuildFiles(dir); len(reqs) > 0 {
+				return ProjectVersion{Spec: reqs[0].Spec(), File: reqs[0].File, Detail: reqs[0].Detail}, true
+			}
+			if _, err := os.Stat(filepath.Join(dir, ".git")); err == nil {
+				buildFiles = false
+			}
+		}
+
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return ProjectVersion{}, false
+		}
+		dir = parent
+	}
+}
+
+// FindBuildRequirements walks up from dir to the repository root, stopping below the home
+// directory, and returns the requirements of the first directory whose build files declare
+// a Java version.
+func FindBuildRequirements(dir string) []Requirement {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return nil
+	}
+	home := homeDir()
+	for {
+		if dir == home {
+			return nil
+		}
+		if reqs := DetectBuildFiles(dir); len(reqs) > 0 {
+			return reqs
+		}
+		if _, err := os.Stat(filepath.Join(dir, ".git")); err == nil {
+			return nil
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return nil
+		}
+		dir = parent
+	}
+}
+
+// homeDir returns the cleaned home directory, or "" if it is unknown.
+func homeDir() string {
+	home, err := os.UserHomeDir()
+	if err != nil || home == "" {
+		return ""
+	}
+	if abs, err := filepath.Abs(home); err == nil {
+		return abs
+	}
+	return filepath.Clean(home)
+}
+
+// FindProjectFile walks up from dir looking for a .java-version file.
+// Returns the file path and the first non-empty, non-comment line.
+func FindProjectFile(dir string) (string, string, bool) {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return "", "", false
+	}
+
+	for {
+		path := filepath.Join(dir, ProjectFileName)
+		if spec, err := ReadProjectFile(path); err == nil && spec != "" {
+			return path, spec, true
+		}
+
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return "", "", false
+		}
+		dir = parent
+	}
+}
+
+// ReadProjectFile returns the version specifier stored in a .java-version file.
+func ReadProjectFile(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		line := strings.TrimSpace(sc.Text())
+		if line != "" && !strings.HasPrefix(line, "#") {
+			return line, nil
+		}
+	}
+	return "", sc.Err()
+}
+
+// Tool returns the path to a JDK tool (e.g. "javac") inside an installation.
+func Tool(inst models.JavaInstallation, name string) (string, error) {
+	if runtime.GOOS == "windows" && filepath.Ext(name) == "" {
+		name += ".exe"
+	}
+	path := filepath.Join(inst.Path, "bin", name)
+	info, err := os.Stat(path)
+	if err != nil || info.IsDir() {
+		return "", fmt.Errorf("%s not found in %s", name, filepath.Join(inst.Path, "bin"))
+	}
+	return path, nil
+}
@@ -0,0 +1,84 @@
+package resolver
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/user/jswitch/pkg/config"
+	"github.com/user/jswitch/pkg/lockfile"
+	"github.com/user/jswitch/pkg/models"
+)
+
+// fakeHome creates an installation directory; a JDK has javac besides java.
+func fakeHome(t *testing.T, name string, jdk bool) string {
+	t.Helper()
+	home := filepath.Join(t.TempDir(), name)
+	tools := []string{"java"}
+	if jdk {
+		tools = append(tools, "javac")
+	}
+	if err := os.MkdirAll(filepath.Join(home, "bin"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	for _, tool := range tools {
+		if err := os.WriteFile(filepath.Join(home, "bin", tool), nil, 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return home
+}
+
+func TestActiveMatchesLockedBuild(t *testing.T) {
+	t.Setenv(EnvVar, "")
+	zulu := models.JavaInstallation{Version: "21.0.1", MajorVersion: 21, Vendor: "Azul Zulu", Path: fakeHome(t, "zulu-21", true)}
+	jre := models.JavaInstallation{Version: "21.0.1", MajorVersion: 21, Vendor: "Eclipse Adoptium", Path: fakeHome(t, "temurin-21-jre", false)}
+	jdk := models.JavaInstallation{Version: "21.0.1", MajorVersion: 21, Vendor: "Eclipse Adoptium", Path: fakeHome(t, "temurin-21", true)}
+
+	project := t.TempDir()
+	lock := &lockfile.Lock{Feature: 21, Vendor: "temurin", ImageType: "jdk", Version: "21.0.1"}
+	if err := lockfile.Write(filepath.Join(project, lockfile.FileName), lock); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := &config.Config{Installations: []models.JavaInstallation{zulu, jre}}
+	_, err := Active(cfg, project)
+	var nf *config.NotFoundError
+	if !errors.As(err, &nf) {
+		t.Fatalf("Active with only other builds of 21.0.1 = %v, want a NotFoundError", err)
+	}
+
+	cfg.Installations = append(cfg.Installations, jdk)
+	r, err := Active(cfg, project)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.Installation != jdk || r.Source != SourceProject {
+		t.Errorf("Active = %+v from %s, want the Temurin JDK from the lockfile", r.Installation, r.Source)
+	}
+}
+
+func TestFindProjectStopsBelowHome(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("USERPROFILE", home)
+	// A Dockerfile in the home directory, outside any repository.
+	if err := os.WriteFile(filepath.Join(home, "Dockerfile"), []byte("FROM eclipse-temurin:21-jdk\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	dir := filepath.Join(home, "scratch", "app")
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	if pv, ok := FindProject(dir, true); ok {
+		t.Errorf("FindProject found %s in %s, want nothing", pv.Spec, pv.File)
+	}
+	if reqs := FindBuildRequirements(dir); len(reqs) != 0 {
+		t.Errorf("FindBuildRequirements = %+v, want nothing", reqs)
+	}
+	if reqs := FindBuildRequirements(home); len(reqs) != 0 {
+		t.Errorf("FindBuildRequirements(home) = %+v, want nothing", reqs)
+	}
+}
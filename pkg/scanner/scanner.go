@@ -8,8 +8,8 @@ import (
 	"os/exec"
 	"path/filepath"
 	"regexp"
-	"strconv"
 	"strings"
+	"sync"
 
 	"github.com/user/jswitch/pkg/models"
 )
@@ -24,9 +24,16 @@ var ignoredDirs = map[string]bool{
 	"node_modules":  true,
 }
 
+// candidate is a java executable found while walking, not yet verified.
+type candidate struct {
+	exePath     string
+	installPath string
+}
+
 // ScanSystem recursively scans the provided root paths for Java installations.
-func ScanSystem(rootPaths []string) ([]models.JavaInstallation, error) {
-	var installations []models.JavaInstallation
+// Candidates are verified by running 'java -version', up to workers at a time.
+func ScanSystem(rootPaths []string, workers int) ([]models.JavaInstallation, error) {
+	var candidates []candidate
 	seenPaths := make(map[string]bool)
 
 	for _, root := range rootPaths {
@@ -65,11 +72,8 @@ func ScanSystem(rootPaths []string) ([]models.JavaInstallation, error) {
 						return nil
 					}
 
-					inst, err := verifyAndParseJava(path, installPath)
-					if err == nil {
-						installations = append(installations, inst)
-						seenPaths[installPath] = true
-					}
+					candidates = append(candidates, candidate{exePath: path, installPath: installPath})
+					seenPaths[installPath] = true
 				}
 			}
 
@@ -81,7 +85,41 @@ func ScanSystem(rootPaths []string) ([]models.JavaInstallation, error) {
 		}
 	}
 
-	return installations, nil
+	return verifyAll(candidates, workers), nil
+}
+
+// verifyAll verifies candidates concurrently, keeping the walk order and dropping failures.
+func verifyAll(candidates []candidate, workers int) []models.JavaInstallation {
+	if workers < 1 {
+		workers = 1
+	}
+	results := make([]*models.JavaInstallation, len(candidates))
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				if inst, err := verifyAndParseJava(candidates[i].exePath, candidates[i].installPath); err == nil {
+					results[i] = &inst
+				}
+			}
+		}()
+	}
+	for i := range candidates {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+
+	installations := []models.JavaInstallation{}
+	for _, inst := range results {
+		if inst != nil {
+			installations = append(installations, *inst)
+		}
+	}
+	return installations
 }
 
 // verifyAndParseJava runs 'java -version' and parses the output.
@@ -129,24 +167,7 @@ func parseVersionOutput(output, installRoot string) (models.JavaInstallation, er
 	// Add more vendor checks as needed
 
 	// Determine major version
-	major := 0
-	if strings.HasPrefix(versionStr, "1.") {
-		// Old style: 1.8.0 -> 8
-		parts := strings.Split(versionStr, ".")
-		if len(parts) >= 2 {
-			if val, err := strconv.Atoi(parts[1]); err == nil {
-				major = val
-			}
-		}
-	} else {
-		// New style: 17.0.2 -> 17
-		parts := strings.Split(versionStr, ".")
-		if len(parts) > 0 {
-			if val, err := strconv.Atoi(parts[0]); err == nil {
-				major = val
-			}
-		}
-	}
+	major := models.ParseMajorVersion(versionStr)
 
 	return models.JavaInstallation{
 		Version:      versionStr,
@@ -155,3 +176,14 @@ func parseVersionOutput(output, installRoot string) (models.JavaInstallation, er
 		Vendor:       vendor,
 	}, nil
 }
+
+// Inspect verifies the JDK whose home directory is dir by running its bin/java -version.
+func Inspect(dir string) (models.JavaInstallation, error) {
+	for _, name := range []string{"java", "java.exe"} {
+		exe := filepath.Join(dir, "bin", name)
+		if info, err := os.Stat(exe); err == nil && !info.IsDir() {
+			return verifyAndParseJava(exe, dir)
+		}
+	}
+	return models.JavaInstallation{}, fmt.Errorf("no bin/java in %s", dir)
+}
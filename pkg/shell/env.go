@@ -0,0 +1,89 @@
+package shell
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+)
+
+// EnvVar lists the variables the shell integration set for the active installation, so that
+// they can be unset when switching to another one.
+const EnvVar = "_JSWITCH_ENV"
+
+// Applied returns the names of the variables the shell integration set, from $_JSWITCH_ENV.
+func Applied() []string {
+	return strings.Fields(os.Getenv(EnvVar))
+}
+
+// EnvScript returns the code the named shell evaluates to unset the variables in previous and
+// set vars instead, recording their names in $_JSWITCH_ENV.
+func EnvScript(name string, previous []string, vars map[string]string) (string, error) {
+	names := make([]string, 0, len(vars))
+	for n := range vars {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+
+	var stale []string
+	for _, n := range previous {
+		if _, ok := vars[n]; !ok {
+			stale = append(stale, n)
+		}
+	}
+
+	var b strings.Builder
+	switch name {
+	case "bash", "zsh", "sh":
+		if len(stale) > 0 {
+			fmt.Fprintf(&b, "unset %s\n", strings.Join(stale, " "))
+		}
+		for _, n := range names {
+			fmt.Fprintf(&b, "export %s=%s\n", n, posixQuote(vars[n]))
+		}
+		if len(names) > 0 {
+			fmt.Fprintf(&b, "export %s=%s\n", EnvVar, posixQuote(strings.Join(names, " ")))
+		} else {
+			fmt.Fprintf(&b, "unset %s\n", EnvVar)
+		}
+	case "fish":
+		for _, n := range stale {
+			fmt.Fprintf(&b, "set -e %s\n", n)
+		}
+		for _, n := range names {
+			fmt.Fprintf(&b, "set -gx %s %s\n", n, fishQuote(vars[n]))
+		}
+		if len(names) > 0 {
+			fmt.Fprintf(&b, "set -gx %s %s\n", EnvVar, fishQuote(strings.Join(names, " ")))
+		} else {
+			fmt.Fprintf(&b, "set -e %s\n", EnvVar)
+		}
+	case "pwsh", "powershell":
+		for _, n := range stale {
+			fmt.Fprintf(&b, "Remove-Item Env:%s -ErrorAction SilentlyContinue\n", n)
+		}
+		for _, n := range names {
+			fmt.Fprintf(&b, "$env:%s = %s\n", n, pwshQuote(vars[n]))
+		}
+		if len(names) > 0 {
+			fmt.Fprintf(&b, "$env:%s = %s\n", EnvVar, pwshQuote(strings.Join(names, " ")))
+		} else {
+			fmt.Fprintf(&b, "Remove-Item Env:%s -ErrorAction SilentlyContinue\n", EnvVar)
+		}
+	default:
+		return "", fmt.Errorf("unsupported shell %q (supported: %s)", name, strings.Join(Names, ", "))
+	}
+	return b.String(), nil
+}
+
+func posixQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+func fishQuote(s string) string {
+	return "'" + strings.NewReplacer(`\`, `\\`, "'", `\'`).Replace(s) + "'"
+}
+
+func pwshQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", "''") + "'"
+}
@@ -0,0 +1,299 @@
+package shell
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+
+	"github.com/user/jswitch/pkg/fileutil"
+)
+
+// BlockStart and BlockEnd delimit the block 'jswitch setup' manages in a profile.
+const (
+	BlockStart = "# >>> jswitch >>>"
+	BlockEnd   = "# <<< jswitch <<<"
+)
+
+const (
+	blockNote = "# Managed by 'jswitch setup'. Remove with 'jswitch setup --remove'."
+
+	backupSuffix = ".jswitch.bak"
+)
+
+// Shell is a shell whose startup profile jswitch knows how to configure.
+type Shell struct {
+	// Name identifies the shell ("bash", "zsh", "sh", "fish", "pwsh").
+	Name string
+	// Profile is the absolute path to the startup file we manage.
+	Profile string
+	// AutoSwitch adds a hook that follows $JSWITCH_VERSION and .java-version files on cd
+	// (bash, zsh and fish only).
+	AutoSwitch bool
+}
+
+// Names lists every supported shell name.
+var Names = []string{"bash", "zsh", "sh", "fish", "pwsh"}
+
+// Detect returns the shells that appear to be in use for the given home directory.
+// A shell is included when its profile already exists or when it is the login shell ($SHELL).
+func Detect(home string) []Shell {
+	login := filepath.Base(os.Getenv("SHELL"))
+
+	var shells []Shell
+	for _, name := range Names {
+		sh, err := ForName(home, name)
+		if err != nil {
+			continue
+		}
+		if _, err := os.Stat(sh.Profile); err == nil || name == login || (name == "pwsh" && runtime.GOOS == "windows") {
+			shells = append(shells, sh)
+		}
+	}
+	return shells
+}
+
+// ForName returns the Shell for a supported shell name.
+func ForName(home, name string) (Shell, error) {
+	var profile string
+	switch name {
+	case "bash":
+		profile = filepath.Join(home, ".bashrc")
+	case "zsh":
+		profile = filepath.Join(home, ".zshrc")
+	case "sh":
+		profile = filepath.Join(home, ".profile")
+	case "fish":
+		profile = filepath.Join(home, ".config", "fish", "config.fish")
+	case "pwsh", "powershell":
+		name = "pwsh"
+		if runtime.GOOS == "windows" {
+			profile = filepath.Join(home, "Documents", "PowerShell", "Microsoft.PowerShell_profile.ps1")
+		} else {
+			profile = filepath.Join(home, ".config", "powershell", "Microsoft.PowerShell_profile.ps1")
+		}
+	default:
+		return Shell{}, fmt.Errorf("unsupported shell %q (supported: %s)", name, strings.Join(Names, ", "))
+	}
+	return Shell{Name: name, Profile: profile}, nil
+}
+
+// Snippet returns the lines placed inside the managed block for this shell.
+func (s Shell) Snippet(linkPath string) string {
+	switch s.Name {
+	case "fish":
+		snippet := fmt.Sprintf("set -gx JAVA_HOME %s\nset -gx PATH $JAVA_HOME/bin $PATH\n", fishQuote(linkPath)) + fishEnv
+		if s.AutoSwitch {
+			snippet += fmt.Sprintf(fishAutoSwitch, fishQuote(linkPath))
+		}
+		return snippet
+	case "pwsh":
+		if runtime.GOOS == "windows" {
+			// The switcher writes JAVA_HOME to the user environment; refresh it for new sessions.
+			return "$env:JAVA_HOME = [Environment]::GetEnvironmentVariable('JAVA_HOME', 'User')\n" +
+				"$env:PATH = \"$env:JAVA_HOME\\bin;$env:PATH\"\n" + pwshEnv
+		}
+		return fmt.Sprintf("$env:JAVA_HOME = %s\n$env:PATH = \"$env:JAVA_HOME/bin\" + [IO.Path]::PathSeparator + $env:PATH\n", pwshQuote(linkPath)) + pwshEnv
+	default:
+		snippet := fmt.Sprintf("export JAVA_HOME=%s\nexport PATH=\"$JAVA_HOME/bin:$PATH\"\n", posixQuote(linkPath)) + posixEnv
+		switch {
+		case s.AutoSwitch && s.Name == "bash":
+			snippet += fmt.Sprintf(posixAutoSwitch, posixQuote(linkPath)) +
+				"PROMPT_COMMAND=\"_jswitch_autoswitch${PROMPT_COMMAND:+;$PROMPT_COMMAND}\"\n_jswitch_autoswitch\n"
+		case s.AutoSwitch && s.Name == "zsh":
+			snippet += fmt.Sprintf(posixAutoSwitch, posixQuote(linkPath)) +
+				"autoload -Uz add-zsh-hook && add-zsh-hook chpwd _jswitch_autoswitch\n_jswitch_autoswitch\n"
+		}
+		return snippet
+	}
+}
+
+// The env hooks apply the variables attached to the installation at $JAVA_HOME with
+// 'jswitch env set', unsetting those of the previous one. They run when the shell starts, after
+// the jswitch commands that can change them, and when auto-switch changes JAVA_HOME.
+const posixEnv = `_jswitch_env() { eval "$(command jswitch env shell sh 2>/dev/null)"; }
+jswitch() {
+  command jswitch "$@"
+  set -- "$?" "${1-}"
+  case $2 in use|alias|env) _jswitch_env ;; esac
+  return "$1"
+}
+_jswitch_env
+`
+
+const fishEnv = `function _jswitch_env
+    command jswitch env shell fish 2>/dev/null | source
+end
+function jswitch
+    command jswitch $argv
+    set -l rc $status
+    contains -- "$argv[1]" use alias env; and _jswitch_env
+    return $rc
+end
+_jswitch_env
+`
+
+const pwshEnv = `$global:_jswitch_exe = (Get-Command jswitch -CommandType Application -ErrorAction SilentlyContinue | Select-Object -First 1).Source
+function global:_jswitch_env { if ($global:_jswitch_exe) { & $global:_jswitch_exe env shell pwsh 2>$null | Out-String | Invoke-Expression } }
+function global:jswitch {
+    & $global:_jswitch_exe @args
+    $rc = $LASTEXITCODE
+    if ($args.Count -gt 0 -and $args[0] -in 'use', 'alias', 'env') { _jswitch_env }
+    $global:LASTEXITCODE = $rc
+}
+_jswitch_env
+`
+
+// The auto-switch hooks point JAVA_HOME at the project's JDK when $JSWITCH_VERSION or a
+// .java-version file applies, and back at the current link otherwise.
+const posixAutoSwitch = `_jswitch_autoswitch() {
+  [ "$PWD" = "${_JSWITCH_PWD-}" ] && return
+  _JSWITCH_PWD=$PWD
+  local res home=%[1]s
+  res=$(command jswitch current --format '{{.Source}} {{.Path}}' 2>/dev/null)
+  case $res in project\ *|env\ *) home=${res#* } ;; esac
+  [ "$home" = "$JAVA_HOME" ] && return
+  PATH=${PATH//"$JAVA_HOME/bin:"/}
+  export JAVA_HOME=$home
+  export PATH="$JAVA_HOME/bin:$PATH"
+  _jswitch_env
+}
+`
+
+const fishAutoSwitch = `function _jswitch_autoswitch --on-variable PWD
+    set -l home %[1]s
+    set -l res (command jswitch current --format '{{.Source}} {{.Path}}' 2>/dev/null)
+    if string match -qr '^(project|env) ' -- "$res"
+        set home (string replace -r '^\S+ ' '' -- "$res")
+    end
+    test "$home" = "$JAVA_HOME"; and return
+    if set -l i (contains -i -- $JAVA_HOME/bin $PATH)
+        set -e PATH[$i]
+    end
+    set -gx JAVA_HOME $home
+    set -gx PATH $JAVA_HOME/bin $PATH
+    _jswitch_env
+end
+_jswitch_autoswitch
+`
+
+// IsConfigured reports whether the shell's profile already contains the managed block.
+func (s Shell) IsConfigured() bool {
+	data, err := os.ReadFile(s.Profile)
+	if err != nil {
+		return false
+	}
+	_, _, ok := findBlock(data)
+	return ok
+}
+
+// Install writes (or refreshes) the managed block in the shell's profile.
+// The profile as it was before jswitch first changed it is kept as <profile>.jswitch.bak.
+// Returns false if the profile was already up to date.
+func (s Shell) Install(linkPath string) (bool, error) {
+	original, err := os.ReadFile(s.Profile)
+	if err != nil && !os.IsNotExist(err) {
+		return false, fmt.Errorf("failed to read %s: %w", s.Profile, err)
+	}
+
+	block := []byte(BlockStart + "\n" + blockNote + "\n" + s.Snippet(linkPath) + BlockEnd + "\n")
+
+	var updated []byte
+	if start, end, ok := findBlock(original); ok {
+		updated = append(append(append([]byte{}, original[:start]...), block...), original[end:]...)
+	} else {
+		updated = append([]byte{}, original...)
+		if len(updated) > 0 && !bytes.HasSuffix(updated, []byte("\n")) {
+			updated = append(updated, '\n')
+		}
+		if len(updated) > 0 {
+			updated = append(updated, '\n')
+		}
+		updated = append(updated, block...)
+	}
+
+	if bytes.Equal(original, updated) {
+		return false, nil
+	}
+	return true, s.write(original, updated)
+}
+
+// Remove strips the managed block from the shell's profile.
+// Returns false if there was no block to remove.
+func (s Shell) Remove() (bool, error) {
+	original, err := os.ReadFile(s.Profile)
+	if os.IsNotExist(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to read %s: %w", s.Profile, err)
+	}
+
+	start, end, ok := findBlock(original)
+	if !ok {
+		return false, nil
+	}
+
+	head := original[:start]
+	// Drop the blank separator line Install added in front of the block.
+	if bytes.HasSuffix(head, []byte("\n\n")) {
+		head = head[:len(head)-1]
+	}
+	updated := append(append([]byte{}, head...), original[end:]...)
+
+	return true, s.write(original, updated)
+}
+
+func (s Shell) write(original, updated []byte) error {
+	mode := os.FileMode(0644)
+	if info, err := os.Stat(s.Profile); err == nil {
+		mode = info.Mode().Perm()
+		if err := backup(s.Profile+backupSuffix, original, mode); err != nil {
+			return fmt.Errorf("failed to back up %s: %w", s.Profile, err)
+		}
+	}
+
+	if err := os.MkdirAll(filepath.Dir(s.Profile), 0755); err != nil {
+		return fmt.Errorf("failed to create directory for %s: %w", s.Profile, err)
+	}
+	if err := fileutil.WriteFile(s.Profile, updated, mode); err != nil {
+		return fmt.Errorf("failed to write %s: %w", s.Profile, err)
+	}
+	return nil
+}
+
+// backup writes data to path unless a backup exists already, so that it keeps the profile
+// as it was before jswitch first changed it.
+func backup(path string, data []byte, mode os.FileMode) error {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, mode)
+	if os.IsExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// findBlock locates the managed block, returning the byte range including the trailing newline.
+func findBlock(data []byte) (int, int, bool) {
+	start := bytes.Index(data, []byte(BlockStart))
+	if start < 0 {
+		return 0, 0, false
+	}
+	rel := bytes.Index(data[start:], []byte(BlockEnd))
+	if rel < 0 {
+		return 0, 0, false
+	}
+	end := start + rel + len(BlockEnd)
+	if end < len(data) && data[end] == '\n' {
+		end++
+	}
+	return start, end, true
+}
@@ -0,0 +1,73 @@
+package shell
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func TestInstallKeepsFirstBackup(t *testing.T) {
+	profile := filepath.Join(t.TempDir(), ".bashrc")
+	original := "export EDITOR=vi\n"
+	if err := os.WriteFile(profile, []byte(original), 0644); err != nil {
+		t.Fatal(err)
+	}
+	s := Shell{Name: "bash", Profile: profile}
+
+	if _, err := s.Install("/opt/jswitch/current"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Install("/srv/jswitch/current"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Remove(); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := os.ReadFile(profile + backupSuffix)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != original {
+		t.Errorf("backup = %q, want the profile before the first change %q", data, original)
+	}
+}
+
+func TestSnippetQuotesLinkPath(t *testing.T) {
+	link := `/home/o'brien/$HOME "jdk"\current`
+	tests := []struct {
+		shell string
+		want  []string
+	}{
+		{"bash", []string{`export JAVA_HOME='/home/o'\''brien/$HOME "jdk"\current'`, `local res home='/home/o'\''brien/$HOME "jdk"\current'`}},
+		{"fish", []string{`set -gx JAVA_HOME '/home/o\'brien/$HOME "jdk"\\current'`, `set -l home '/home/o\'brien/$HOME "jdk"\\current'`}},
+		{"pwsh", []string{`$env:JAVA_HOME = '/home/o''brien/$HOME "jdk"\current'`}},
+	}
+	for _, tt := range tests {
+		if tt.shell == "pwsh" && runtime.GOOS == "windows" {
+			continue
+		}
+		snippet := Shell{Name: tt.shell, AutoSwitch: true}.Snippet(link)
+		for _, want := range tt.want {
+			if !strings.Contains(snippet, want) {
+				t.Errorf("%s snippet does not contain %s:\n%s", tt.shell, want, snippet)
+			}
+		}
+	}
+
+	sh, err := exec.LookPath("sh")
+	if err != nil {
+		return
+	}
+	line, _, _ := strings.Cut(Shell{Name: "sh"}.Snippet(link), "\n")
+	out, err := exec.Command(sh, "-c", line+`; printf %s "$JAVA_HOME"`).Output()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(out) != link {
+		t.Errorf("sh sets JAVA_HOME=%s, want %s", out, link)
+	}
+}
@@ -1,6 +1,45 @@
 package switcher
 
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+)
+
 // Switch sets the system's Java version to the specified path.
+// The path is validated first so a bad target never replaces a working setup.
 func Switch(javaPath string) error {
+	if err := Validate(javaPath); err != nil {
+		return err
+	}
 	return switchJava(javaPath)
 }
+
+// Validate checks that javaPath looks like a Java installation root (contains bin/java).
+func Validate(javaPath string) error {
+	if javaPath == "" {
+		return fmt.Errorf("no installation path given")
+	}
+	info, err := os.Stat(javaPath)
+	if err != nil {
+		return fmt.Errorf("installation path %s is not accessible: %w", javaPath, err)
+	}
+	if !info.IsDir() {
+		return fmt.Errorf("installation path %s is not a directory", javaPath)
+	}
+
+	exe := "java"
+	if runtime.GOOS == "windows" {
+		exe = "java.exe"
+	}
+	javaBin := filepath.Join(javaPath, "bin", exe)
+	info, err = os.Stat(javaBin)
+	if err != nil {
+		return fmt.Errorf("no java executable found at %s: %w", javaBin, err)
+	}
+	if info.IsDir() {
+		return fmt.Errorf("%s is a directory, not an executable", javaBin)
+	}
+	return nil
+}
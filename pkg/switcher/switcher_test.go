@@ -0,0 +1,61 @@
+package switcher
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+// fakeJDK creates an installation root with a bin/java.
+func fakeJDK(t *testing.T, dir, name string) string {
+	t.Helper()
+	exe := "java"
+	if runtime.GOOS == "windows" {
+		exe = "java.exe"
+	}
+	home := filepath.Join(dir, name)
+	if err := os.MkdirAll(filepath.Join(home, "bin"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(home, "bin", exe), []byte("#!/bin/sh\n"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	return home
+}
+
+func TestValidate(t *testing.T) {
+	dir := t.TempDir()
+	noJava := filepath.Join(dir, "no-java")
+	if err := os.MkdirAll(filepath.Join(noJava, "bin"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	javaDir := filepath.Join(dir, "java-dir")
+	if err := os.MkdirAll(filepath.Join(javaDir, "bin", "java"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	file := filepath.Join(dir, "file")
+	if err := os.WriteFile(file, nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		path    string
+		wantErr bool
+	}{
+		{"installation", fakeJDK(t, dir, "jdk-21"), false},
+		{"empty path", "", true},
+		{"missing", filepath.Join(dir, "missing"), true},
+		{"not a directory", file, true},
+		{"no bin/java", noJava, true},
+		{"bin/java is a directory", javaDir, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := Validate(tt.path); (err != nil) != tt.wantErr {
+				t.Errorf("Validate(%q) = %v, want error %v", tt.path, err, tt.wantErr)
+			}
+		})
+	}
+}
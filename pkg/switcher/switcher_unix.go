@@ -6,33 +6,62 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"strconv"
+	"time"
+
+	"github.com/user/jswitch/pkg/paths"
 )
 
 func switchJava(javaPath string) error {
-	home, err := os.UserHomeDir()
+	linkPath, err := paths.CurrentLink()
 	if err != nil {
 		return err
 	}
 
-	jswitchDir := filepath.Join(home, ".jswitch")
-	linkPath := filepath.Join(jswitchDir, "current")
+	dataDir := filepath.Dir(linkPath)
+	if err := os.MkdirAll(dataDir, 0755); err != nil {
+		return fmt.Errorf("failed to create %s: %w", dataDir, err)
+	}
 
-	// Remove existing link or file if it exists
-	if _, err := os.Lstat(linkPath); err == nil {
-		if err := os.Remove(linkPath); err != nil {
-			return fmt.Errorf("failed to remove existing symlink: %w", err)
+	// Remember the previous target so we can roll back if the new link turns out to be broken.
+	previous, err := os.Readlink(linkPath)
+	if err != nil && !os.IsNotExist(err) {
+		if info, statErr := os.Lstat(linkPath); statErr == nil && info.Mode()&os.ModeSymlink == 0 {
+			return fmt.Errorf("%s exists and is not a symlink; move it out of the way first", linkPath)
 		}
+		return fmt.Errorf("failed to read existing symlink: %w", err)
 	}
 
-	// Create new symlink
-	if err := os.Symlink(javaPath, linkPath); err != nil {
-		return fmt.Errorf("failed to create symlink: %w", err)
+	if err := replaceSymlink(javaPath, linkPath); err != nil {
+		return err
 	}
 
-	fmt.Printf("Success! Symlink updated at %s\n", linkPath)
-	fmt.Println("Ensure this line is in your ~/.zshrc or ~/.bashrc:")
-	fmt.Printf("export JAVA_HOME=%s\n", linkPath)
-	fmt.Println("export PATH=$JAVA_HOME/bin:$PATH")
+	if err := Validate(linkPath); err != nil {
+		if previous != "" {
+			if rbErr := replaceSymlink(previous, linkPath); rbErr != nil {
+				return fmt.Errorf("new symlink is broken (%v) and rollback to %s failed: %w", err, previous, rbErr)
+			}
+			return fmt.Errorf("new symlink is broken, rolled back to %s: %w", previous, err)
+		}
+		os.Remove(linkPath)
+		return fmt.Errorf("new symlink is broken: %w", err)
+	}
 
 	return nil
 }
+
+// replaceSymlink atomically points linkPath at target.
+// The link is created under a temporary name and renamed over the old one, so
+// concurrent readers always see either the old or the new target, never nothing.
+func replaceSymlink(target, linkPath string) error {
+	tmpPath := linkPath + ".tmp-" + strconv.Itoa(os.Getpid()) + "-" + strconv.FormatInt(time.Now().UnixNano(), 36)
+
+	if err := os.Symlink(target, tmpPath); err != nil {
+		return fmt.Errorf("failed to create symlink: %w", err)
+	}
+	if err := os.Rename(tmpPath, linkPath); err != nil {
+		os.Remove(tmpPath)
+		return fmt.Errorf("failed to replace symlink: %w", err)
+	}
+	return nil
+}
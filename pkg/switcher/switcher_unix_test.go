@@ -0,0 +1,109 @@
+//go:build !windows
+
+package switcher
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/user/jswitch/pkg/paths"
+)
+
+// useTempHome points the jswitch home at a temporary directory and returns the current link.
+func useTempHome(t *testing.T, dir string) string {
+	t.Helper()
+	t.Setenv(paths.EnvHome, filepath.Join(dir, "home"))
+	link, err := paths.CurrentLink()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return link
+}
+
+func TestSwitchIsAtomic(t *testing.T) {
+	dir := t.TempDir()
+	link := useTempHome(t, dir)
+	homes := []string{fakeJDK(t, dir, "jdk-17"), fakeJDK(t, dir, "jdk-21")}
+	if err := Switch(homes[0]); err != nil {
+		t.Fatal(err)
+	}
+
+	var done atomic.Bool
+	var wg sync.WaitGroup
+	errs := make(chan error, 8)
+	for r := 0; r < 4; r++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			for !done.Load() {
+				target, err := os.Readlink(link)
+				if err != nil {
+					errs <- fmt.Errorf("Readlink: %w", err)
+					return
+				}
+				if target != homes[0] && target != homes[1] {
+					errs <- fmt.Errorf("link points at %q", target)
+					return
+				}
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			for !done.Load() {
+				if _, err := os.Stat(filepath.Join(link, "bin", "java")); err != nil {
+					errs <- fmt.Errorf("Stat: %w", err)
+					return
+				}
+			}
+		}()
+	}
+
+	for i := 0; i < 500; i++ {
+		if err := Switch(homes[i%2]); err != nil {
+			t.Fatalf("Switch: %v", err)
+		}
+	}
+	done.Store(true)
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if errors.Is(err, fs.ErrNotExist) {
+			t.Errorf("the current link was missing during a switch: %v", err)
+		} else {
+			t.Error(err)
+		}
+	}
+}
+
+func TestFailedSwitchKeepsPreviousTarget(t *testing.T) {
+	dir := t.TempDir()
+	link := useTempHome(t, dir)
+	good := fakeJDK(t, dir, "jdk-17")
+	if err := Switch(good); err != nil {
+		t.Fatal(err)
+	}
+	broken := filepath.Join(dir, "broken")
+	if err := os.MkdirAll(broken, 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := Switch(broken); err == nil {
+		t.Error("Switch to a home without bin/java succeeded")
+	}
+	// switchJava validates the new link itself and rolls back if it is broken.
+	if err := switchJava(broken); err == nil {
+		t.Error("switchJava to a home without bin/java succeeded")
+	}
+	if target, err := os.Readlink(link); err != nil || target != good {
+		t.Errorf("current link points at %q (%v) after failed switches, want %q", target, err, good)
+	}
+	if leftovers, _ := filepath.Glob(link + ".tmp-*"); len(leftovers) > 0 {
+		t.Errorf("temporary links left behind: %q", leftovers)
+	}
+}
@@ -0,0 +1,179 @@
+package truststore
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"crypto/x509"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+	"unicode/utf16"
+)
+
+// The JKS format, as written by sun.security.provider.JavaKeyStore:
+//
+//	magic 0xFEEDFEED, version 2, entry count
+//	per entry: tag (1 private key, 2 trusted certificate), alias, creation time in ms, then
+//	  tag 1: protected key, certificate chain (type, length, DER)
+//	  tag 2: certificate type, length, DER
+//	SHA-1 of the UTF-16BE password, "Mighty Aphrodite" and everything before it
+//
+// Strings are Java modified UTF-8 with a 16-bit length.
+const (
+	jksMagic   = 0xFEEDFEED
+	jksVersion = 2
+
+	jksTagKey  = 1
+	jksTagCert = 2
+)
+
+// jksEntry is a private key entry, kept byte for byte.
+type jksEntry struct {
+	raw []byte
+}
+
+func (s *Store) readJKS(data []byte) error {
+	if len(data) < 12+sha1.Size {
+		return errors.New("truncated JKS file")
+	}
+	body, digest := data[:len(data)-sha1.Size], data[len(data)-sha1.Size:]
+	if !bytes.Equal(jksDigest(s.password, body), digest) {
+		return errors.New("keystore password was incorrect, or the file is damaged")
+	}
+
+	r := bytes.NewReader(body[4:])
+	var version, count uint32
+	binary.Read(r, binary.BigEndian, &version)
+	binary.Read(r, binary.BigEndian, &count)
+	if version != jksVersion {
+		return fmt.Errorf("unsupported JKS version %d", version)
+	}
+
+	for i := 0; i < int(count); i++ {
+		start := len(body) - r.Len()
+		var tag uint32
+		if err := binary.Read(r, binary.BigEndian, &tag); err != nil {
+			return err
+		}
+		alias, err := readUTF(r)
+		if err != nil {
+			return err
+		}
+		var created int64
+		if err := binary.Read(r, binary.BigEndian, &created); err != nil {
+			return err
+		}
+		switch tag {
+		case jksTagKey:
+			if _, err := readBytes(r); err != nil {
+				return err
+			}
+			var chain uint32
+			if err := binary.Read(r, binary.BigEndian, &chain); err != nil {
+				return err
+			}
+			for j := 0; j < int(chain); j++ {
+				if _, err := readUTF(r); err != nil {
+					return err
+				}
+				if _, err := readBytes(r); err != nil {
+					return err
+				}
+			}
+			end := len(body) - r.Len()
+			s.jksKeys = append(s.jksKeys, jksEntry{raw: body[start:end]})
+		case jksTagCert:
+			if _, err := readUTF(r); err != nil {
+				return err
+			}
+			der, err := readBytes(r)
+			if err != nil {
+				return err
+			}
+			cert, err := x509.ParseCertificate(der)
+			if err != nil {
+				return fmt.Errorf("entry %q: %w", alias, err)
+			}
+			s.entries = append(s.entries, Entry{Alias: alias, Cert: cert, created: created})
+		default:
+			return fmt.Errorf("unknown JKS entry type %d", tag)
+		}
+	}
+	return nil
+}
+
+func (s *Store) writeJKS() ([]byte, error) {
+	var b bytes.Buffer
+	binary.Write(&b, binary.BigEndian, uint32(jksMagic))
+	binary.Write(&b, binary.BigEndian, uint32(jksVersion))
+	binary.Write(&b, binary.BigEndian, uint32(len(s.entries)+len(s.jksKeys)))
+	for _, k := range s.jksKeys {
+		b.Write(k.raw)
+	}
+	now := time.Now().UnixMilli()
+	for _, e := range s.entries {
+		binary.Write(&b, binary.BigEndian, uint32(jksTagCert))
+		if err := writeUTF(&b, e.Alias); err != nil {
+			return nil, err
+		}
+		created := e.created
+		if created == 0 {
+			created = now
+		}
+		binary.Write(&b, binary.BigEndian, created)
+		writeUTF(&b, "X.509")
+		binary.Write(&b, binary.BigEndian, uint32(len(e.Cert.Raw)))
+		b.Write(e.Cert.Raw)
+	}
+	b.Write(jksDigest(s.password, b.Bytes()))
+	return b.Bytes(), nil
+}
+
+// jksDigest is the integrity check of a JKS file.
+func jksDigest(password string, body []byte) []byte {
+	h := sha1.New()
+	for _, c := range utf16.Encode([]rune(password)) {
+		h.Write([]byte{byte(c >> 8), byte(c)})
+	}
+	h.Write([]byte("Mighty Aphrodite"))
+	h.Write(body)
+	return h.Sum(nil)
+}
+
+// readUTF reads a Java modified UTF-8 string. Aliases and certificate types are ASCII in
+// practice, for which it is plain UTF-8.
+func readUTF(r *bytes.Reader) (string, error) {
+	var n uint16
+	if err := binary.Read(r, binary.BigEndian, &n); err != nil {
+		return "", err
+	}
+	buf := make([]byte, n)
+	if _, err := io.ReadFull(r, buf); err != nil {
+		return "", err
+	}
+	return string(buf), nil
+}
+
+func writeUTF(w *bytes.Buffer, s string) error {
+	if len(s) > 0xFFFF {
+		return fmt.Errorf("alias too long: %q", s)
+	}
+	binary.Write(w, binary.BigEndian, uint16(len(s)))
+	w.WriteString(s)
+	return nil
+}
+
+func readBytes(r *bytes.Reader) ([]byte, error) {
+	var n uint32
+	if err := binary.Read(r, binary.BigEndian, &n); err != nil {
+		return nil, err
+	}
+	if int64(n) > int64(r.Len()) {
+		return nil, io.ErrUnexpectedEOF
+	}
+	buf := make([]byte, n)
+	_, err := io.ReadFull(r, buf)
+	return buf, err
+}
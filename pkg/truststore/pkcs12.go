@@ -0,0 +1,274 @@
+package truststore
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"errors"
+	"fmt"
+	"unicode/utf16"
+
+	pkcs12 "software.sslmate.com/src/go-pkcs12"
+)
+
+// PKCS#12 truststores. JDK 18 and later ship cacerts without a password: no MAC and
+// unencrypted certificate bags, which are read here directly so that aliases survive.
+// Password-protected stores are checked with go-pkcs12, which also decrypts certificate
+// bags; entries whose alias cannot be read without decrypting get one derived from their
+// subject. Stores are always written with unencrypted bags, and a MAC if they have a
+// password, so that aliases survive the next read.
+
+var (
+	oidData           = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 7, 1}
+	oidCertBag        = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 12, 10, 1, 3}
+	oidFriendlyName   = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 9, 20}
+	oidX509CertType   = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 9, 22, 1}
+	oidKeyBag         = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 12, 10, 1, 1}
+	oidShroudedKeyBag = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 12, 10, 1, 2}
+	oidSHA1           = asn1.ObjectIdentifier{1, 3, 14, 3, 2, 26}
+)
+
+// macIterations is the iteration count of the MAC key derivation, as keytool uses.
+const macIterations = 10000
+
+type pfxPDU struct {
+	Version  int
+	AuthSafe contentInfo
+	MacData  asn1.RawValue `asn1:"optional"`
+}
+
+type contentInfo struct {
+	ContentType asn1.ObjectIdentifier
+	Content     asn1.RawValue `asn1:"tag:0,explicit,optional"`
+}
+
+type macData struct {
+	Mac        digestInfo
+	MacSalt    []byte
+	Iterations int `asn1:"optional,default:1"`
+}
+
+type digestInfo struct {
+	Algorithm pkix.AlgorithmIdentifier
+	Digest    []byte
+}
+
+type safeBag struct {
+	ID         asn1.ObjectIdentifier
+	Value      asn1.RawValue     `asn1:"tag:0,explicit"`
+	Attributes []pkcs12Attribute `asn1:"set,optional"`
+}
+
+type pkcs12Attribute struct {
+	ID    asn1.ObjectIdentifier
+	Value asn1.RawValue `asn1:"set"`
+}
+
+type certBag struct {
+	ID   asn1.ObjectIdentifier
+	Data []byte `asn1:"tag:0,explicit"`
+}
+
+func (s *Store) readPKCS12(data []byte) error {
+	var pfx pfxPDU
+	if rest, err := asn1.Unmarshal(data, &pfx); err != nil || len(rest) > 0 || !pfx.AuthSafe.ContentType.Equal(oidData) {
+		return errors.New("not a JKS or PKCS#12 keystore")
+	}
+	s.protected = len(pfx.MacData.FullBytes) > 0
+
+	entries, plain, err := readPlainBags(pfx)
+	if err != nil {
+		return err
+	}
+	if !s.protected && plain {
+		s.entries = entries
+		return nil
+	}
+
+	// go-pkcs12 checks the MAC, and decrypts what readPlainBags could not read.
+	certs, err := pkcs12.DecodeTrustStore(data, s.password)
+	if errors.Is(err, pkcs12.ErrIncorrectPassword) {
+		return errors.New("keystore password was incorrect")
+	}
+	if err != nil {
+		return err
+	}
+	if plain {
+		s.entries = entries
+		return nil
+	}
+	for _, cert := range certs {
+		s.entries = append(s.entries, Entry{Alias: subjectAlias(cert), Cert: cert})
+	}
+	return nil
+}
+
+// readPlainBags returns the certificates of a PKCS#12 file whose bags are not encrypted,
+// with their friendly names as aliases. plain is false if any bag is encrypted.
+func readPlainBags(pfx pfxPDU) (entries []Entry, plain bool, err error) {
+	var authSafe []byte
+	if _, err := asn1.Unmarshal(pfx.AuthSafe.Content.Bytes, &authSafe); err != nil {
+		return nil, false, err
+	}
+	var infos []contentInfo
+	if _, err := asn1.Unmarshal(authSafe, &infos); err != nil {
+		return nil, false, err
+	}
+	for _, ci := range infos {
+		if !ci.ContentType.Equal(oidData) {
+			return nil, false, nil
+		}
+		var contents []byte
+		if _, err := asn1.Unmarshal(ci.Content.Bytes, &contents); err != nil {
+			return nil, false, err
+		}
+		var bags []safeBag
+		if _, err := asn1.Unmarshal(contents, &bags); err != nil {
+			return nil, false, err
+		}
+		for _, bag := range bags {
+			switch {
+			case bag.ID.Equal(oidCertBag):
+				var cb certBag
+				if _, err := asn1.Unmarshal(bag.Value.Bytes, &cb); err != nil {
+					return nil, false, err
+				}
+				if !cb.ID.Equal(oidX509CertType) {
+					continue
+				}
+				cert, err := x509.ParseCertificate(cb.Data)
+				if err != nil {
+					return nil, false, err
+				}
+				alias := friendlyName(bag)
+				if alias == "" {
+					alias = subjectAlias(cert)
+				}
+				entries = append(entries, Entry{Alias: alias, Cert: cert})
+			case bag.ID.Equal(oidKeyBag) || bag.ID.Equal(oidShroudedKeyBag):
+				return nil, false, errors.New("the keystore holds private keys; only truststores are supported")
+			}
+		}
+	}
+	return entries, true, nil
+}
+
+// friendlyName returns the friendlyName attribute of a bag, a BMPString.
+func friendlyName(bag safeBag) string {
+	for _, attr := range bag.Attributes {
+		if !attr.ID.Equal(oidFriendlyName) {
+			continue
+		}
+		var raw asn1.RawValue
+		if _, err := asn1.Unmarshal(attr.Value.Bytes, &raw); err != nil || raw.Tag != asn1.TagBMPString || len(raw.Bytes)%2 != 0 {
+			return ""
+		}
+		units := make([]uint16, len(raw.Bytes)/2)
+		for i := range units {
+			units[i] = uint16(raw.Bytes[2*i])<<8 | uint16(raw.Bytes[2*i+1])
+		}
+		return string(utf16.Decode(units))
+	}
+	return ""
+}
+
+func (s *Store) writePKCS12() ([]byte, error) {
+	entries := make([]pkcs12.TrustStoreEntry, len(s.entries))
+	for i, e := range s.entries {
+		entries[i] = pkcs12.TrustStoreEntry{Cert: e.Cert, FriendlyName: e.Alias}
+	}
+	data, err := pkcs12.Passwordless.EncodeTrustStoreEntries(entries, "")
+	if err != nil {
+		return nil, fmt.Errorf("encoding PKCS#12: %w", err)
+	}
+	if !s.protected {
+		return data, nil
+	}
+	return addMAC(data, s.password)
+}
+
+// addMAC protects a PKCS#12 file without a MAC with an HMAC-SHA1 over its contents, which
+// every Java release jswitch can install verifies.
+func addMAC(data []byte, password string) ([]byte, error) {
+	var pfx pfxPDU
+	if _, err := asn1.Unmarshal(data, &pfx); err != nil {
+		return nil, err
+	}
+	var authSafe []byte
+	if _, err := asn1.Unmarshal(pfx.AuthSafe.Content.Bytes, &authSafe); err != nil {
+		return nil, err
+	}
+	md := macData{MacSalt: make([]byte, 20), Iterations: macIterations}
+	if _, err := rand.Read(md.MacSalt); err != nil {
+		return nil, err
+	}
+	key := pkcs12KDF(bmpPassword(password), md.MacSalt, md.Iterations, 3, sha1.Size)
+	mac := hmac.New(sha1.New, key)
+	mac.Write(authSafe)
+	md.Mac = digestInfo{
+		Algorithm: pkix.AlgorithmIdentifier{Algorithm: oidSHA1, Parameters: asn1.NullRawValue},
+		Digest:    mac.Sum(nil),
+	}
+	return asn1.Marshal(struct {
+		Version  int
+		AuthSafe contentInfo
+		MacData  macData
+	}{pfx.Version, pfx.AuthSafe, md})
+}
+
+// bmpPassword encodes a password as PKCS#12 key derivation expects: UTF-16BE with a
+// terminating zero.
+func bmpPassword(password string) []byte {
+	var b []byte
+	for _, c := range utf16.Encode([]rune(password)) {
+		b = append(b, byte(c>>8), byte(c))
+	}
+	return append(b, 0, 0)
+}
+
+// pkcs12KDF derives size bytes of key material with SHA-1, as RFC 7292 appendix B.2
+// describes. id selects the purpose: 3 for a MAC key.
+func pkcs12KDF(password, salt []byte, iterations int, id byte, size int) []byte {
+	const u, v = sha1.Size, 64
+	d := bytes.Repeat([]byte{id}, v)
+	in := append(fillBlocks(salt, v), fillBlocks(password, v)...)
+	var out []byte
+	for len(out) < size {
+		h := sha1.New()
+		h.Write(d)
+		h.Write(in)
+		a := h.Sum(nil)
+		for j := 1; j < iterations; j++ {
+			sum := sha1.Sum(a)
+			a = sum[:]
+		}
+		out = append(out, a...)
+
+		// Each v-byte block of in becomes (block + b + 1) mod 2^(8v).
+		b := fillBlocks(a, v)
+		for k := 0; k < len(in); k += v {
+			carry := 1
+			for n := v - 1; n >= 0; n-- {
+				sum := int(in[k+n]) + int(b[n]) + carry
+				in[k+n], carry = byte(sum), sum>>8
+			}
+		}
+	}
+	return out[:size]
+}
+
+// fillBlocks repeats b to fill a whole number of v-byte blocks.
+func fillBlocks(b []byte, v int) []byte {
+	if len(b) == 0 {
+		return nil
+	}
+	out := make([]byte, v*((len(b)+v-1)/v))
+	for i := range out {
+		out[i] = b[i%len(b)]
+	}
+	return out
+}
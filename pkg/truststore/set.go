@@ -0,0 +1,166 @@
+package truststore
+
+import (
+	"bytes"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/user/jswitch/pkg/fileutil"
+)
+
+// aliasSuffix marks the truststore entries jswitch manages, like the " [jdk]" of the
+// entries JDKs ship with.
+const aliasSuffix = " [jswitch]"
+
+var validName = regexp.MustCompile(`^[a-z0-9][a-z0-9._-]*$`)
+
+// Cert is a named PEM file of the set of extra trusted certificates, which may hold a chain.
+type Cert struct {
+	Name  string
+	Path  string
+	Certs []*x509.Certificate
+}
+
+// Aliases returns the truststore alias of each certificate.
+func (c Cert) Aliases() []string {
+	if len(c.Certs) == 1 {
+		return []string{c.Name + aliasSuffix}
+	}
+	aliases := make([]string, len(c.Certs))
+	for i := range c.Certs {
+		aliases[i] = fmt.Sprintf("%s-%d%s", c.Name, i+1, aliasSuffix)
+	}
+	return aliases
+}
+
+// NameFor derives the name of a certificate file from its path: corp-root-ca.pem becomes
+// corp-root-ca.
+func NameFor(path string) string {
+	name := strings.ToLower(strings.TrimSuffix(filepath.Base(path), filepath.Ext(path)))
+	return strings.Map(func(r rune) rune {
+		if (r >= 'a' && r <= 'z') || (r >= '0' && r <= '9') || r == '.' || r == '_' || r == '-' {
+			return r
+		}
+		return '-'
+	}, name)
+}
+
+// LoadSet reads the certificates in dir, by name.
+func LoadSet(dir string) ([]Cert, error) {
+	files, err := filepath.Glob(filepath.Join(dir, "*.pem"))
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(files)
+	var set []Cert
+	for _, path := range files {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		certs, err := ReadPEM(data)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		set = append(set, Cert{Name: strings.TrimSuffix(filepath.Base(path), ".pem"), Path: path, Certs: certs})
+	}
+	return set, nil
+}
+
+// AddToSet stores certs in dir under name, replacing a file of that name.
+func AddToSet(dir, name string, certs []*x509.Certificate) (Cert, error) {
+	if !validName.MatchString(name) {
+		return Cert{}, fmt.Errorf("invalid name %q: use lowercase letters, digits, '.', '_' and '-'", name)
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return Cert{}, err
+	}
+	var b bytes.Buffer
+	for _, cert := range certs {
+		pem.Encode(&b, &pem.Block{Type: "CERTIFICATE", Bytes: cert.Raw})
+	}
+	path := filepath.Join(dir, name+".pem")
+	if err := fileutil.WriteFile(path, b.Bytes(), 0644); err != nil {
+		return Cert{}, err
+	}
+	return Cert{Name: name, Path: path, Certs: certs}, nil
+}
+
+// Result describes what Apply changed in a truststore.
+type Result struct {
+	Path    string
+	Added   int
+	Removed int
+}
+
+// Apply makes the truststore of the JDK at home trust the certificates of add and no longer
+// trust those of remove, then reads it back to verify. Certificates the JDK already trusts
+// are not added again, and only entries added by jswitch are removed.
+func Apply(home, password string, add, remove []Cert) (Result, error) {
+	path, err := Find(home)
+	if err != nil {
+		return Result{}, err
+	}
+	result := Result{Path: path}
+	s, err := Open(path, password)
+	if err != nil {
+		return result, err
+	}
+	for _, c := range remove {
+		for i, alias := range c.Aliases() {
+			if s.RemoveEntry(alias, c.Certs[i]) {
+				result.Removed++
+			}
+		}
+	}
+	for _, c := range add {
+		for i, alias := range c.Aliases() {
+			if s.Add(alias, c.Certs[i]) {
+				result.Added++
+			}
+		}
+	}
+	if result.Added == 0 && result.Removed == 0 {
+		return result, nil
+	}
+	if err := s.Save(); err != nil {
+		return result, err
+	}
+
+	s, err = Open(path, password)
+	if err != nil {
+		return result, fmt.Errorf("verifying: %w", err)
+	}
+	for _, c := range add {
+		for _, cert := range c.Certs {
+			if !s.Contains(cert) {
+				return result, fmt.Errorf("verifying: %s is not in %s after writing it", cert.Subject.CommonName, path)
+			}
+		}
+	}
+	return result, nil
+}
+
+// Trusts reports whether the truststore of the JDK at home trusts every certificate of c.
+func Trusts(home, password string, c Cert) (bool, error) {
+	path, err := Find(home)
+	if err != nil {
+		return false, err
+	}
+	s, err := Open(path, password)
+	if err != nil {
+		return false, err
+	}
+	for _, cert := range c.Certs {
+		if !s.Contains(cert) {
+			return false, nil
+		}
+	}
+	return true, nil
+}
@@ -0,0 +1,205 @@
+// Package truststore reads and writes the trusted certificates of Java keystores, such as a
+// JDK's lib/security/cacerts, in the JKS format of JDK 8 to 17 and the PKCS#12 format of
+// JDK 18 and later.
+package truststore
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/user/jswitch/pkg/fileutil"
+)
+
+// DefaultPassword is the password of the cacerts file every JDK ships.
+const DefaultPassword = "changeit"
+
+// Format is the file format of a keystore.
+type Format string
+
+const (
+	FormatJKS    Format = "JKS"
+	FormatPKCS12 Format = "PKCS12"
+)
+
+// Entry is a trusted certificate.
+type Entry struct {
+	Alias string
+	Cert  *x509.Certificate
+
+	// created is the creation time of a JKS entry in ms, kept when the file is rewritten.
+	created int64
+}
+
+// Store is a keystore loaded into memory. Entries other than trusted certificates (private
+// keys in a JKS file) are kept as they are.
+type Store struct {
+	Path   string
+	Format Format
+
+	password string
+	entries  []Entry
+	// jksKeys holds the private key entries of a JKS file, written back unchanged.
+	jksKeys []jksEntry
+	// protected records whether a PKCS#12 file has a MAC, and so a password.
+	protected bool
+}
+
+// Find returns the truststore of the JDK or JRE at home.
+func Find(home string) (string, error) {
+	for _, rel := range []string{"lib/security/cacerts", "jre/lib/security/cacerts"} {
+		path := filepath.Join(home, filepath.FromSlash(rel))
+		if info, err := os.Stat(path); err == nil && info.Mode().IsRegular() {
+			return path, nil
+		}
+	}
+	return "", fmt.Errorf("no lib/security/cacerts in %s", home)
+}
+
+// Open loads the keystore at path, checking its integrity with password.
+func Open(path, password string) (*Store, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	s := &Store{Path: path, password: password}
+	if len(data) >= 4 && binary.BigEndian.Uint32(data) == jksMagic {
+		s.Format = FormatJKS
+		err = s.readJKS(data)
+	} else {
+		s.Format = FormatPKCS12
+		err = s.readPKCS12(data)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("reading %s: %w", path, err)
+	}
+	return s, nil
+}
+
+// Entries returns the trusted certificates.
+func (s *Store) Entries() []Entry {
+	return append([]Entry(nil), s.entries...)
+}
+
+// Contains reports whether cert is trusted, under any alias.
+func (s *Store) Contains(cert *x509.Certificate) bool {
+	for _, e := range s.entries {
+		if e.Cert.Equal(cert) {
+			return true
+		}
+	}
+	return false
+}
+
+// Add trusts cert under alias, replacing another certificate of that alias. Returns false
+// if cert is trusted already.
+func (s *Store) Add(alias string, cert *x509.Certificate) bool {
+	if s.Contains(cert) {
+		return false
+	}
+	s.Remove(alias)
+	s.entries = append(s.entries, Entry{Alias: alias, Cert: cert})
+	return true
+}
+
+// Remove drops the certificate of alias. Returns false if there was none.
+func (s *Store) Remove(alias string) bool {
+	for i, e := range s.entries {
+		if strings.EqualFold(e.Alias, alias) {
+			s.entries = append(s.entries[:i], s.entries[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+// RemoveEntry drops cert if it is stored under alias, so that an entry the JDK ships with
+// the same certificate stays. Returns false if it is not.
+func (s *Store) RemoveEntry(alias string, cert *x509.Certificate) bool {
+	for i, e := range s.entries {
+		if strings.EqualFold(e.Alias, alias) && e.Cert.Equal(cert) {
+			s.entries = append(s.entries[:i], s.entries[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+// Save writes the store back in its format. The file is replaced rather than overwritten, so
+// a cacerts hardlinked by 'jswitch dedupe' stops being shared instead of changing other JDKs.
+func (s *Store) Save() error {
+	var data []byte
+	var err error
+	switch s.Format {
+	case FormatJKS:
+		data, err = s.writeJKS()
+	default:
+		data, err = s.writePKCS12()
+	}
+	if err != nil {
+		return fmt.Errorf("encoding %s: %w", s.Path, err)
+	}
+	perm := os.FileMode(0644)
+	if info, err := os.Stat(s.Path); err == nil {
+		perm = info.Mode().Perm()
+	}
+	return fileutil.WriteFile(s.Path, data, perm)
+}
+
+// ReadPEM returns the certificates in PEM data, or in DER data as a .cer file may hold.
+func ReadPEM(data []byte) ([]*x509.Certificate, error) {
+	if certs, err := x509.ParseCertificates(data); err == nil && len(certs) > 0 {
+		return certs, nil
+	}
+	var certs []*x509.Certificate
+	for {
+		var block *pem.Block
+		block, data = pem.Decode(data)
+		if block == nil {
+			break
+		}
+		if block.Type != "CERTIFICATE" {
+			continue
+		}
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		certs = append(certs, cert)
+	}
+	if len(certs) == 0 {
+		return nil, errors.New("no PEM CERTIFICATE blocks found")
+	}
+	return certs, nil
+}
+
+// Fingerprint returns the SHA-256 fingerprint of cert in the colon-separated form keytool prints.
+func Fingerprint(cert *x509.Certificate) string {
+	sum := sha256.Sum256(cert.Raw)
+	hexSum := strings.ToUpper(hex.EncodeToString(sum[:]))
+	var b bytes.Buffer
+	for i := 0; i < len(hexSum); i += 2 {
+		if i > 0 {
+			b.WriteByte(':')
+		}
+		b.WriteString(hexSum[i : i+2])
+	}
+	return b.String()
+}
+
+// subjectAlias derives an alias from a certificate's subject, for entries without one.
+func subjectAlias(cert *x509.Certificate) string {
+	name := cert.Subject.CommonName
+	if name == "" {
+		name = cert.Subject.String()
+	}
+	return strings.ToLower(name)
+}
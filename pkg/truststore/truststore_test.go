@@ -0,0 +1,346 @@
+package truststore
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/binary"
+	"encoding/pem"
+	"math/big"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	pkcs12 "software.sslmate.com/src/go-pkcs12"
+)
+
+// newCert returns a self-signed certificate with common name cn.
+func newCert(t *testing.T, cn string) *x509.Certificate {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	serial, err := rand.Int(rand.Reader, big.NewInt(1<<62))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber:          serial,
+		Subject:               pkix.Name{CommonName: cn},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cert
+}
+
+// jksKeyEntry is a private key entry with an opaque protected key, as keytool writes one.
+func jksKeyEntry(alias string, created int64, key []byte, chain *x509.Certificate) []byte {
+	var b bytes.Buffer
+	binary.Write(&b, binary.BigEndian, uint32(jksTagKey))
+	writeUTF(&b, alias)
+	binary.Write(&b, binary.BigEndian, created)
+	binary.Write(&b, binary.BigEndian, uint32(len(key)))
+	b.Write(key)
+	binary.Write(&b, binary.BigEndian, uint32(1))
+	writeUTF(&b, "X.509")
+	binary.Write(&b, binary.BigEndian, uint32(len(chain.Raw)))
+	b.Write(chain.Raw)
+	return b.Bytes()
+}
+
+// writeJKSFile writes a JKS keystore holding a private key entry and one trusted certificate.
+func writeJKSFile(t *testing.T, path, password string, key []byte, shipped Entry) {
+	t.Helper()
+	s := &Store{
+		Format:   FormatJKS,
+		password: password,
+		entries:  []Entry{shipped},
+		jksKeys:  []jksEntry{{raw: key}},
+	}
+	data, err := s.writeJKS()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func writePKCS12File(t *testing.T, path string, enc *pkcs12.Encoder, password string, shipped Entry) {
+	t.Helper()
+	data, err := enc.EncodeTrustStoreEntries([]pkcs12.TrustStoreEntry{{Cert: shipped.Cert, FriendlyName: shipped.Alias}}, password)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func aliases(s *Store) []string {
+	var out []string
+	for _, e := range s.Entries() {
+		out = append(out, e.Alias)
+	}
+	return out
+}
+
+func TestRoundTrip(t *testing.T) {
+	shipped := Entry{Alias: "digicertglobalrootca [jdk]", Cert: newCert(t, "DigiCert Global Root CA"), created: 1600000000000}
+	key := jksKeyEntry("server", 1500000000000, []byte("opaque protected key"), newCert(t, "server"))
+
+	tests := []struct {
+		name   string
+		format Format
+		write  func(t *testing.T, path string)
+		// created reports whether the format keeps creation times.
+		created bool
+	}{
+		{
+			name:   "PKCS#12 without password",
+			format: FormatPKCS12,
+			write:  func(t *testing.T, path string) { writePKCS12File(t, path, pkcs12.Passwordless, "", shipped) },
+		},
+		{
+			name:   "PKCS#12 with MAC",
+			format: FormatPKCS12,
+			write: func(t *testing.T, path string) {
+				s := &Store{Path: path, Format: FormatPKCS12, password: DefaultPassword, protected: true, entries: []Entry{shipped}}
+				if err := s.Save(); err != nil {
+					t.Fatal(err)
+				}
+			},
+		},
+		{
+			name:    "JKS",
+			format:  FormatJKS,
+			write:   func(t *testing.T, path string) { writeJKSFile(t, path, DefaultPassword, key, shipped) },
+			created: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "cacerts")
+			tt.write(t, path)
+
+			s, err := Open(path, DefaultPassword)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if s.Format != tt.format {
+				t.Errorf("format = %s, want %s", s.Format, tt.format)
+			}
+			corp := newCert(t, "Corp Root CA")
+			if !s.Add("corp"+aliasSuffix, corp) {
+				t.Fatal("Add returned false for a new certificate")
+			}
+			if s.Add("again"+aliasSuffix, shipped.Cert) {
+				t.Error("Add returned true for a certificate the store trusts")
+			}
+			if err := s.Save(); err != nil {
+				t.Fatal(err)
+			}
+
+			s, err = Open(path, DefaultPassword)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got, want := aliases(s), []string{shipped.Alias, "corp" + aliasSuffix}; !slices.Equal(got, want) {
+				t.Errorf("aliases after saving = %q, want %q", got, want)
+			}
+			if !s.Contains(corp) || !s.Contains(shipped.Cert) {
+				t.Error("a certificate is missing after saving")
+			}
+			if tt.created && s.entries[0].created != shipped.created {
+				t.Errorf("created = %d, want %d", s.entries[0].created, shipped.created)
+			}
+			if tt.format == FormatJKS {
+				if len(s.jksKeys) != 1 || !bytes.Equal(s.jksKeys[0].raw, key) {
+					t.Error("the private key entry changed")
+				}
+			}
+
+			if s.RemoveEntry("corp"+aliasSuffix, shipped.Cert) {
+				t.Error("RemoveEntry removed a certificate stored under another alias")
+			}
+			if !s.RemoveEntry("corp"+aliasSuffix, corp) {
+				t.Fatal("RemoveEntry did not remove the added certificate")
+			}
+			if err := s.Save(); err != nil {
+				t.Fatal(err)
+			}
+			s, err = Open(path, DefaultPassword)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if s.Contains(corp) || !s.Contains(shipped.Cert) {
+				t.Errorf("after RemoveEntry the store holds %q", aliases(s))
+			}
+		})
+	}
+}
+
+func TestOpenWrongPassword(t *testing.T) {
+	dir := t.TempDir()
+	shipped := Entry{Alias: "root [jdk]", Cert: newCert(t, "Root")}
+
+	jks := filepath.Join(dir, "cacerts.jks")
+	writeJKSFile(t, jks, DefaultPassword, jksKeyEntry("k", 1, []byte("key"), shipped.Cert), shipped)
+	if _, err := Open(jks, "wrong"); err == nil || !strings.Contains(err.Error(), "password was incorrect") {
+		t.Errorf("JKS with a wrong password: %v", err)
+	}
+
+	p12 := filepath.Join(dir, "cacerts.p12")
+	writePKCS12File(t, p12, pkcs12.LegacyDES, DefaultPassword, shipped)
+	if _, err := Open(p12, "wrong"); err == nil || !strings.Contains(err.Error(), "password was incorrect") {
+		t.Errorf("PKCS#12 with a wrong password: %v", err)
+	}
+}
+
+func TestEncryptedPKCS12(t *testing.T) {
+	// Older keytool releases encrypt the certificate bags, hiding the aliases. Once saved,
+	// the bags are plain and the aliases added by jswitch survive.
+	path := filepath.Join(t.TempDir(), "cacerts")
+	shipped := Entry{Alias: "root [jdk]", Cert: newCert(t, "Root CA")}
+	writePKCS12File(t, path, pkcs12.LegacyDES, DefaultPassword, shipped)
+
+	s, err := Open(path, DefaultPassword)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := aliases(s); !slices.Equal(got, []string{"root ca"}) {
+		t.Errorf("derived aliases = %q", got)
+	}
+	corp := newCert(t, "Corp")
+	s.Add("corp"+aliasSuffix, corp)
+	if err := s.Save(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Open(path, "wrong"); err == nil {
+		t.Error("the saved store has no MAC")
+	}
+	s, err = Open(path, DefaultPassword)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := aliases(s); !slices.Equal(got, []string{"root ca", "corp" + aliasSuffix}) {
+		t.Errorf("aliases after saving = %q", got)
+	}
+}
+
+// fakeHome creates a JDK home whose truststore is a passwordless PKCS#12 file.
+func fakeHome(t *testing.T, entries ...Entry) string {
+	t.Helper()
+	home := t.TempDir()
+	path := filepath.Join(home, "lib", "security", "cacerts")
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	s := &Store{Path: path, Format: FormatPKCS12, entries: entries}
+	if err := s.Save(); err != nil {
+		t.Fatal(err)
+	}
+	return home
+}
+
+func TestApplyRemovesOnlyOwnEntries(t *testing.T) {
+	corp := newCert(t, "Corp Root CA")
+	// The JDK ships the corp certificate itself, and someone added it under the alias
+	// jswitch would use, without the suffix.
+	home := fakeHome(t,
+		Entry{Alias: "corp [jdk]", Cert: corp},
+		Entry{Alias: "corp", Cert: corp},
+	)
+	other := Cert{Name: "other", Certs: []*x509.Certificate{newCert(t, "Other")}}
+	if _, err := Apply(home, "", []Cert{other}, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := Apply(home, "", nil, []Cert{{Name: "corp", Certs: []*x509.Certificate{corp}}, other})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Removed != 1 {
+		t.Errorf("removed %d entries, want 1", result.Removed)
+	}
+	path, _ := Find(home)
+	s, err := Open(path, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := aliases(s), []string{"corp [jdk]", "corp"}; !slices.Equal(got, want) {
+		t.Errorf("aliases after Apply = %q, want %q", got, want)
+	}
+}
+
+func pemEncode(certs ...*x509.Certificate) []byte {
+	var b bytes.Buffer
+	for _, c := range certs {
+		pem.Encode(&b, &pem.Block{Type: "CERTIFICATE", Bytes: c.Raw})
+	}
+	return b.Bytes()
+}
+
+func TestReadPEM(t *testing.T) {
+	leaf, root := newCert(t, "Intermediate"), newCert(t, "Root")
+	key := pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: []byte("x")})
+
+	tests := []struct {
+		name    string
+		data    []byte
+		want    []*x509.Certificate
+		wantErr bool
+	}{
+		{"DER", leaf.Raw, []*x509.Certificate{leaf}, false},
+		{"PEM", pemEncode(root), []*x509.Certificate{root}, false},
+		{"chain", pemEncode(leaf, root), []*x509.Certificate{leaf, root}, false},
+		{"other blocks skipped", append(key, pemEncode(root)...), []*x509.Certificate{root}, false},
+		{"no certificates", key, nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ReadPEM(tt.data)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, want error %v", err, tt.wantErr)
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("got %d certificates, want %d", len(got), len(tt.want))
+			}
+			for i := range got {
+				if !got[i].Equal(tt.want[i]) {
+					t.Errorf("certificate %d is %s", i, got[i].Subject.CommonName)
+				}
+			}
+		})
+	}
+}
+
+func TestCertAliases(t *testing.T) {
+	one := Cert{Name: "corp", Certs: []*x509.Certificate{newCert(t, "a")}}
+	if got, want := one.Aliases(), []string{"corp [jswitch]"}; !slices.Equal(got, want) {
+		t.Errorf("Aliases() = %q, want %q", got, want)
+	}
+	chain := Cert{Name: "corp", Certs: []*x509.Certificate{newCert(t, "a"), newCert(t, "b"), newCert(t, "c")}}
+	if got, want := chain.Aliases(), []string{"corp-1 [jswitch]", "corp-2 [jswitch]", "corp-3 [jswitch]"}; !slices.Equal(got, want) {
+		t.Errorf("Aliases() = %q, want %q", got, want)
+	}
+}
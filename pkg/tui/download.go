@@ -3,7 +3,6 @@ package tui
 import (
 	"fmt"
 	"os"
-	"path/filepath"
 	"strings"
 
 	"github.com/charmbracelet/bubbles/progress"
@@ -20,6 +19,9 @@ type errMsg error
 
 type DownloadModel struct {
 	version      int
+	opts         fetcher.Options
+	dest         string
+	installed    *models.JavaInstallation
 	progress     progress.Model
 	percent      float64
 	status       string
@@ -30,21 +32,24 @@ type DownloadModel struct {
 	progressChan chan float64
 }
 
-func NewDownloadModel(version int) DownloadModel {
+// NewDownloadModel installs the latest release of a feature version into dest.
+func NewDownloadModel(version int, opts fetcher.Options, dest string) DownloadModel {
 	return DownloadModel{
 		version:  version,
+		opts:     opts,
+		dest:     dest,
 		progress: progress.New(progress.WithDefaultGradient()),
 		status:   fmt.Sprintf("Finding latest Java %d release...", version),
 	}
 }
 
 func (m DownloadModel) Init() tea.Cmd {
-	return findVersionCmd(m.version)
+	return findVersionCmd(m.version, m.opts)
 }
 
-func findVersionCmd(version int) tea.Cmd {
+func findVersionCmd(version int, opts fetcher.Options) tea.Cmd {
 	return func() tea.Msg {
-		url, semver, err := fetcher.GetLatestVersion(version)
+		url, semver, err := fetcher.GetLatestVersion(version, opts)
 		if err != nil {
 			return errMsg(err)
 		}
@@ -90,9 +95,7 @@ func (m DownloadModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		m.semver = msg.semver
 		m.status = fmt.Sprintf("Downloading Java %s...", msg.semver)
 
-		// Determine destination
-		home, _ := os.UserHomeDir()
-		dest := filepath.Join(home, ".jswitch", "versions")
+		dest := m.dest
 		os.MkdirAll(dest, 0755)
 
 		m.progressChan = make(chan float64)
@@ -121,20 +124,20 @@ func (m DownloadModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		m.percent = 1.0
 
 		// Update Config
-		cfg, err := config.LoadConfig()
-		if err == nil {
-			cfg.Installations = append(cfg.Installations, models.JavaInstallation{
-				Vendor:  "Eclipse Adoptium",
-				Version: m.semver,
-				Path:    string(msg),
-			})
-			if err := config.SaveConfig(cfg); err != nil {
-				m.status += fmt.Sprintf("\nFailed to save config: %v", err)
-			} else {
-				m.status += "\nConfig updated."
+		inst := fetcher.NewInstallation(m.semver, string(msg))
+		var retargeted []string
+		err := config.Update(func(cfg *config.Config) error {
+			cfg.AddInstallation(inst)
+			for _, name := range cfg.ReconcileAliases() {
+				retargeted = append(retargeted, fmt.Sprintf("\nAlias %s now points at %s.", name, cfg.Aliases[name].Target))
 			}
+			return nil
+		})
+		if err != nil {
+			m.status += fmt.Sprintf("\nFailed to update config: %v", err)
 		} else {
-			m.status += fmt.Sprintf("\nFailed to load config: %v", err)
+			m.installed = &inst
+			m.status += "\nConfig updated." + strings.Join(retargeted, "")
 		}
 
 		m.status += "\nPress q to quit."
@@ -153,6 +156,19 @@ func (m DownloadModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	return m, nil
 }
 
+// Err returns the error that ended the download, if any.
+func (m DownloadModel) Err() error {
+	return m.err
+}
+
+// Installed returns the installation that was downloaded and recorded, if any.
+func (m DownloadModel) Installed() (models.JavaInstallation, bool) {
+	if m.installed == nil {
+		return models.JavaInstallation{}, false
+	}
+	return *m.installed, true
+}
+
 func (m DownloadModel) View() string {
 	if m.err != nil {
 		return lipgloss.NewStyle().Foreground(lipgloss.Color("196")).Render(m.status) + "\n"
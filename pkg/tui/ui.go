@@ -25,20 +25,25 @@ var (
 
 	activeStyle = lipgloss.NewStyle().
 			Foreground(lipgloss.Color("42")) // Green
+
+	previousStyle = lipgloss.NewStyle().
+			Foreground(lipgloss.Color("214")) // Orange
 )
 
 type Model struct {
 	installations []models.JavaInstallation
 	cursor        int
 	activeID      string
+	previousID    string
 	quitting      bool
 	SelectedID    string // Public field to retrieve selection after Run
 }
 
-func NewModel(installations []models.JavaInstallation, activeID string) Model {
+func NewModel(installations []models.JavaInstallation, activeID, previousID string) Model {
 	return Model{
 		installations: installations,
 		activeID:      activeID,
+		previousID:    previousID,
 	}
 }
 
@@ -94,6 +99,8 @@ func (m Model) View() string {
 		checked := " "
 		if inst.Version == m.activeID {
 			checked = "✓"
+		} else if inst.Version == m.previousID {
+			checked = "↺"
 		}
 
 		// Row string: "[✓] Vendor Version (Path)"
@@ -103,12 +110,14 @@ func (m Model) View() string {
 			s += selectedStyle.Render(cursor + row)
 		} else if inst.Version == m.activeID {
 			s += activeStyle.Render(cursor + row)
+		} else if inst.Version == m.previousID {
+			s += previousStyle.Render(cursor + row)
 		} else {
 			s += normalStyle.Render(cursor + row)
 		}
 		s += "\n"
 	}
 
-	s += "\n" + lipgloss.NewStyle().Foreground(lipgloss.Color("240")).Render("↑/↓: Navigate • Enter: Switch • q: Quit") + "\n"
+	s += "\n" + lipgloss.NewStyle().Foreground(lipgloss.Color("240")).Render("↑/↓: Navigate • Enter: Switch • ↺: Previous • q: Quit") + "\n"
 	return s
 }